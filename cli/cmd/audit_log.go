@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/liuyukai/agentskills-cli/internal/api"
+	"github.com/spf13/cobra"
+)
+
+var auditLogCmd = &cobra.Command{
+	Use:   "audit-log",
+	Short: "Show who changed what in the registry (admins only)",
+	Long: `Show the registry's audit log: every publish, yank, deprecation, dist-tag,
+ownership, token and organization change, with the user and token that made
+it and the client's IP address. Only registry admins may read it.
+
+--skill matches the target of an event: a skill name, "@<org>" for
+organization changes or a token id. --jsonl writes the events as JSON lines,
+one per event, for export to other tools.`,
+	Example: `  agentskills audit-log --skill code-review
+  agentskills audit-log --actor alice --since 7d
+  agentskills audit-log --since 2026-01-01 --jsonl > audit.jsonl`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		skill, _ := cmd.Flags().GetString("skill")
+		actor, _ := cmd.Flags().GetString("actor")
+		since, _ := cmd.Flags().GetString("since")
+		limit, _ := cmd.Flags().GetInt("limit")
+		jsonl, _ := cmd.Flags().GetBool("jsonl")
+		filter := api.AuditFilter{Skill: skill, Actor: actor, Limit: limit}
+		if since != "" {
+			t, err := parseSince(since)
+			if err != nil {
+				return err
+			}
+			filter.Since = t
+		}
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+		if jsonl {
+			if err := client.ExportAudit(filter, os.Stdout); err != nil {
+				return fmt.Errorf("exporting audit log: %w", err)
+			}
+			return nil
+		}
+		events, err := client.Audit(filter)
+		if err != nil {
+			return fmt.Errorf("reading audit log: %w", err)
+		}
+		if len(events) == 0 {
+			fmt.Println("No audit events found.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TIME\tACTOR\tACTION\tTARGET\tDETAIL\tCLIENT IP")
+		for _, e := range events {
+			target := e.Target
+			if e.Version != "" {
+				target += "@" + e.Version
+			}
+			actor := e.Actor
+			if e.TokenID != "" {
+				actor += " (token " + e.TokenID + ")"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				formatTime(&e.Time, ""), actor, e.Action, target, e.Detail, e.ClientIP)
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+// parseSince turns a --since value into an absolute time. It accepts a
+// number of days ("7d") or a Go duration ("12h") back from now, a date
+// ("2026-01-01") or an RFC 3339 timestamp.
+func parseSince(s string) (time.Time, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return time.Time{}, fmt.Errorf("invalid --since %q: want a positive number of days like 7d", s)
+		}
+		return time.Now().AddDate(0, 0, -n), nil
+	}
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --since %q: want e.g. 7d, 12h, 2026-01-01 or an RFC 3339 time", s)
+	}
+	return t, nil
+}
+
+func init() {
+	auditLogCmd.Flags().String("skill", "", "Only events on this skill, @org or token id")
+	auditLogCmd.Flags().String("actor", "", "Only events by this user")
+	auditLogCmd.Flags().String("since", "", "Only events since a time: 7d, 12h, a date or an RFC 3339 time")
+	auditLogCmd.Flags().Int("limit", 0, "Only the newest N events (default all)")
+	auditLogCmd.Flags().Bool("jsonl", false, "Write the events as JSON lines")
+	rootCmd.AddCommand(auditLogCmd)
+}
@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/liuyukai/agentskills-cli/internal/config"
+	"github.com/liuyukai/agentskills-cli/internal/credential"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show and change CLI configuration",
+	Long: `Show and change CLI configuration.
+
+Each key is resolved from these layers, later ones taking precedence:
+
+  1. built-in defaults
+  2. ~/.agentskills/config.yaml
+  3. .agentskills.yaml in the working directory or its nearest parent
+  4. $AGENTSKILLS_PROFILE, $AGENTSKILLS_API_URL, $AGENTSKILLS_TOKEN,
+     $AGENTSKILLS_CREDENTIAL_HELPER
+  5. --profile and --api-url
+
+If no layer sets a token, it is fetched from the credential helper.
+
+Keys: profile, api_url, token, credential_helper.`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the resolved value of a key",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadHelperToken(cmd)
+		if err != nil {
+			return err
+		}
+		value, err := cfg.Get(args[0])
+		if err != nil {
+			return err
+		}
+		if showOrigin, _ := cmd.Flags().GetBool("show-origin"); showOrigin {
+			fmt.Printf("%s\t%s\n", originOf(cfg, args[0]), value)
+			return nil
+		}
+		fmt.Println(value)
+		return nil
+	},
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the resolved configuration",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadHelperToken(cmd)
+		if err != nil {
+			return err
+		}
+		showOrigin, _ := cmd.Flags().GetBool("show-origin")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, key := range config.Keys {
+			value, _ := cfg.Get(key)
+			if key == config.KeyToken {
+				value = maskToken(value)
+			}
+			if showOrigin {
+				fmt.Fprintf(w, "%s\t%s=%s\n", originOf(cfg, key), key, value)
+			} else {
+				fmt.Fprintf(w, "%s=%s\n", key, value)
+			}
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set a key in the user or project config file",
+	Long: `Set a key in ~/.agentskills/config.yaml, or with --project in the
+nearest .agentskills.yaml (created in the working directory if there is none).
+
+In the user config file, profile sets the default profile, api_url sets the
+registry of the selected profile, and token sets the token saved for the
+resolved registry URL (or with the credential helper, if one is set).
+credential_helper names a command that stores tokens, such as a vault
+client; see "agentskills login --help". Project files hold profile and
+api_url only, since they are usually committed.`,
+	Example: `  agentskills config set profile corp
+  agentskills config set api_url https://skills.corp.example.com --profile corp
+  agentskills config set api_url http://localhost:8000 --project
+  agentskills config set credential_helper "vault-agentskills --mount ci"`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, value := args[0], args[1]
+		if _, err := (config.Config{}).Get(key); err != nil {
+			return err
+		}
+		if project, _ := cmd.Flags().GetBool("project"); project {
+			return setProjectConfig(key, value)
+		}
+
+		f, err := config.LoadFile()
+		if err != nil {
+			return fmt.Errorf("loading config: %w", err)
+		}
+		switch key {
+		case config.KeyProfile:
+			if _, ok := f.Profiles[value]; !ok && value != config.DefaultProfile {
+				return fmt.Errorf("profile %q not found; create it with agentskills login --profile %s", value, value)
+			}
+			f.DefaultProfile = value
+			fmt.Printf("Default profile is now %s\n", value)
+		case config.KeyAPIURL:
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			f.SetProfile(cfg.Profile, value)
+			fmt.Printf("Profile %s now uses %s\n", cfg.Profile, value)
+		case config.KeyToken:
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			if h := credential.New(cfg.CredentialHelper); h != nil {
+				if err := h.Store(cfg.APIURL, value); err != nil {
+					return err
+				}
+				fmt.Printf("Token for %s stored with credential helper %s\n", cfg.APIURL, cfg.CredentialHelper)
+				return nil
+			}
+			f.SetToken(cfg.APIURL, value)
+			fmt.Printf("Token saved for %s\n", cfg.APIURL)
+		case config.KeyCredentialHelper:
+			f.CredentialHelper = value
+		}
+		if err := f.Save(); err != nil {
+			return fmt.Errorf("saving config: %w", err)
+		}
+		return nil
+	},
+}
+
+// setProjectConfig sets key in the nearest project config file.
+func setProjectConfig(key, value string) error {
+	switch key {
+	case config.KeyToken:
+		return fmt.Errorf("tokens cannot be stored in %s; use agentskills login or $%s", config.ProjectFilename, config.EnvToken)
+	case config.KeyCredentialHelper:
+		return fmt.Errorf("credential_helper cannot be set in %s; set it in your user config", config.ProjectFilename)
+	}
+	path, p, err := config.LoadProjectFile()
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		dir, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("getting working directory: %w", err)
+		}
+		path, p = filepath.Join(dir, config.ProjectFilename), &config.ProjectFile{}
+	}
+	switch key {
+	case config.KeyProfile:
+		p.Profile = value
+	case config.KeyAPIURL:
+		p.APIURL = value
+	}
+	if err := config.SaveProjectFile(path, p); err != nil {
+		return err
+	}
+	fmt.Printf("Set %s in %s\n", key, path)
+	return nil
+}
+
+// loadHelperToken resolves the configuration like loadConfig and, if no
+// layer set a token, fetches it from the credential helper.
+func loadHelperToken(cmd *cobra.Command) (config.Config, error) {
+	cfg, err := loadConfig(cmd)
+	if err != nil || cfg.Token != "" {
+		return cfg, err
+	}
+	if h := credential.New(cfg.CredentialHelper); h != nil {
+		token, err := h.Get(cfg.APIURL)
+		if err != nil {
+			return cfg, err
+		}
+		if token != "" {
+			cfg.Token = token
+			cfg.Origins[config.KeyToken] = "credential helper " + cfg.CredentialHelper
+		}
+	}
+	return cfg, nil
+}
+
+// originOf describes where the value of key came from.
+func originOf(cfg config.Config, key string) string {
+	if origin, ok := cfg.Origins[key]; ok {
+		return origin
+	}
+	return "unset"
+}
+
+// maskToken hides all but the start of a token.
+func maskToken(token string) string {
+	switch {
+	case token == "":
+		return ""
+	case len(token) <= 8:
+		return "****"
+	}
+	return token[:8] + "..."
+}
+
+func init() {
+	configGetCmd.Flags().Bool("show-origin", false, "Also show where the value came from")
+	configListCmd.Flags().Bool("show-origin", false, "Also show where each value came from")
+	configSetCmd.Flags().Bool("project", false, "Write to the project's .agentskills.yaml instead of the user config")
+	configCmd.AddCommand(configGetCmd, configSetCmd, configListCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var deprecateCmd = &cobra.Command{
+	Use:   "deprecate <name> <message>",
+	Short: "Mark a skill as deprecated",
+	Long: `Mark a skill as deprecated. The message is shown by search, pull and vendor,
+e.g. to point users at a replacement. Use --undo to clear it.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		undo, _ := cmd.Flags().GetBool("undo")
+
+		var message string
+		if len(args) == 2 {
+			message = args[1]
+		}
+		if undo && message != "" {
+			return fmt.Errorf("--undo does not take a message")
+		}
+		if !undo && message == "" {
+			return fmt.Errorf("deprecation message required: agentskills deprecate %s \"<message>\"", name)
+		}
+
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		client := clientFor(cfg)
+
+		if err := client.Deprecate(name, message); err != nil {
+			return fmt.Errorf("deprecate failed: %w", err)
+		}
+		if undo {
+			fmt.Printf("%s is no longer deprecated\n", name)
+		} else {
+			fmt.Printf("Deprecated %s: %s\n", name, message)
+		}
+		return nil
+	},
+}
+
+func init() {
+	deprecateCmd.Flags().Bool("undo", false, "Clear the deprecation notice")
+	rootCmd.AddCommand(deprecateCmd)
+}
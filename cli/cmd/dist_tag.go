@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var distTagCmd = &cobra.Command{
+	Use:   "dist-tag",
+	Short: "Manage distribution tags (e.g. beta, stable) of a skill",
+	Long: `Dist-tags name release channels so consumers can opt in with
+"agentskills pull code-review@beta" instead of an exact version.
+"latest" is managed by the registry and always points at the highest stable version.`,
+}
+
+var distTagAddCmd = &cobra.Command{
+	Use:   "add <name>@<version> <tag>",
+	Short: "Point a dist-tag at a version",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, version := parsePullArg(args[0])
+		if version == "" {
+			return fmt.Errorf("version required: agentskills dist-tag add %s@<version> %s", name, args[1])
+		}
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+		if _, err := client.SetDistTag(name, args[1], version); err != nil {
+			return fmt.Errorf("setting dist-tag: %w", err)
+		}
+		fmt.Printf("%s: %s -> %s\n", name, args[1], version)
+		return nil
+	},
+}
+
+var distTagRmCmd = &cobra.Command{
+	Use:   "rm <name> <tag>",
+	Short: "Remove a dist-tag",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+		if _, err := client.RemoveDistTag(args[0], args[1]); err != nil {
+			return fmt.Errorf("removing dist-tag: %w", err)
+		}
+		fmt.Printf("Removed dist-tag %s from %s\n", args[1], args[0])
+		return nil
+	},
+}
+
+var distTagLsCmd = &cobra.Command{
+	Use:   "ls <name>",
+	Short: "List dist-tags of a skill",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+		tags, err := client.DistTags(args[0])
+		if err != nil {
+			return fmt.Errorf("listing dist-tags: %w", err)
+		}
+
+		names := make([]string, 0, len(tags))
+		for tag := range tags {
+			names = append(names, tag)
+		}
+		sort.Strings(names)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, tag := range names {
+			fmt.Fprintf(w, "%s\t%s\n", tag, tags[tag])
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+func init() {
+	distTagCmd.AddCommand(distTagAddCmd, distTagRmCmd, distTagLsCmd)
+	rootCmd.AddCommand(distTagCmd)
+}
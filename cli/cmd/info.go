@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var infoCmd = &cobra.Command{
+	Use:   "info <name>",
+	Short: "Show details of a skill",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+
+		client := clientFor(cfg)
+		info, err := client.GetSkill(args[0])
+		if err != nil {
+			return fmt.Errorf("fetching skill info: %w", err)
+		}
+
+		title := info.Name
+		if info.LatestVersion.Version != "" {
+			title += "@" + info.LatestVersion.Version
+		}
+		fmt.Println(title)
+		if info.Description != "" {
+			fmt.Println(info.Description)
+		}
+		fmt.Println()
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "Owner:\t%s\n", info.Owner)
+		if info.Visibility != "" {
+			fmt.Fprintf(w, "Visibility:\t%s\n", info.Visibility)
+		}
+		if info.License != "" {
+			fmt.Fprintf(w, "License:\t%s\n", info.License)
+		}
+		if len(info.Tags) > 0 {
+			fmt.Fprintf(w, "Tags:\t%s\n", strings.Join(info.Tags, ", "))
+		}
+		fmt.Fprintf(w, "Downloads:\t%d\n", info.Downloads)
+		if len(info.DistTags) > 0 {
+			fmt.Fprintf(w, "Dist-tags:\t%s\n", formatDistTags(info.DistTags))
+		}
+		fmt.Fprintf(w, "Versions:\t%d (agentskills versions %s)\n", len(info.Versions), info.Name)
+		if info.LatestVersion.PublishedAt != "" {
+			fmt.Fprintf(w, "Published:\t%s (%s)\n", info.LatestVersion.PublishedAt, formatSize(info.LatestVersion.SizeBytes))
+		}
+		if info.Deprecated != "" {
+			fmt.Fprintf(w, "Deprecated:\t%s\n", info.Deprecated)
+		}
+		w.Flush()
+
+		if len(info.Frontmatter) > 0 {
+			var buf strings.Builder
+			enc := yaml.NewEncoder(&buf)
+			enc.SetIndent(2)
+			if err := enc.Encode(info.Frontmatter); err != nil {
+				return fmt.Errorf("formatting frontmatter: %w", err)
+			}
+			fmt.Println("\nFrontmatter:")
+			for _, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+				fmt.Println("  " + line)
+			}
+		}
+		return nil
+	},
+}
+
+// formatDistTags renders tags as "beta: 2.0.0-rc.1, latest: 1.4.0".
+func formatDistTags(tags map[string]string) string {
+	names := make([]string, 0, len(tags))
+	for tag := range tags {
+		names = append(names, tag)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, tag := range names {
+		parts[i] = tag + ": " + tags[tag]
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatSize(bytes int64) string {
+	switch {
+	case bytes >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(bytes)/(1<<30))
+	case bytes >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(bytes)/(1<<20))
+	}
+	return fmt.Sprintf("%.1f KB", float64(bytes)/1024)
+}
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+}
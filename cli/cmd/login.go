@@ -1,36 +1,240 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
 
+	"github.com/liuyukai/agentskills-cli/internal/api"
 	"github.com/liuyukai/agentskills-cli/internal/config"
+	"github.com/liuyukai/agentskills-cli/internal/credential"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 var loginCmd = &cobra.Command{
 	Use:   "login",
-	Short: "Save API token to local config",
+	Short: "Verify an API token with the registry and save it to local config",
+	Long: `Prompt for an API token without echoing it, check it against the
+registry and save it to ~/.agentskills/config.yaml, or hand it to the
+configured credential_helper so it is never written to disk in plain text.
+
+With --profile, the registry is saved as a named profile that other
+commands select with the same flag. Tokens are stored per registry URL.
+
+In scripts, pipe the token in with --token-stdin:
+
+  echo "$AGENTSKILLS_TOKEN" | agentskills login --token-stdin`,
+	Example: `  agentskills login
+  agentskills login --profile corp --api-url https://skills.corp.example.com --default
+  agentskills search lint --profile corp`,
+	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		fmt.Print("Enter API token: ")
-		var token string
-		if _, err := fmt.Scanln(&token); err != nil {
-			return fmt.Errorf("reading token: %w", err)
+		fromStdin, _ := cmd.Flags().GetBool("token-stdin")
+		token, err := readToken(fromStdin)
+		if err != nil {
+			return err
+		}
+
+		var o config.Overrides
+		o.Profile, _ = cmd.Flags().GetString("profile")
+		o.APIURL, _ = cmd.Flags().GetString("api-url")
+		o.NewProfile = true
+		cfg, err := config.Resolve(o)
+		if err != nil {
+			return fmt.Errorf("loading config: %w", err)
+		}
+		f, err := config.LoadFile()
+		if err != nil {
+			return fmt.Errorf("loading config: %w", err)
 		}
+		profile, apiURL := cfg.Profile, cfg.APIURL
 
-		apiURL, _ := cmd.Flags().GetString("api-url")
-		cfg := config.Config{
-			APIURL: apiURL,
-			Token:  token,
+		who, err := api.NewClient(apiURL, token).WhoAmI()
+		var apiErr *api.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+			return fmt.Errorf("%s rejected the token: %s", apiURL, apiErr.Message)
+		}
+		if err != nil {
+			return fmt.Errorf("verifying token: %w", err)
+		}
+		if who.Auth == "none" {
+			return fmt.Errorf("%s did not recognize the token (the server does not require authentication)", apiURL)
+		}
+
+		// An existing profile keeps its registry unless --api-url moves it;
+		// a project or environment override only applies to this login.
+		if _, ok := f.Profiles[profile]; !ok || o.APIURL != "" {
+			f.SetProfile(profile, apiURL)
+		}
+		helper := credential.New(cfg.CredentialHelper)
+		if helper != nil {
+			if err := helper.Store(apiURL, token); err != nil {
+				return err
+			}
+			f.SetToken(apiURL, "")
+		} else {
+			f.SetToken(apiURL, token)
+		}
+		// The first profile someone sets up becomes the default.
+		makeDefault, _ := cmd.Flags().GetBool("default")
+		if makeDefault || f.DefaultProfile == "" && len(f.Profiles) == 1 && profile != config.DefaultProfile {
+			f.DefaultProfile = profile
 		}
-		if err := config.Save(cfg); err != nil {
+		if err := f.Save(); err != nil {
 			return fmt.Errorf("saving config: %w", err)
 		}
 
-		fmt.Println("Token saved to ~/.agentskills/config.yaml")
+		fmt.Printf("Logged in to %s as %s (profile %s)\n", apiURL, describeIdentity(who), profile)
+		if helper != nil {
+			fmt.Printf("Token stored with credential helper %s\n", cfg.CredentialHelper)
+		} else {
+			fmt.Println("Token saved to ~/.agentskills/config.yaml")
+		}
+		return nil
+	},
+}
+
+var whoamiCmd = &cobra.Command{
+	Use:   "whoami",
+	Short: "Show the registry user of the saved API token",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		who, err := clientFor(cfg).WhoAmI()
+		var apiErr *api.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+			return fmt.Errorf("not logged in to %s (%s); run agentskills login", cfg.APIURL, apiErr.Message)
+		}
+		if err != nil {
+			return fmt.Errorf("checking token: %w", err)
+		}
+		if who.Auth == "none" {
+			fmt.Printf("Anonymous: %s does not require authentication\n", cfg.APIURL)
+			return nil
+		}
+
+		fmt.Println(describeIdentity(who))
+		fmt.Printf("  profile:  %s\n  registry: %s\n", cfg.Profile, cfg.APIURL)
+		if t := who.Token; t != nil {
+			scopes := "all"
+			if len(t.Scopes) > 0 {
+				scopes = strings.Join(t.Scopes, " ")
+			}
+			fmt.Printf("  token:    %s (%s)\n  scopes:   %s\n  expires:  %s\n", t.Name, t.ID, scopes, formatTime(t.ExpiresAt, "never"))
+		}
+		return nil
+	},
+}
+
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Remove the saved API token from local config",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		if origin := cfg.Origins[config.KeyToken]; origin == "env "+config.EnvToken {
+			return fmt.Errorf("the token for %s comes from $%s; unset it to log out", cfg.APIURL, config.EnvToken)
+		}
+		helper := credential.New(cfg.CredentialHelper)
+		token := cfg.Token
+		if token == "" && helper != nil {
+			if token, err = helper.Get(cfg.APIURL); err != nil {
+				return err
+			}
+		}
+		if token == "" {
+			fmt.Printf("Not logged in to %s.\n", cfg.APIURL)
+			return nil
+		}
+
+		if revoke, _ := cmd.Flags().GetBool("revoke"); revoke {
+			client := api.NewClient(cfg.APIURL, token)
+			who, err := client.WhoAmI()
+			if err != nil {
+				return fmt.Errorf("checking token: %w", err)
+			}
+			if who.Token == nil {
+				return fmt.Errorf("the saved token is not a user API token and cannot be revoked")
+			}
+			if err := client.RevokeToken(who.Token.ID); err != nil {
+				return fmt.Errorf("revoking token: %w", err)
+			}
+			fmt.Printf("Revoked token %s\n", who.Token.ID)
+		}
+
+		f, err := config.LoadFile()
+		if err != nil {
+			return fmt.Errorf("loading config: %w", err)
+		}
+		if helper != nil {
+			if err := helper.Erase(cfg.APIURL); err != nil {
+				return err
+			}
+		}
+		f.SetToken(cfg.APIURL, "")
+		if err := f.Save(); err != nil {
+			return fmt.Errorf("saving config: %w", err)
+		}
+		fmt.Printf("Logged out of %s\n", cfg.APIURL)
 		return nil
 	},
 }
 
+// readToken reads an API token from stdin, either piped or typed at a
+// terminal prompt without echo.
+func readToken(fromStdin bool) (string, error) {
+	var token string
+	if fromStdin {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("reading token: %w", err)
+		}
+		token = string(data)
+	} else {
+		fd := int(os.Stdin.Fd())
+		if !term.IsTerminal(fd) {
+			return "", errors.New("stdin is not a terminal; pipe the token in with --token-stdin")
+		}
+		fmt.Fprint(os.Stderr, "Enter API token: ")
+		data, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("reading token: %w", err)
+		}
+		token = string(data)
+	}
+
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", errors.New("no token given")
+	}
+	return token, nil
+}
+
+// describeIdentity formats who for messages like "Logged in as ...".
+func describeIdentity(who *api.WhoAmI) string {
+	if who.Username == "" {
+		return "admin (shared token)"
+	}
+	if who.Admin {
+		return who.Username + " (admin)"
+	}
+	return who.Username
+}
+
 func init() {
-	rootCmd.AddCommand(loginCmd)
+	loginCmd.Flags().Bool("token-stdin", false, "Read the token from stdin instead of prompting")
+	loginCmd.Flags().Bool("default", false, "Make the profile the default for commands run without --profile")
+	logoutCmd.Flags().Bool("revoke", false, "Also revoke the token on the registry")
+	rootCmd.AddCommand(loginCmd, whoamiCmd, logoutCmd)
 }
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/liuyukai/agentskills-cli/internal/api"
+	"github.com/spf13/cobra"
+)
+
+var orgCmd = &cobra.Command{
+	Use:   "org",
+	Short: "Manage organizations and their members",
+	Long: `Manage organizations. An organization owns the scope of the same name:
+only its members can publish skills named @<org>/<name>, and every member
+may manage them. Owners also manage the member list.`,
+}
+
+var orgCreateCmd = &cobra.Command{
+	Use:   "create <org>",
+	Short: "Create an organization with yourself as owner",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+		org, err := client.CreateOrg(strings.TrimPrefix(args[0], "@"))
+		if err != nil {
+			return fmt.Errorf("creating organization: %w", err)
+		}
+		fmt.Printf("Created organization %s; publish skills as @%s/<name>\n", org.Name, org.Name)
+		return nil
+	},
+}
+
+var orgMembersCmd = &cobra.Command{
+	Use:   "members <org>",
+	Short: "List the members of an organization",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+		org, err := client.GetOrg(strings.TrimPrefix(args[0], "@"))
+		if err != nil {
+			return fmt.Errorf("loading organization: %w", err)
+		}
+		printOrgMembers(org)
+		return nil
+	},
+}
+
+var orgAddCmd = &cobra.Command{
+	Use:   "add <org> <username>",
+	Short: "Add a member to an organization, or change their role",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		role, _ := cmd.Flags().GetString("role")
+		if role != "owner" && role != "member" {
+			return fmt.Errorf("invalid role %q: want owner or member", role)
+		}
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+		org, err := client.SetOrgMember(strings.TrimPrefix(args[0], "@"), args[1], role)
+		if err != nil {
+			return fmt.Errorf("adding member: %w", err)
+		}
+		printOrgMembers(org)
+		return nil
+	},
+}
+
+var orgRemoveCmd = &cobra.Command{
+	Use:   "rm <org> <username>",
+	Short: "Remove a member from an organization",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+		org, err := client.RemoveOrgMember(strings.TrimPrefix(args[0], "@"), args[1])
+		if err != nil {
+			return fmt.Errorf("removing member: %w", err)
+		}
+		printOrgMembers(org)
+		return nil
+	},
+}
+
+func printOrgMembers(org *api.Org) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "USERNAME\tROLE")
+	for _, m := range org.Members {
+		fmt.Fprintf(w, "%s\t%s\n", m.Username, m.Role)
+	}
+	w.Flush()
+}
+
+func init() {
+	orgAddCmd.Flags().String("role", "member", "Role of the member: owner or member")
+	orgCmd.AddCommand(orgCreateCmd, orgMembersCmd, orgAddCmd, orgRemoveCmd)
+	rootCmd.AddCommand(orgCmd)
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/liuyukai/agentskills-cli/internal/api"
+	"github.com/spf13/cobra"
+)
+
+var ownerCmd = &cobra.Command{
+	Use:   "owner",
+	Short: "Manage who owns and collaborates on a skill",
+	Long: `Manage who owns and collaborates on a skill.
+
+Collaborators may publish versions and manage the skill like its owner,
+but only the owner adds or removes collaborators and transfers the skill.
+A skill can be owned by a user or, written as @<org>, by an organization,
+whose members then manage it. Every change is recorded with who made it.`,
+}
+
+var ownerListCmd = &cobra.Command{
+	Use:   "ls <name>",
+	Short: "Show the owner, collaborators and ownership history of a skill",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+		owners, err := client.Owners(args[0])
+		if err != nil {
+			return fmt.Errorf("listing owners: %w", err)
+		}
+		printOwners(owners)
+		if len(owners.History) > 0 {
+			fmt.Println("\nHistory:")
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, e := range owners.History {
+				fmt.Fprintf(w, "  %s\t%s\tby %s\n", formatTime(&e.At, ""), describeOwnerEvent(e), e.Actor)
+			}
+			w.Flush()
+		}
+		return nil
+	},
+}
+
+var ownerAddCmd = &cobra.Command{
+	Use:   "add <name> <username>",
+	Short: "Add a collaborator to a skill",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+		owners, err := client.AddCollaborator(args[0], args[1])
+		if err != nil {
+			return fmt.Errorf("adding collaborator: %w", err)
+		}
+		printOwners(owners)
+		return nil
+	},
+}
+
+var ownerRemoveCmd = &cobra.Command{
+	Use:   "rm <name> <username>",
+	Short: "Remove a collaborator from a skill",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+		owners, err := client.RemoveCollaborator(args[0], args[1])
+		if err != nil {
+			return fmt.Errorf("removing collaborator: %w", err)
+		}
+		printOwners(owners)
+		return nil
+	},
+}
+
+var ownerTransferCmd = &cobra.Command{
+	Use:   "transfer <name> <username|@org>",
+	Short: "Transfer a skill to another user or an organization",
+	Long: `Transfer a skill to another user or an organization. You lose your owner
+rights unless the new owner adds you back as a collaborator.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+		owners, err := client.Transfer(args[0], args[1])
+		if err != nil {
+			return fmt.Errorf("transferring skill: %w", err)
+		}
+		fmt.Printf("Transferred %s to %s\n", owners.Name, owners.Owner)
+		return nil
+	},
+}
+
+func printOwners(owners *api.Owners) {
+	fmt.Printf("Owner:         %s\n", owners.Owner)
+	collaborators := "none"
+	if len(owners.Collaborators) > 0 {
+		collaborators = strings.Join(owners.Collaborators, ", ")
+	}
+	fmt.Printf("Collaborators: %s\n", collaborators)
+}
+
+func describeOwnerEvent(e api.OwnerEvent) string {
+	switch e.Action {
+	case "add_collaborator":
+		return "added collaborator " + e.User
+	case "remove_collaborator":
+		return "removed collaborator " + e.User
+	case "transfer":
+		return fmt.Sprintf("transferred from %s to %s", e.From, e.User)
+	}
+	return e.Action + " " + e.User
+}
+
+func init() {
+	ownerCmd.AddCommand(ownerListCmd, ownerAddCmd, ownerRemoveCmd, ownerTransferCmd)
+	rootCmd.AddCommand(ownerCmd)
+}
@@ -9,44 +9,49 @@ import (
 
 	"github.com/liuyukai/agentskills-cli/internal/api"
 	"github.com/liuyukai/agentskills-cli/internal/bundle"
-	"github.com/liuyukai/agentskills-cli/internal/config"
 	"github.com/spf13/cobra"
 )
 
 var pullCmd = &cobra.Command{
-	Use:   "pull <name>[@version]",
+	Use:   "pull <name>[@version|range]",
 	Short: "Download and extract a skill bundle",
-	Args:  cobra.ExactArgs(1),
+	Long: `Download and extract a skill bundle. The version may be exact or a range,
+which resolves to the highest matching non-yanked version:
+
+  agentskills pull code-review            # latest stable version
+  agentskills pull code-review@1.2.0      # exact version
+  agentskills pull code-review@^1.2       # >=1.2.0 <2.0.0
+  agentskills pull code-review@~1.2.3     # >=1.2.3 <1.3.0
+  agentskills pull "code-review@>=1.0.0 <2.0.0"
+  agentskills pull @acme/code-review@^2   # organization-scoped skill`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		nameVersion := args[0]
 		name, version := parsePullArg(nameVersion)
 
-		cfg, err := config.Load()
+		cfg, err := loadConfig(cmd)
 		if err != nil {
-			return fmt.Errorf("loading config: %w", err)
+			return err
 		}
 
-		client := api.NewClient(cfg.APIURL, cfg.Token)
+		client := clientFor(cfg)
 
-		// Resolve version if not specified
-		if version == "" {
-			fmt.Printf("Fetching latest version of %s...\n", name)
-			info, err := client.GetSkill(name)
-			if err != nil {
-				return fmt.Errorf("fetching skill info: %w", err)
-			}
-			version = info.LatestVersion.Version
+		version, err = resolveVersion(client, name, version)
+		if err != nil {
+			return err
 		}
 
 		// Download
 		fmt.Printf("Downloading %s@%s...  ", name, version)
-		tmpFile, expectedChecksum, err := client.Download(name, version)
+		dl, err := client.Download(name, version)
 		if err != nil {
 			fmt.Println("✗")
 			return fmt.Errorf("download failed: %w", err)
 		}
+		tmpFile, expectedChecksum := dl.Path, dl.Checksum
 		defer os.Remove(tmpFile)
 		fmt.Println("✓")
+		warnDownload(name, version, dl)
 
 		// Verify checksum
 		fmt.Print("Verifying checksum...  ")
@@ -75,6 +80,8 @@ var pullCmd = &cobra.Command{
 	},
 }
 
+// parsePullArg splits "name@version". The "@" that starts a scoped name
+// such as "@acme/code-review" is not a separator.
 func parsePullArg(s string) (name, version string) {
 	if idx := strings.LastIndex(s, "@"); idx > 0 {
 		return s[:idx], s[idx+1:]
@@ -82,6 +89,20 @@ func parsePullArg(s string) (name, version string) {
 	return s, ""
 }
 
+// warnDownload prints yank and deprecation notices reported by the server.
+func warnDownload(name, version string, dl *api.DownloadResult) {
+	if dl.Yanked {
+		msg := fmt.Sprintf("Warning: %s@%s has been yanked", name, version)
+		if dl.YankReason != "" {
+			msg += ": " + dl.YankReason
+		}
+		fmt.Fprintln(os.Stderr, msg)
+	}
+	if dl.Deprecated != "" {
+		fmt.Fprintf(os.Stderr, "Warning: %s is deprecated: %s\n", name, dl.Deprecated)
+	}
+}
+
 func init() {
 	rootCmd.AddCommand(pullCmd)
 }
@@ -2,14 +2,16 @@ package cmd
 
 import (
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 
 	"github.com/liuyukai/agentskills-cli/internal/api"
 	"github.com/liuyukai/agentskills-cli/internal/bundle"
-	"github.com/liuyukai/agentskills-cli/internal/config"
 	"github.com/liuyukai/agentskills-cli/internal/parser"
+	"github.com/liuyukai/agentskills-cli/internal/semver"
 	"github.com/spf13/cobra"
 )
 
@@ -20,9 +22,9 @@ var pushCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		skillPath := args[0]
 
-		cfg, err := config.Load()
+		cfg, err := loadConfig(cmd)
 		if err != nil {
-			return fmt.Errorf("loading config: %w", err)
+			return err
 		}
 
 		// Validate SKILL.md locally
@@ -63,10 +65,19 @@ var pushCmd = &cobra.Command{
 
 		// Upload
 		fmt.Printf("Uploading %s@%s...   ", meta.Name, meta.Version)
-		client := api.NewClient(cfg.APIURL, cfg.Token)
-		result, err := client.Publish(bundlePath)
+		client := clientFor(cfg)
+		distTag, _ := cmd.Flags().GetString("tag")
+		result, err := client.Publish(bundlePath, distTag)
 		if err != nil {
 			fmt.Println("✗")
+			var apiErr *api.APIError
+			if errors.As(err, &apiErr) && apiErr.Code == api.CodeVersionExists {
+				return fmt.Errorf("%s@%s is already published and versions are immutable.\n"+
+					"Bump the version in SKILL.md (e.g. %s) and push again", meta.Name, meta.Version, nextPatch(meta.Version))
+			}
+			if errors.As(err, &apiErr) && apiErr.Code == api.CodeForbidden {
+				return fmt.Errorf("publish rejected: %s", apiErr.Message)
+			}
 			return fmt.Errorf("upload failed: %w", err)
 		}
 		fmt.Println("✓")
@@ -78,10 +89,36 @@ var pushCmd = &cobra.Command{
 
 		fmt.Printf("Checksum: %s\n", result.Checksum)
 		fmt.Printf("\nPublished %s@%s successfully.\n", meta.Name, meta.Version)
+		if distTag != "" {
+			fmt.Printf("Tagged %s@%s as %s.\n", meta.Name, meta.Version, distTag)
+		}
 		return nil
 	},
 }
 
+// nextPatch suggests the next version after version: the next patch release,
+// or the next prerelease of the same series.
+func nextPatch(version string) string {
+	v, err := semver.Parse(version)
+	if err != nil {
+		return "a higher version"
+	}
+	v.Build = nil
+	if v.IsPrerelease() {
+		// 2.0.0-beta.1 -> 2.0.0-beta.2; otherwise start a new prerelease series.
+		last := len(v.Prerelease) - 1
+		if n, err := strconv.ParseUint(v.Prerelease[last], 10, 64); err == nil {
+			v.Prerelease[last] = strconv.FormatUint(n+1, 10)
+		} else {
+			v.Prerelease = append(v.Prerelease, "1")
+		}
+		return v.String()
+	}
+	v.Patch++
+	return v.String()
+}
+
 func init() {
+	pushCmd.Flags().String("tag", "", "Point this dist-tag (e.g. beta) at the published version")
 	rootCmd.AddCommand(pushCmd)
 }
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/liuyukai/agentskills-cli/internal/api"
+	"github.com/liuyukai/agentskills-cli/internal/semver"
+)
+
+// resolveVersion turns the version part of a name@version argument into a
+// concrete version. An empty spec means the latest stable version, an exact
+// version is used as-is (even if yanked or a prerelease), a range resolves to
+// the highest matching non-yanked version, and anything else is a dist-tag.
+func resolveVersion(client *api.Client, name, spec string) (string, error) {
+	if spec == "" {
+		fmt.Printf("Fetching latest version of %s...\n", name)
+		info, err := client.GetSkill(name)
+		if err != nil {
+			return "", fmt.Errorf("fetching skill info: %w", err)
+		}
+		if info.LatestVersion.Version == "" {
+			return "", fmt.Errorf("%s has no stable, non-yanked version; request one explicitly with %s@<version>", name, name)
+		}
+		return info.LatestVersion.Version, nil
+	}
+	if semver.IsValid(spec) {
+		return spec, nil
+	}
+
+	constraint, err := semver.ParseConstraint(spec)
+	if err != nil {
+		if !distTagRegex.MatchString(spec) {
+			return "", err
+		}
+		return resolveDistTag(client, name, spec)
+	}
+	fmt.Printf("Resolving %s@%s...\n", name, spec)
+	versions, err := client.ListVersions(name)
+	if err != nil {
+		return "", fmt.Errorf("listing versions: %w", err)
+	}
+
+	var best string
+	var bestV semver.Version
+	for _, vi := range versions {
+		if vi.Yanked {
+			continue
+		}
+		v, err := semver.Parse(vi.Version)
+		if err != nil || !constraint.Check(v) {
+			continue
+		}
+		if best == "" || v.Compare(bestV) > 0 {
+			best, bestV = vi.Version, v
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("no published version of %s matches %q", name, spec)
+	}
+	return best, nil
+}
+
+// distTagRegex matches dist-tag names as accepted by the registry.
+var distTagRegex = regexp.MustCompile(`^[a-z][a-z0-9-]{0,31}$`)
+
+func resolveDistTag(client *api.Client, name, tag string) (string, error) {
+	fmt.Printf("Resolving %s@%s...\n", name, tag)
+	info, err := client.GetSkill(name)
+	if err != nil {
+		return "", fmt.Errorf("fetching skill info: %w", err)
+	}
+	version, ok := info.DistTags[tag]
+	if !ok {
+		return "", fmt.Errorf("%s has no dist-tag %q", name, tag)
+	}
+	return version, nil
+}
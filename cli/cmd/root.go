@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/liuyukai/agentskills-cli/internal/api"
+	"github.com/liuyukai/agentskills-cli/internal/config"
+	"github.com/liuyukai/agentskills-cli/internal/credential"
 	"github.com/spf13/cobra"
 )
 
@@ -21,5 +24,47 @@ func Execute() {
 }
 
 func init() {
-	rootCmd.PersistentFlags().String("api-url", "http://localhost:8000", "AgentSkills API base URL")
+	rootCmd.PersistentFlags().String("api-url", "", "AgentSkills API base URL (overrides config files and $AGENTSKILLS_API_URL)")
+	rootCmd.PersistentFlags().String("profile", "", "Config profile to use (overrides config files and $AGENTSKILLS_PROFILE)")
+}
+
+// loadConfig resolves the configuration from config files, environment
+// variables and the --profile and --api-url flags. Every command that talks
+// to a registry goes through it.
+func loadConfig(cmd *cobra.Command) (config.Config, error) {
+	var o config.Overrides
+	o.Profile, _ = cmd.Flags().GetString("profile")
+	o.APIURL, _ = cmd.Flags().GetString("api-url")
+	cfg, err := config.Resolve(o)
+	if err != nil {
+		return cfg, fmt.Errorf("loading config: %w", err)
+	}
+	for _, w := range cfg.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
+	return cfg, nil
+}
+
+// newClient returns an API client for the resolved configuration.
+func newClient(cmd *cobra.Command) (*api.Client, error) {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return clientFor(cfg), nil
+}
+
+// clientFor returns an API client for cfg.APIURL.
+func clientFor(cfg config.Config) *api.Client {
+	return sourceClient(cfg, cfg.APIURL, cfg.Token)
+}
+
+// sourceClient returns an API client for a registry. Without a token, the
+// client asks the configured credential helper once it needs one.
+func sourceClient(cfg config.Config, apiURL, token string) *api.Client {
+	client := api.NewClient(apiURL, token)
+	if h := credential.New(cfg.CredentialHelper); h != nil {
+		client.WithCredentials(h)
+	}
+	return client
 }
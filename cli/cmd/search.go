@@ -5,8 +5,6 @@ import (
 	"text/tabwriter"
 	"os"
 
-	"github.com/liuyukai/agentskills-cli/internal/api"
-	"github.com/liuyukai/agentskills-cli/internal/config"
 	"github.com/spf13/cobra"
 )
 
@@ -17,12 +15,12 @@ var searchCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		keyword := args[0]
 
-		cfg, err := config.Load()
+		cfg, err := loadConfig(cmd)
 		if err != nil {
-			return fmt.Errorf("loading config: %w", err)
+			return err
 		}
 
-		client := api.NewClient(cfg.APIURL, cfg.Token)
+		client := clientFor(cfg)
 		results, err := client.Search(keyword)
 		if err != nil {
 			return fmt.Errorf("search failed: %w", err)
@@ -36,9 +34,23 @@ var searchCmd = &cobra.Command{
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 		fmt.Fprintln(w, "NAME\tVERSION\tDOWNLOADS\tDESCRIPTION")
 		for _, r := range results.Results {
-			fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", r.Name, r.LatestVersion, r.Downloads, r.Description)
+			desc := r.Description
+			if r.Deprecated != "" {
+				desc = "[DEPRECATED] " + desc
+			}
+			version := r.LatestVersion
+			if version == "" {
+				version = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", r.Name, version, r.Downloads, desc)
 		}
 		w.Flush()
+
+		for _, r := range results.Results {
+			if r.Deprecated != "" {
+				fmt.Fprintf(os.Stderr, "Warning: %s is deprecated: %s\n", r.Name, r.Deprecated)
+			}
+		}
 		return nil
 	},
 }
@@ -19,8 +19,45 @@ var serveCmd = &cobra.Command{
 		dataDir, _ := cmd.Flags().GetString("data-dir")
 		token, _ := cmd.Flags().GetString("token")
 
-		store := server.NewStore(dataDir)
-		handler := server.NewHandler(store, token)
+		meta, closeMeta, err := openMetaStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer closeMeta()
+
+		blobs, err := openBlobStore(cmd)
+		if err != nil {
+			return err
+		}
+
+		ipLimitFlag, _ := cmd.Flags().GetString("ip-rate-limit")
+		ipLimits, err := server.ParseRateLimits(ipLimitFlag)
+		if err != nil {
+			return fmt.Errorf("--ip-rate-limit: %w", err)
+		}
+		tokenLimitFlag, _ := cmd.Flags().GetString("token-rate-limit")
+		tokenLimits, err := server.ParseRateLimits(tokenLimitFlag)
+		if err != nil {
+			return fmt.Errorf("--token-rate-limit: %w", err)
+		}
+
+		quotas, err := parseQuotas(cmd)
+		if err != nil {
+			return err
+		}
+
+		allowRepublish, _ := cmd.Flags().GetBool("allow-republish")
+		trustProxy, _ := cmd.Flags().GetBool("trust-proxy")
+		insecureOpen, _ := cmd.Flags().GetBool("insecure-open")
+		handler := server.NewHandler(meta, blobs, server.Options{
+			Token:           token,
+			InsecureOpen:    insecureOpen,
+			AllowRepublish:  allowRepublish,
+			TrustProxy:      trustProxy,
+			IPRateLimits:    ipLimits,
+			TokenRateLimits: tokenLimits,
+			Quotas:          quotas,
+		})
 
 		mux := http.NewServeMux()
 		handler.RegisterRoutes(mux)
@@ -31,9 +68,70 @@ var serveCmd = &cobra.Command{
 	},
 }
 
+// openMetaStore opens the metadata store selected by the --data-dir and
+// --db flags. The returned function closes it.
+func openMetaStore(cmd *cobra.Command) (server.MetaStore, func(), error) {
+	dataDir, _ := cmd.Flags().GetString("data-dir")
+	dsn, _ := cmd.Flags().GetString("db")
+	if dsn == "" {
+		return server.NewFSMetaStore(dataDir), func() {}, nil
+	}
+	sqlStore, err := server.OpenSQLMetaStore(dsn, dataDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sqlStore, func() { sqlStore.Close() }, nil
+}
+
+// openBlobStore opens the bundle store selected by the --data-dir and
+// --blob-store flags.
+func openBlobStore(cmd *cobra.Command) (server.BlobStore, error) {
+	dataDir, _ := cmd.Flags().GetString("data-dir")
+	blobURL, _ := cmd.Flags().GetString("blob-store")
+	if blobURL == "" {
+		return server.NewFSBlobStore(dataDir), nil
+	}
+	s3Store, err := server.NewS3BlobStoreFromURL(blobURL)
+	if err != nil {
+		return nil, err
+	}
+	return s3Store, nil
+}
+
+// parseQuotas reads the quota flags of serve.
+func parseQuotas(cmd *cobra.Command) (server.Quotas, error) {
+	var q server.Quotas
+	for _, f := range []struct {
+		name string
+		dst  *int64
+	}{
+		{"max-bundle-size", &q.MaxBundleSize},
+		{"max-storage-per-owner", &q.MaxBytesPerOwner},
+	} {
+		value, _ := cmd.Flags().GetString(f.name)
+		size, err := server.ParseByteSize(value)
+		if err != nil {
+			return q, fmt.Errorf("--%s: %w", f.name, err)
+		}
+		*f.dst = size
+	}
+	q.MaxVersionsPerSkill, _ = cmd.Flags().GetInt("max-versions-per-skill")
+	return q, nil
+}
+
 func init() {
 	serveCmd.Flags().Int("port", 8000, "Port to listen on")
-	serveCmd.Flags().String("data-dir", "/data", "Data directory for bundles and metadata")
-	serveCmd.Flags().String("token", "", "Bearer token required for publish (empty = no auth)")
+	serveCmd.PersistentFlags().String("data-dir", "/data", "Data directory for bundles and metadata")
+	serveCmd.PersistentFlags().String("db", "", "Metadata database DSN: sqlite:<path> or postgres://... (empty = meta.json files)")
+	serveCmd.PersistentFlags().String("blob-store", "", "Bundle storage URL: s3://bucket?endpoint=http://host:9000 (empty = data dir)")
+	serveCmd.Flags().String("token", "", "Shared admin token that may manage every skill (empty = no auth required until a user is created)")
+	serveCmd.Flags().Bool("insecure-open", false, "Without --token, let requests with no user token act as admin even after users are created")
+	serveCmd.Flags().Bool("allow-republish", false, "Allow overwriting an already published version (breaks existing lock files)")
+	serveCmd.Flags().Bool("trust-proxy", false, "Take client IPs from the last X-Forwarded-For address (only behind a reverse proxy that appends it)")
+	serveCmd.Flags().String("ip-rate-limit", "", "Request budgets per client IP, e.g. read=20/s,publish=30/m:5 (empty = unlimited)")
+	serveCmd.Flags().String("token-rate-limit", "", "Request budgets per API token, e.g. read=50/s,publish=60/m (empty = unlimited)")
+	serveCmd.Flags().String("max-bundle-size", "50MB", "Largest bundle that may be published (0 = unlimited)")
+	serveCmd.Flags().String("max-storage-per-owner", "0", "Bundle storage quota of each user and organization, e.g. 10GB (0 = unlimited)")
+	serveCmd.Flags().Int("max-versions-per-skill", 0, "Most versions a skill may have, including yanked ones (0 = unlimited)")
 	rootCmd.AddCommand(serveCmd)
 }
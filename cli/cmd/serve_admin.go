@@ -0,0 +1,253 @@
+//go:build server
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/liuyukai/agentskills-cli/server"
+	"github.com/spf13/cobra"
+)
+
+var serveAdminCmd = &cobra.Command{
+	Use:   "admin",
+	Short: "Administer the registry's data directory or database directly",
+	Long: `Admin commands operate on the same storage as "agentskills serve"
+(selected with --data-dir and --db) without going through the HTTP API.`,
+}
+
+var serveAdminCreateUserCmd = &cobra.Command{
+	Use:   "create-user <username>",
+	Short: "Register a user and print their first API token",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		username := args[0]
+		if err := server.ValidateUsername(username); err != nil {
+			return err
+		}
+		admin, _ := cmd.Flags().GetBool("admin")
+		tokenName, _ := cmd.Flags().GetString("token-name")
+		expires, _ := cmd.Flags().GetString("expires")
+		expiresAt, err := parseExpiry(expires)
+		if err != nil {
+			return err
+		}
+
+		meta, closeMeta, err := openMetaStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer closeMeta()
+
+		err = meta.CreateUser(server.User{Username: username, Admin: admin, CreatedAt: time.Now().UTC()})
+		if errors.Is(err, server.ErrUserExists) {
+			return fmt.Errorf("user %q already exists", username)
+		}
+		if err != nil {
+			return fmt.Errorf("creating user: %w", err)
+		}
+		token, secret := server.NewAPIToken(username, tokenName, expiresAt)
+		if err := meta.CreateToken(token); err != nil {
+			return fmt.Errorf("creating token: %w", err)
+		}
+
+		fmt.Printf("Created user %s", username)
+		if admin {
+			fmt.Print(" (admin)")
+		}
+		fmt.Printf("\nAPI token %q (id %s):\n\n  %s\n\nStore it now; it cannot be shown again.\n", tokenName, token.ID, secret)
+		return nil
+	},
+}
+
+var serveAdminCreateOrgCmd = &cobra.Command{
+	Use:   "create-org <org> --owner <username>",
+	Short: "Create an organization owned by a registered user",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := strings.TrimPrefix(args[0], "@")
+		if err := server.ValidateOrgName(name); err != nil {
+			return err
+		}
+		owner, _ := cmd.Flags().GetString("owner")
+
+		meta, closeMeta, err := openMetaStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer closeMeta()
+
+		u, err := meta.GetUser(owner)
+		if err != nil {
+			return fmt.Errorf("loading user: %w", err)
+		}
+		if u == nil {
+			return fmt.Errorf("user %q not found", owner)
+		}
+		err = meta.CreateOrg(server.Org{
+			Name:      name,
+			CreatedAt: time.Now().UTC().Truncate(time.Second),
+			Members:   []server.OrgMember{{Username: owner, Role: server.OrgRoleOwner}},
+		})
+		if errors.Is(err, server.ErrOrgExists) {
+			return fmt.Errorf("organization %q already exists", name)
+		}
+		if err != nil {
+			return fmt.Errorf("creating organization: %w", err)
+		}
+		fmt.Printf("Created organization %s owned by %s\n", name, owner)
+		return nil
+	},
+}
+
+var serveAdminPruneCmd = &cobra.Command{
+	Use:   "prune [skill...]",
+	Short: "Remove old prereleases according to the retention policy",
+	Long: `Remove the prerelease versions that the retention policy no longer keeps,
+from every skill or from the named ones. Stable versions are always kept, as
+are the newest --keep-prereleases prereleases of each skill, versions a
+dist-tag points at and versions downloaded within --keep-downloaded-within.
+Versions with no recorded download time, such as those last downloaded from
+an older server, count as downloaded when they were published.
+
+Pruned versions are recorded in the skill's metadata, so requests for them
+get 410 Gone, and they cannot be published again. Run "gc" afterwards to
+delete the bundles that no other version shares. Use --dry-run to see what
+would be removed first.`,
+	Example: `  agentskills serve admin prune --dry-run
+  agentskills serve admin prune --keep-prereleases 5 --keep-downloaded-within 14d code-review`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keep, _ := cmd.Flags().GetInt("keep-prereleases")
+		if keep < 0 {
+			return fmt.Errorf("--keep-prereleases must not be negative")
+		}
+		window, _ := cmd.Flags().GetString("keep-downloaded-within")
+		within, err := parseDays(window)
+		if err != nil {
+			return fmt.Errorf("--keep-downloaded-within: %w", err)
+		}
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		meta, closeMeta, err := openMetaStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer closeMeta()
+		blobs, err := openBlobStore(cmd)
+		if err != nil {
+			return err
+		}
+
+		policy := server.RetentionPolicy{KeepPrereleases: keep, KeepDownloadedWithin: within}
+		pruned, pruneErr := server.Prune(meta, blobs, policy, time.Now(), dryRun, args...)
+		if len(pruned) == 0 && pruneErr == nil {
+			fmt.Println("Nothing to prune.")
+			return nil
+		}
+
+		var total int64
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SKILL\tVERSION\tSIZE")
+		for _, p := range pruned {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", p.Skill, p.Version, formatSize(p.SizeBytes))
+			total += p.SizeBytes
+		}
+		w.Flush()
+		verb := "Removed"
+		if dryRun {
+			verb = "Would remove"
+		}
+		fmt.Printf("\n%s %d versions (%s).\n", verb, len(pruned), formatSize(total))
+		return pruneErr
+	},
+}
+
+var serveAdminGCCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Delete bundles that no version points at",
+	Long: `Delete the stored bundles that no published version points at, such as those
+of pruned or replaced versions. Bundles are stored once per sha256 digest and
+shared by every version with the same content.
+
+gc is safe to run while the server is running: bundles written within
+--grace are kept, as they may belong to a publish in progress. Bundles stored
+by skill name and version by older servers are moved to the digest layout.`,
+	Example: `  agentskills serve admin gc --dry-run
+  agentskills serve admin gc --db sqlite:/data/meta.db`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		graceFlag, _ := cmd.Flags().GetString("grace")
+		grace, err := parseDays(graceFlag)
+		if err != nil {
+			return fmt.Errorf("--grace: %w", err)
+		}
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		meta, closeMeta, err := openMetaStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer closeMeta()
+		blobs, err := openBlobStore(cmd)
+		if err != nil {
+			return err
+		}
+
+		res, err := server.CollectGarbage(meta, blobs, grace, time.Now(), dryRun)
+		for _, digest := range res.Removed {
+			fmt.Println(digest)
+		}
+		if err != nil {
+			return err
+		}
+		verb, moved := "Removed", "Moved"
+		if dryRun {
+			verb, moved = "Would remove", "Would move"
+		}
+		if res.Migrated > 0 {
+			fmt.Printf("%s %d bundles to the digest layout.\n", moved, res.Migrated)
+		}
+		fmt.Printf("%s %d of %d blobs (%d referenced).\n", verb, len(res.Removed), res.Blobs, res.Referenced)
+		return nil
+	},
+}
+
+// parseDays parses a number of days ("30d") or a Go duration ("12h").
+func parseDays(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q: want a number of days like 30d", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q: want e.g. 30d or 12h", s)
+	}
+	return d, nil
+}
+
+func init() {
+	serveAdminPruneCmd.Flags().Int("keep-prereleases", 10, "Prereleases to keep per skill, newest first")
+	serveAdminPruneCmd.Flags().String("keep-downloaded-within", "30d", "Keep versions downloaded within this time, e.g. 30d or 12h")
+	serveAdminPruneCmd.Flags().Bool("dry-run", false, "Only report what would be removed")
+	serveAdminCmd.AddCommand(serveAdminPruneCmd)
+	serveAdminGCCmd.Flags().String("grace", "1h", "Keep unreferenced bundles written within this time, e.g. 1h or 1d")
+	serveAdminGCCmd.Flags().Bool("dry-run", false, "Only report what would be removed")
+	serveAdminCmd.AddCommand(serveAdminGCCmd)
+	serveAdminCreateOrgCmd.Flags().String("owner", "", "Username of the first owner (required)")
+	serveAdminCreateOrgCmd.MarkFlagRequired("owner")
+	serveAdminCmd.AddCommand(serveAdminCreateOrgCmd)
+	serveAdminCreateUserCmd.Flags().Bool("admin", false, "Allow the user to manage every skill")
+	serveAdminCreateUserCmd.Flags().String("token-name", "default", "Name of the initial API token")
+	serveAdminCreateUserCmd.Flags().String("expires", "", "Expiry of the initial token: a duration like 90d or 12h, or a date (default never)")
+	serveAdminCmd.AddCommand(serveAdminCreateUserCmd)
+	serveCmd.AddCommand(serveAdminCmd)
+}
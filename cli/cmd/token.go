@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage your registry API tokens",
+}
+
+var tokenCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create an API token, e.g. for CI",
+	Long: `Create an API token. Without --scope the token has all of your rights.
+
+A scope is <permission>[:<skill>[,<skill>...]] where permission is one of
+read, publish (publish versions, move dist-tags), yank or admin (deprecate
+skills, manage tokens; implies the others), and skills are names or globs
+such as @acme/* for every skill of an organization.`,
+	Example: `  agentskills token create laptop
+  agentskills token create ci-code-review --scope publish:code-review-* --expires 90d
+  agentskills token create release --scope publish:lint,format --scope yank:lint`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		scopes, _ := cmd.Flags().GetStringArray("scope")
+		expires, _ := cmd.Flags().GetString("expires")
+		expiresAt, err := parseExpiry(expires)
+		if err != nil {
+			return err
+		}
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+		created, err := client.CreateToken(args[0], expiresAt, scopes)
+		if err != nil {
+			return fmt.Errorf("creating token: %w", err)
+		}
+
+		fmt.Printf("Created token %q (id %s)", created.Name, created.ID)
+		if created.ExpiresAt != nil {
+			fmt.Printf(", expires %s", created.ExpiresAt.Local().Format(time.RFC3339))
+		}
+		if len(created.Scopes) > 0 {
+			fmt.Printf(", scopes %s", strings.Join(created.Scopes, " "))
+		}
+		fmt.Printf(".\n\n  %s\n\nStore it now; it cannot be shown again.\n", created.Token)
+		return nil
+	},
+}
+
+var tokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List your API tokens",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+		tokens, err := client.ListTokens()
+		if err != nil {
+			return fmt.Errorf("listing tokens: %w", err)
+		}
+		if len(tokens) == 0 {
+			fmt.Println("No tokens found.")
+			return nil
+		}
+
+		now := time.Now()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tSCOPES\tCREATED\tLAST USED\tEXPIRES")
+		for _, t := range tokens {
+			expires := formatTime(t.ExpiresAt, "never")
+			if t.ExpiresAt != nil && !now.Before(*t.ExpiresAt) {
+				expires += " (expired)"
+			}
+			scopes := "all"
+			if len(t.Scopes) > 0 {
+				scopes = strings.Join(t.Scopes, " ")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				t.ID, t.Name, scopes, formatTime(&t.CreatedAt, ""), formatTime(t.LastUsedAt, "never"), expires)
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+var tokenRevokeCmd = &cobra.Command{
+	Use:   "revoke <id>",
+	Short: "Revoke an API token",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+		if err := client.RevokeToken(args[0]); err != nil {
+			return fmt.Errorf("revoking token: %w", err)
+		}
+		fmt.Printf("Revoked token %s\n", args[0])
+		return nil
+	},
+}
+
+// parseExpiry turns an --expires value into an absolute time. It accepts a
+// number of days ("90d"), a Go duration ("12h"), a date ("2026-12-31") or an
+// RFC 3339 timestamp. An empty value means the token never expires.
+func parseExpiry(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var t time.Time
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid expiry %q: want a positive number of days like 90d", s)
+		}
+		t = time.Now().AddDate(0, 0, n)
+	} else if d, err := time.ParseDuration(s); err == nil {
+		if d <= 0 {
+			return nil, fmt.Errorf("invalid expiry %q: must be in the future", s)
+		}
+		t = time.Now().Add(d)
+	} else if t, err = time.Parse(time.RFC3339, s); err != nil {
+		if t, err = time.ParseInLocation("2006-01-02", s, time.Local); err != nil {
+			return nil, fmt.Errorf("invalid expiry %q: want e.g. 90d, 12h, 2026-12-31 or an RFC 3339 time", s)
+		}
+	}
+	t = t.UTC().Truncate(time.Second)
+	return &t, nil
+}
+
+func formatTime(t *time.Time, zero string) string {
+	if t == nil || t.IsZero() {
+		return zero
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func init() {
+	tokenCreateCmd.Flags().StringArray("scope", nil, "Limit the token, e.g. publish:code-review-* (repeatable)")
+	tokenCreateCmd.Flags().String("expires", "", "Expiry: a duration like 90d or 12h, or a date (default never)")
+	tokenCmd.AddCommand(tokenCreateCmd, tokenListCmd, tokenRevokeCmd)
+	rootCmd.AddCommand(tokenCmd)
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var usageCmd = &cobra.Command{
+	Use:   "usage [user|@org]",
+	Short: "Show storage used and the registry's quotas",
+	Long: `Show the bundle storage charged to a user or organization, per skill, and
+the registry's quotas. Skills scoped to an organization count against it;
+other skills count against their owner.
+
+Without an argument, shows your own usage. Only admins may see other users'
+usage, and only members may see an organization's.`,
+	Example: `  agentskills usage
+  agentskills usage @acme`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+		var owner string
+		if len(args) == 1 {
+			owner = args[0]
+		} else {
+			who, err := client.WhoAmI()
+			if err != nil {
+				return fmt.Errorf("checking token: %w", err)
+			}
+			if who.Username == "" {
+				return fmt.Errorf("not logged in as a user; name the user or @org to show")
+			}
+			owner = who.Username
+		}
+
+		usage, err := client.Usage(owner)
+		if err != nil {
+			return fmt.Errorf("fetching usage: %w", err)
+		}
+		q := usage.Quotas
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "Owner:\t%s\n", usage.Owner)
+		fmt.Fprintf(w, "Storage:\t%s of %s\n", formatSize(usage.Bytes), formatLimit(q.MaxBytes, formatSize))
+		fmt.Fprintf(w, "Skills:\t%d\n", len(usage.Skills))
+		fmt.Fprintf(w, "Versions:\t%d (per skill: %s)\n", usage.Versions,
+			formatLimit(int64(q.MaxVersionsPerSkill), func(n int64) string { return fmt.Sprint(n) }))
+		fmt.Fprintf(w, "Max bundle size:\t%s\n", formatLimit(q.MaxBundleSize, formatSize))
+		w.Flush()
+
+		if len(usage.Skills) == 0 {
+			return nil
+		}
+		fmt.Println()
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SKILL\tVERSIONS\tSIZE")
+		for _, s := range usage.Skills {
+			fmt.Fprintf(w, "%s\t%d\t%s\n", s.Name, s.Versions, formatSize(s.Bytes))
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+// formatLimit renders a quota, where zero means unlimited.
+func formatLimit(n int64, format func(int64) string) string {
+	if n == 0 {
+		return "unlimited"
+	}
+	return format(n)
+}
+
+func init() {
+	rootCmd.AddCommand(usageCmd)
+}
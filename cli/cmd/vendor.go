@@ -8,10 +8,10 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/liuyukai/agentskills-cli/internal/api"
 	"github.com/liuyukai/agentskills-cli/internal/bundle"
 	"github.com/liuyukai/agentskills-cli/internal/config"
 	"github.com/liuyukai/agentskills-cli/internal/lockfile"
+	"github.com/liuyukai/agentskills-cli/internal/parser"
 	"github.com/spf13/cobra"
 )
 
@@ -24,6 +24,8 @@ var vendorCmd = &cobra.Command{
 version and checksum in agentskills.lock.
 
   agentskills vendor code-review@1.2.0   # vendor a specific skill
+  agentskills vendor code-review@^1      # vendor the highest matching 1.x
+  agentskills vendor @acme/code-review   # vendor/skills/@acme/code-review
   agentskills vendor                      # restore all skills from lock file
   agentskills vendor --remove code-review # remove a vendored skill`,
 	Args: cobra.MaximumNArgs(1),
@@ -46,38 +48,31 @@ func init() {
 
 // vendorAdd downloads a single skill and records it in the lock file.
 func vendorAdd(cmd *cobra.Command, nameVersion string) error {
-	name, version := parsePullArg(nameVersion)
+	name, spec := parsePullArg(nameVersion)
 
-	cfg, err := config.Load()
+	cfg, err := loadConfig(cmd)
 	if err != nil {
-		return fmt.Errorf("loading config: %w", err)
-	}
-	if cmd.Flags().Changed("api-url") {
-		apiURL, _ := cmd.Flags().GetString("api-url")
-		cfg.APIURL = apiURL
+		return err
 	}
-
-	client := api.NewClient(cfg.APIURL, cfg.Token)
+	client := clientFor(cfg)
 
 	// Resolve version
-	if version == "" {
-		fmt.Printf("Fetching latest version of %s...\n", name)
-		info, err := client.GetSkill(name)
-		if err != nil {
-			return fmt.Errorf("fetching skill info: %w", err)
-		}
-		version = info.LatestVersion.Version
+	version, err := resolveVersion(client, name, spec)
+	if err != nil {
+		return err
 	}
 
 	// Download
 	fmt.Printf("Downloading %s@%s...  ", name, version)
-	tmpFile, expectedChecksum, err := client.Download(name, version)
+	dl, err := client.Download(name, version)
 	if err != nil {
 		fmt.Println("x")
 		return fmt.Errorf("download failed: %w", err)
 	}
+	tmpFile, expectedChecksum := dl.Path, dl.Checksum
 	defer os.Remove(tmpFile)
 	fmt.Println("ok")
+	warnDownload(name, version, dl)
 
 	// Verify checksum
 	checksum, err := fileChecksum(tmpFile)
@@ -88,12 +83,15 @@ func vendorAdd(cmd *cobra.Command, nameVersion string) error {
 		return fmt.Errorf("checksum mismatch: expected=%s actual=%s", expectedChecksum, checksum)
 	}
 
-	// Extract to vendor/skills/<name>/
-	destDir := filepath.Join(vendorDir, name)
+	// Extract to vendor/skills/<name>/ or vendor/skills/@<org>/<name>/
+	destDir, err := vendorPath(name)
+	if err != nil {
+		return err
+	}
 	if err := os.RemoveAll(destDir); err != nil {
 		return fmt.Errorf("cleaning vendor dir: %w", err)
 	}
-	if err := os.MkdirAll(vendorDir, 0o755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(destDir), 0o755); err != nil {
 		return fmt.Errorf("creating vendor dir: %w", err)
 	}
 	if err := bundle.Unpack(tmpFile, destDir); err != nil {
@@ -108,11 +106,15 @@ func vendorAdd(cmd *cobra.Command, nameVersion string) error {
 	if lf == nil {
 		lf = &lockfile.LockFile{Skills: make(map[string]lockfile.LockedSkill)}
 	}
-	lf.Set(name, lockfile.LockedSkill{
+	locked := lockfile.LockedSkill{
 		Version:  version,
 		Checksum: "sha256:" + checksum,
 		Source:   cfg.APIURL,
-	})
+	}
+	if spec != version {
+		locked.Constraint = spec
+	}
+	lf.Set(name, locked)
 	if err := lockfile.Save(lockfile.Filename, lf); err != nil {
 		return fmt.Errorf("saving lock file: %w", err)
 	}
@@ -133,7 +135,13 @@ func vendorSync(cmd *cobra.Command) error {
 		return nil
 	}
 
-	cfg, err := config.Load()
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+	// Skills may come from different registries; each is fetched with the
+	// token saved for its source.
+	f, err := config.LoadFile()
 	if err != nil {
 		return fmt.Errorf("loading config: %w", err)
 	}
@@ -142,20 +150,21 @@ func vendorSync(cmd *cobra.Command) error {
 
 	var failed []string
 	for name, locked := range lf.Skills {
-		source := locked.Source
-		if source == "" {
-			source = cfg.APIURL
+		source, token := locked.Source, f.TokenFor(locked.Source)
+		if source == "" || source == cfg.APIURL {
+			source, token = cfg.APIURL, cfg.Token
 		}
-		client := api.NewClient(source, cfg.Token)
+		client := sourceClient(cfg, source, token)
 
 		fmt.Printf("  %s@%s...  ", name, locked.Version)
 
-		tmpFile, _, err := client.Download(name, locked.Version)
+		dl, err := client.Download(name, locked.Version)
 		if err != nil {
 			fmt.Println("x (download failed)")
 			failed = append(failed, name)
 			continue
 		}
+		tmpFile := dl.Path
 
 		// Verify checksum against lock file
 		checksum, err := fileChecksum(tmpFile)
@@ -175,7 +184,13 @@ func vendorSync(cmd *cobra.Command) error {
 		}
 
 		// Extract
-		destDir := filepath.Join(vendorDir, name)
+		destDir, err := vendorPath(name)
+		if err != nil {
+			os.Remove(tmpFile)
+			fmt.Printf("x (%v)\n", err)
+			failed = append(failed, name)
+			continue
+		}
 		os.RemoveAll(destDir)
 		if err := bundle.Unpack(tmpFile, destDir); err != nil {
 			os.Remove(tmpFile)
@@ -186,6 +201,7 @@ func vendorSync(cmd *cobra.Command) error {
 		os.Remove(tmpFile)
 
 		fmt.Println("ok")
+		warnDownload(name, locked.Version, dl)
 	}
 
 	if len(failed) > 0 {
@@ -198,10 +214,18 @@ func vendorSync(cmd *cobra.Command) error {
 
 // vendorRemove removes a vendored skill and its lock file entry.
 func vendorRemove(name string) error {
-	destDir := filepath.Join(vendorDir, name)
+	destDir, err := vendorPath(name)
+	if err != nil {
+		return err
+	}
 	if err := os.RemoveAll(destDir); err != nil {
 		return fmt.Errorf("removing vendor dir: %w", err)
 	}
+	if parent := filepath.Dir(destDir); parent != filepath.Clean(vendorDir) {
+		// Drop the @org directory once its last skill is gone; Remove
+		// fails harmlessly while it is not empty.
+		os.Remove(parent)
+	}
 
 	lf, err := lockfile.Load(lockfile.Filename)
 	if err != nil {
@@ -218,6 +242,16 @@ func vendorRemove(name string) error {
 	return nil
 }
 
+// vendorPath returns the directory a skill is vendored into. Scoped skills
+// are nested under their organization, e.g. vendor/skills/@acme/code-review.
+// The name is validated first, since lock file keys must not escape vendorDir.
+func vendorPath(name string) (string, error) {
+	if err := parser.ValidateName(name); err != nil {
+		return "", fmt.Errorf("invalid skill name: %w", err)
+	}
+	return filepath.Join(vendorDir, filepath.FromSlash(name)), nil
+}
+
 // fileChecksum returns the hex-encoded SHA256 checksum of a file.
 func fileChecksum(path string) (string, error) {
 	f, err := os.Open(path)
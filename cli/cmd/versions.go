@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/liuyukai/agentskills-cli/internal/api"
+	"github.com/liuyukai/agentskills-cli/internal/semver"
+	"github.com/spf13/cobra"
+)
+
+var versionsCmd = &cobra.Command{
+	Use:   "versions <name>",
+	Short: "List published versions of a skill, newest first",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		page, _ := cmd.Flags().GetInt("page")
+		perPage, _ := cmd.Flags().GetInt("per-page")
+
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+
+		client := clientFor(cfg)
+		list, err := client.ListVersionsPage(args[0], page, perPage, true)
+		if err != nil {
+			return fmt.Errorf("listing versions: %w", err)
+		}
+		if len(list.Versions) == 0 {
+			fmt.Println("No versions found.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tPUBLISHED\tSIZE\tSTATUS")
+		for _, v := range list.Versions {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", v.Version, v.PublishedAt, formatSize(v.SizeBytes), versionStatus(v))
+		}
+		w.Flush()
+
+		if pages := (list.Total + list.PerPage - 1) / list.PerPage; pages > 1 {
+			fmt.Printf("\nPage %d of %d (%d versions). Use --page to see more.\n", list.Page, pages, list.Total)
+		}
+		return nil
+	},
+}
+
+func versionStatus(v api.VersionInfo) string {
+	switch {
+	case v.Yanked && v.YankReason != "":
+		return "yanked: " + v.YankReason
+	case v.Yanked:
+		return "yanked"
+	}
+	if sv, err := semver.Parse(v.Version); err == nil && sv.IsPrerelease() {
+		return "prerelease"
+	}
+	return ""
+}
+
+func init() {
+	versionsCmd.Flags().Int("page", 1, "Page to show")
+	versionsCmd.Flags().Int("per-page", 50, "Versions per page (max 100)")
+	rootCmd.AddCommand(versionsCmd)
+}
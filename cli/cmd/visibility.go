@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var visibilityCmd = &cobra.Command{
+	Use:   "visibility <name> <public|internal|private>",
+	Short: "Change who can see and download a skill",
+	Long: `Change who can see and download a skill:
+
+  public    everyone, including clients without a token
+  internal  every user authenticated with the registry
+  private   the owner, collaborators and registry admins
+
+Skills a user cannot see do not appear in search and are reported as not
+found. A visibility: field in SKILL.md frontmatter sets it on publish.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, visibility := args[0], args[1]
+		switch visibility {
+		case "public", "internal", "private":
+		default:
+			return fmt.Errorf("invalid visibility %q: want public, internal or private", visibility)
+		}
+
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+		if err := client.SetVisibility(name, visibility); err != nil {
+			return fmt.Errorf("setting visibility: %w", err)
+		}
+		fmt.Printf("%s is now %s\n", name, visibility)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(visibilityCmd)
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var yankCmd = &cobra.Command{
+	Use:   "yank <name>@<version>",
+	Short: "Mark a published version as yanked",
+	Long: `Mark a published version as yanked so it is no longer resolved as latest.
+Yanked versions stay downloadable by exact version, with a warning.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, version := parsePullArg(args[0])
+		if version == "" {
+			return fmt.Errorf("version required: agentskills yank %s@<version>", name)
+		}
+
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		client := clientFor(cfg)
+
+		undo, _ := cmd.Flags().GetBool("undo")
+		if undo {
+			if _, err := client.Unyank(name, version); err != nil {
+				return fmt.Errorf("un-yank failed: %w", err)
+			}
+			fmt.Printf("Restored %s@%s\n", name, version)
+			return nil
+		}
+
+		reason, _ := cmd.Flags().GetString("reason")
+		if _, err := client.Yank(name, version, reason); err != nil {
+			return fmt.Errorf("yank failed: %w", err)
+		}
+		fmt.Printf("Yanked %s@%s\n", name, version)
+		return nil
+	},
+}
+
+func init() {
+	yankCmd.Flags().String("reason", "", "Reason shown to users who still download this version")
+	yankCmd.Flags().Bool("undo", false, "Restore a previously yanked version")
+	rootCmd.AddCommand(yankCmd)
+}
@@ -1,19 +1,32 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
-	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Client struct {
 	baseURL    string
 	token      string
 	httpClient *http.Client
+
+	// creds supplies the token on first use when none was given.
+	creds       Credentials
+	credsLoaded bool
+}
+
+// Credentials looks up the token of a registry on demand, for example by
+// asking a credential helper.
+type Credentials interface {
+	Get(apiURL string) (string, error)
 }
 
 type PublishResult struct {
@@ -24,25 +37,54 @@ type PublishResult struct {
 }
 
 type SkillInfo struct {
-	Name          string        `json:"name"`
-	Owner         string        `json:"owner"`
-	Downloads     int64         `json:"downloads"`
-	LatestVersion VersionInfo   `json:"latest_version"`
+	Name          string                 `json:"name"`
+	Description   string                 `json:"description"`
+	Owner         string                 `json:"owner"`
+	Tags          []string               `json:"tags"`
+	License       string                 `json:"license"`
+	Downloads     int64                  `json:"downloads"`
+	Deprecated    string                 `json:"deprecated"`
+	Visibility    string                 `json:"visibility"`
+	LatestVersion VersionInfo            `json:"latest_version"`
+	DistTags      map[string]string      `json:"dist_tags"`
+	Frontmatter   map[string]interface{} `json:"frontmatter"`
+	Versions      []VersionInfo          `json:"versions"`
 }
 
 type VersionInfo struct {
 	Version     string `json:"version"`
 	Description string `json:"description"`
+	License     string `json:"license"`
 	Checksum    string `json:"checksum"`
 	SizeBytes   int64  `json:"size_bytes"`
 	PublishedAt string `json:"published_at"`
+	Yanked      bool   `json:"yanked"`
+	YankReason  string `json:"yank_reason"`
+}
+
+// VersionList is one page of a skill's versions in semver order.
+type VersionList struct {
+	Name     string        `json:"name"`
+	Total    int           `json:"total"`
+	Page     int           `json:"page"`
+	PerPage  int           `json:"per_page"`
+	Versions []VersionInfo `json:"versions"`
+}
+
+// DownloadResult is a bundle downloaded to a temporary file.
+type DownloadResult struct {
+	Path       string // temp file; the caller must remove it
+	Checksum   string // hex SHA-256 reported by the server
+	Yanked     bool
+	YankReason string
+	Deprecated string
 }
 
 type SearchResult struct {
-	Total   int            `json:"total"`
-	Page    int            `json:"page"`
-	PerPage int            `json:"per_page"`
-	Results []SearchEntry  `json:"results"`
+	Total   int           `json:"total"`
+	Page    int           `json:"page"`
+	PerPage int           `json:"per_page"`
+	Results []SearchEntry `json:"results"`
 }
 
 type SearchEntry struct {
@@ -52,6 +94,167 @@ type SearchEntry struct {
 	Downloads     int64    `json:"downloads"`
 	LatestVersion string   `json:"latest_version"`
 	Tags          []string `json:"tags"`
+	Deprecated    string   `json:"deprecated"`
+	Visibility    string   `json:"visibility"`
+}
+
+// TokenInfo describes an API token without its secret.
+type TokenInfo struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	Scopes     []string   `json:"scopes"`
+}
+
+// CreatedToken is a newly created API token. Token is the secret and is
+// only ever returned once.
+type CreatedToken struct {
+	TokenInfo
+	Token string `json:"token"`
+}
+
+// WhoAmI describes the identity the registry sees for the client's token.
+type WhoAmI struct {
+	Username string `json:"username"`
+	Admin    bool   `json:"admin"`
+	// Auth is "token" for a user API token, "admin_token" for the server's
+	// shared admin token and "none" if the server requires no auth.
+	Auth  string     `json:"auth"`
+	Token *TokenInfo `json:"token"`
+}
+
+// Owners lists who manages a skill. Owner is a username, or "@<org>" for a
+// skill owned by an organization.
+type Owners struct {
+	Name          string       `json:"name"`
+	Owner         string       `json:"owner"`
+	Collaborators []string     `json:"collaborators"`
+	History       []OwnerEvent `json:"history"`
+}
+
+// OwnerEvent is one recorded change of a skill's owner or collaborators.
+type OwnerEvent struct {
+	Action string    `json:"action"` // add_collaborator, remove_collaborator or transfer
+	User   string    `json:"user"`
+	From   string    `json:"from"`
+	Actor  string    `json:"actor"`
+	At     time.Time `json:"at"`
+}
+
+// Org is an organization and its members.
+type Org struct {
+	Name      string      `json:"name"`
+	CreatedAt time.Time   `json:"created_at"`
+	Members   []OrgMember `json:"members"`
+}
+
+// OrgMember is a user's membership in an organization: "owner" or "member".
+type OrgMember struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// AuditEvent is one entry of the registry's audit log.
+type AuditEvent struct {
+	ID       string    `json:"id"`
+	Time     time.Time `json:"time"`
+	Actor    string    `json:"actor"`
+	TokenID  string    `json:"token_id"`
+	Action   string    `json:"action"`
+	Target   string    `json:"target"` // skill name, "@<org>" or token id
+	Version  string    `json:"version"`
+	Checksum string    `json:"checksum"`
+	Detail   string    `json:"detail"`
+	ClientIP string    `json:"client_ip"`
+}
+
+// AuditFilter selects audit events; zero fields match everything.
+type AuditFilter struct {
+	Skill string // event target: a skill name, "@<org>" or a token id
+	Actor string
+	Since time.Time
+	Limit int // only the newest Limit events
+}
+
+func (f AuditFilter) path(format string) string {
+	q := url.Values{}
+	if f.Skill != "" {
+		q.Set("skill", f.Skill)
+	}
+	if f.Actor != "" {
+		q.Set("actor", f.Actor)
+	}
+	if !f.Since.IsZero() {
+		q.Set("since", f.Since.UTC().Format(time.RFC3339))
+	}
+	if f.Limit > 0 {
+		q.Set("limit", strconv.Itoa(f.Limit))
+	}
+	if format != "" {
+		q.Set("format", format)
+	}
+	if len(q) == 0 {
+		return "/v1/audit"
+	}
+	return "/v1/audit?" + q.Encode()
+}
+
+// Usage is the storage charged to a user or organization.
+type Usage struct {
+	Owner    string       `json:"owner"`
+	Bytes    int64        `json:"bytes"`
+	Versions int          `json:"versions"`
+	Skills   []SkillUsage `json:"skills"`
+	Quotas   Quotas       `json:"quotas"`
+}
+
+// SkillUsage is the storage used by the versions of one skill.
+type SkillUsage struct {
+	Name     string `json:"name"`
+	Versions int    `json:"versions"`
+	Bytes    int64  `json:"bytes"`
+}
+
+// Quotas are the registry's storage limits; zero means unlimited.
+type Quotas struct {
+	MaxBundleSize       int64 `json:"max_bundle_size"`
+	MaxVersionsPerSkill int   `json:"max_versions_per_skill"`
+	MaxBytes            int64 `json:"max_bytes"`
+}
+
+// Error codes returned by the registry in the "code" field of error responses.
+const (
+	CodeVersionExists = "version_exists"
+	CodeForbidden     = "forbidden"
+)
+
+// APIError is a non-success response from the registry.
+type APIError struct {
+	StatusCode int
+	Code       string // machine-readable code, empty for non-JSON responses
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("server returned %d: %s", e.StatusCode, e.Message)
+}
+
+// decodeError builds an APIError from a response, accepting both JSON
+// {"error": ..., "code": ...} bodies and plain text.
+func decodeError(resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+	apiErr := &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+	var payload struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+	}
+	if json.Unmarshal(body, &payload) == nil && payload.Error != "" {
+		apiErr.Message = payload.Error
+		apiErr.Code = payload.Code
+	}
+	return apiErr
 }
 
 func NewClient(baseURL, token string) *Client {
@@ -62,35 +265,127 @@ func NewClient(baseURL, token string) *Client {
 	}
 }
 
-func (c *Client) Publish(bundlePath string) (*PublishResult, error) {
-	f, err := os.Open(bundlePath)
+// WithCredentials makes the client ask creds for its token before the first
+// authenticated request, unless it was created with a token.
+func (c *Client) WithCredentials(creds Credentials) *Client {
+	c.creds = creds
+	return c
+}
+
+// authorize sets the Authorization header of req if the client has a token.
+func (c *Client) authorize(req *http.Request) error {
+	if c.token == "" && c.creds != nil && !c.credsLoaded {
+		token, err := c.creds.Get(c.baseURL)
+		if err != nil {
+			return fmt.Errorf("getting token: %w", err)
+		}
+		c.token, c.credsLoaded = token, true
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return nil
+}
+
+// get sends an authenticated GET request, so that private skills are
+// visible to the client's user.
+func (c *Client) get(u string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
 	if err != nil {
-		return nil, fmt.Errorf("opening bundle: %w", err)
+		return nil, fmt.Errorf("creating request: %w", err)
 	}
-	defer f.Close()
+	if err := c.authorize(req); err != nil {
+		return nil, err
+	}
+	return c.do(req)
+}
+
+// Retries of requests rejected by the registry's rate limits.
+const (
+	maxRateLimitRetries = 3
+	// Longer waits are reported as errors rather than blocking the command.
+	maxRetryAfter = time.Minute
+)
+
+// do sends req. If the registry answers 429 Too Many Requests with a
+// Retry-After of at most maxRetryAfter, it waits that long and sends the
+// request again, provided its body can be replayed.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := c.httpClient.Do(req)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || attempt == maxRateLimitRetries {
+			return resp, err
+		}
+		wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if !ok || wait > maxRetryAfter || req.Body != nil && req.GetBody == nil {
+			return resp, nil
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
 
-	// Create multipart request
-	pr, pw := io.Pipe()
-	writer := newMultipartWriter(pw, filepath.Base(bundlePath), f)
+// parseRetryAfter reads a Retry-After header, which is either a number of
+// seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(secs, 0)) * time.Second, true
+	}
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	return max(t.Sub(now), 0), true
+}
 
-	req, err := http.NewRequest("POST", c.baseURL+"/v1/skills/publish", pr)
+// Publish uploads a bundle. A non-empty distTag is pointed at the new version.
+func (c *Client) Publish(bundlePath, distTag string) (*PublishResult, error) {
+	var fields map[string]string
+	if distTag != "" {
+		fields = map[string]string{"tag": distTag}
+	}
+	form := newMultipartForm(bundlePath, fields)
+	body, err := form.open()
 	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+		return nil, err
 	}
-	req.Header.Set("Authorization", "Bearer "+c.token)
-	req.Header.Set("Content-Type", writer.contentType)
 
-	go writer.write()
+	req, err := http.NewRequest("POST", c.baseURL+"/v1/skills/publish", body)
+	if err != nil {
+		body.Close()
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.GetBody = form.open
+	if err := c.authorize(req); err != nil {
+		body.Close()
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.contentType)
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("sending request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("server returned %d: %s", resp.StatusCode, string(body))
+		return nil, decodeError(resp)
 	}
 
 	var result PublishResult
@@ -101,7 +396,7 @@ func (c *Client) Publish(bundlePath string) (*PublishResult, error) {
 }
 
 func (c *Client) GetSkill(name string) (*SkillInfo, error) {
-	resp, err := c.httpClient.Get(c.baseURL + "/v1/skills/" + url.PathEscape(name))
+	resp, err := c.get(c.baseURL + "/v1/skills/" + url.PathEscape(name))
 	if err != nil {
 		return nil, fmt.Errorf("fetching skill: %w", err)
 	}
@@ -111,8 +406,7 @@ func (c *Client) GetSkill(name string) (*SkillInfo, error) {
 		return nil, fmt.Errorf("skill %q not found", name)
 	}
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("server returned %d: %s", resp.StatusCode, string(body))
+		return nil, decodeError(resp)
 	}
 
 	var info SkillInfo
@@ -122,48 +416,96 @@ func (c *Client) GetSkill(name string) (*SkillInfo, error) {
 	return &info, nil
 }
 
-func (c *Client) Download(name, version string) (tmpFile string, checksum string, err error) {
+// ListVersions returns every published version of a skill in ascending
+// semver order, including yanked ones, fetching all pages.
+func (c *Client) ListVersions(name string) ([]VersionInfo, error) {
+	var all []VersionInfo
+	for page := 1; ; page++ {
+		list, err := c.ListVersionsPage(name, page, 100, false)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, list.Versions...)
+		if len(list.Versions) == 0 || len(all) >= list.Total {
+			return all, nil
+		}
+	}
+}
+
+// ListVersionsPage returns one page of a skill's versions, newest first if desc.
+func (c *Client) ListVersionsPage(name string, page, perPage int, desc bool) (*VersionList, error) {
+	q := url.Values{}
+	q.Set("page", strconv.Itoa(page))
+	q.Set("per_page", strconv.Itoa(perPage))
+	if desc {
+		q.Set("order", "desc")
+	}
+	resp, err := c.get(c.baseURL + "/v1/skills/" + url.PathEscape(name) + "/versions?" + q.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("listing versions: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("skill %q not found", name)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, decodeError(resp)
+	}
+
+	var list VersionList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	return &list, nil
+}
+
+func (c *Client) Download(name, version string) (*DownloadResult, error) {
 	u := fmt.Sprintf("%s/v1/skills/%s/versions/%s/download",
 		c.baseURL, url.PathEscape(name), url.PathEscape(version))
 
-	resp, err := c.httpClient.Get(u)
+	resp, err := c.get(u)
 	if err != nil {
-		return "", "", fmt.Errorf("downloading: %w", err)
+		return nil, fmt.Errorf("downloading: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", "", fmt.Errorf("server returned %d: %s", resp.StatusCode, string(body))
+		return nil, decodeError(resp)
 	}
 
-	checksum = resp.Header.Get("X-Checksum-SHA256")
+	result := &DownloadResult{
+		Checksum:   resp.Header.Get("X-Checksum-SHA256"),
+		Yanked:     resp.Header.Get("X-Skill-Yanked") == "true",
+		YankReason: resp.Header.Get("X-Skill-Yank-Reason"),
+		Deprecated: resp.Header.Get("X-Skill-Deprecated"),
+	}
 
 	f, err := os.CreateTemp("", "agentskills-*.tar.gz")
 	if err != nil {
-		return "", "", fmt.Errorf("creating temp file: %w", err)
+		return nil, fmt.Errorf("creating temp file: %w", err)
 	}
 	defer f.Close()
 
 	if _, err := io.Copy(f, resp.Body); err != nil {
 		os.Remove(f.Name())
-		return "", "", fmt.Errorf("writing download: %w", err)
+		return nil, fmt.Errorf("writing download: %w", err)
 	}
 
-	return f.Name(), checksum, nil
+	result.Path = f.Name()
+	return result, nil
 }
 
 func (c *Client) Search(keyword string) (*SearchResult, error) {
 	u := fmt.Sprintf("%s/v1/skills?q=%s", c.baseURL, url.QueryEscape(keyword))
-	resp, err := c.httpClient.Get(u)
+	resp, err := c.get(u)
 	if err != nil {
 		return nil, fmt.Errorf("searching: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("server returned %d: %s", resp.StatusCode, string(body))
+		return nil, decodeError(resp)
 	}
 
 	var result SearchResult
@@ -172,3 +514,274 @@ func (c *Client) Search(keyword string) (*SearchResult, error) {
 	}
 	return &result, nil
 }
+
+// Yank marks a version as yanked so it is no longer resolved as latest.
+func (c *Client) Yank(name, version, reason string) (*VersionInfo, error) {
+	var info VersionInfo
+	body := map[string]string{"reason": reason}
+	if err := c.doJSON(http.MethodPost, versionPath(name, version)+"/yank", body, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// Unyank reverses Yank.
+func (c *Client) Unyank(name, version string) (*VersionInfo, error) {
+	var info VersionInfo
+	if err := c.doJSON(http.MethodDelete, versionPath(name, version)+"/yank", nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// Deprecate sets the skill's deprecation message; an empty message clears it.
+func (c *Client) Deprecate(name, message string) error {
+	body := map[string]string{"message": message}
+	return c.doJSON(http.MethodPost, "/v1/skills/"+url.PathEscape(name)+"/deprecate", body, nil)
+}
+
+// SetVisibility changes who can see a skill: public, internal or private.
+func (c *Client) SetVisibility(name, visibility string) error {
+	body := map[string]string{"visibility": visibility}
+	return c.doJSON(http.MethodPut, "/v1/skills/"+url.PathEscape(name)+"/visibility", body, nil)
+}
+
+// DistTags returns the skill's dist-tags, including "latest".
+func (c *Client) DistTags(name string) (map[string]string, error) {
+	var tags map[string]string
+	if err := c.doJSON(http.MethodGet, distTagsPath(name), nil, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// SetDistTag points tag at version and returns the updated tags.
+func (c *Client) SetDistTag(name, tag, version string) (map[string]string, error) {
+	var tags map[string]string
+	body := map[string]string{"version": version}
+	if err := c.doJSON(http.MethodPut, distTagsPath(name)+"/"+url.PathEscape(tag), body, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// RemoveDistTag deletes tag and returns the remaining tags.
+func (c *Client) RemoveDistTag(name, tag string) (map[string]string, error) {
+	var tags map[string]string
+	if err := c.doJSON(http.MethodDelete, distTagsPath(name)+"/"+url.PathEscape(tag), nil, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// WhoAmI returns the identity behind the client's token.
+func (c *Client) WhoAmI() (*WhoAmI, error) {
+	var who WhoAmI
+	if err := c.doJSON(http.MethodGet, "/v1/whoami", nil, &who); err != nil {
+		return nil, err
+	}
+	return &who, nil
+}
+
+// Usage returns the storage charged to a user or, for "@<org>", to an
+// organization.
+func (c *Client) Usage(owner string) (*Usage, error) {
+	var usage Usage
+	if err := c.doJSON(http.MethodGet, "/v1/users/"+url.PathEscape(owner)+"/usage", nil, &usage); err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+// CreateToken creates an API token for the authenticated user. A nil
+// expiresAt creates a token that never expires; no scopes create a token
+// with all of the user's rights.
+func (c *Client) CreateToken(name string, expiresAt *time.Time, scopes []string) (*CreatedToken, error) {
+	var created CreatedToken
+	body := struct {
+		Name      string     `json:"name"`
+		ExpiresAt *time.Time `json:"expires_at,omitempty"`
+		Scopes    []string   `json:"scopes,omitempty"`
+	}{name, expiresAt, scopes}
+	if err := c.doJSON(http.MethodPost, "/v1/tokens", body, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// ListTokens lists the authenticated user's API tokens.
+func (c *Client) ListTokens() ([]TokenInfo, error) {
+	var list struct {
+		Tokens []TokenInfo `json:"tokens"`
+	}
+	if err := c.doJSON(http.MethodGet, "/v1/tokens", nil, &list); err != nil {
+		return nil, err
+	}
+	return list.Tokens, nil
+}
+
+// RevokeToken deletes one of the authenticated user's API tokens.
+func (c *Client) RevokeToken(id string) error {
+	return c.doJSON(http.MethodDelete, "/v1/tokens/"+url.PathEscape(id), nil, nil)
+}
+
+// Owners returns the owner, collaborators and ownership history of a skill.
+func (c *Client) Owners(name string) (*Owners, error) {
+	var owners Owners
+	if err := c.doJSON(http.MethodGet, skillPath(name)+"/owners", nil, &owners); err != nil {
+		return nil, err
+	}
+	return &owners, nil
+}
+
+// AddCollaborator lets a user publish and manage a skill.
+func (c *Client) AddCollaborator(name, username string) (*Owners, error) {
+	var owners Owners
+	if err := c.doJSON(http.MethodPut, skillPath(name)+"/collaborators/"+url.PathEscape(username), nil, &owners); err != nil {
+		return nil, err
+	}
+	return &owners, nil
+}
+
+// RemoveCollaborator revokes a collaborator's rights on a skill.
+func (c *Client) RemoveCollaborator(name, username string) (*Owners, error) {
+	var owners Owners
+	if err := c.doJSON(http.MethodDelete, skillPath(name)+"/collaborators/"+url.PathEscape(username), nil, &owners); err != nil {
+		return nil, err
+	}
+	return &owners, nil
+}
+
+// Transfer makes owner, a username or "@<org>", the owner of a skill.
+func (c *Client) Transfer(name, owner string) (*Owners, error) {
+	var owners Owners
+	body := map[string]string{"owner": owner}
+	if err := c.doJSON(http.MethodPost, skillPath(name)+"/transfer", body, &owners); err != nil {
+		return nil, err
+	}
+	return &owners, nil
+}
+
+// CreateOrg creates an organization owned by the authenticated user.
+func (c *Client) CreateOrg(name string) (*Org, error) {
+	var org Org
+	body := map[string]string{"name": name}
+	if err := c.doJSON(http.MethodPost, "/v1/orgs", body, &org); err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// GetOrg returns an organization the authenticated user is a member of.
+func (c *Client) GetOrg(name string) (*Org, error) {
+	var org Org
+	if err := c.doJSON(http.MethodGet, "/v1/orgs/"+url.PathEscape(name), nil, &org); err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// SetOrgMember adds a user to an organization or changes their role.
+func (c *Client) SetOrgMember(org, username, role string) (*Org, error) {
+	var updated Org
+	body := map[string]string{"role": role}
+	if err := c.doJSON(http.MethodPut, orgMemberPath(org, username), body, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// RemoveOrgMember removes a user from an organization.
+func (c *Client) RemoveOrgMember(org, username string) (*Org, error) {
+	var updated Org
+	if err := c.doJSON(http.MethodDelete, orgMemberPath(org, username), nil, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// Audit returns the matching audit events, oldest first. Only registry
+// admins may read the audit log.
+func (c *Client) Audit(f AuditFilter) ([]AuditEvent, error) {
+	var resp struct {
+		Events []AuditEvent `json:"events"`
+	}
+	if err := c.doJSON(http.MethodGet, f.path(""), nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Events, nil
+}
+
+// ExportAudit copies the matching audit events to w as JSON lines, as
+// returned by the registry.
+func (c *Client) ExportAudit(f AuditFilter, w io.Writer) error {
+	resp, err := c.get(c.baseURL + f.path("jsonl"))
+	if err != nil {
+		return fmt.Errorf("exporting audit log: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return decodeError(resp)
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("exporting audit log: %w", err)
+	}
+	return nil
+}
+
+func skillPath(name string) string {
+	return "/v1/skills/" + url.PathEscape(name)
+}
+
+func orgMemberPath(org, username string) string {
+	return "/v1/orgs/" + url.PathEscape(org) + "/members/" + url.PathEscape(username)
+}
+
+func distTagsPath(name string) string {
+	return "/v1/skills/" + url.PathEscape(name) + "/dist-tags"
+}
+
+func versionPath(name, version string) string {
+	return "/v1/skills/" + url.PathEscape(name) + "/versions/" + url.PathEscape(version)
+}
+
+// doJSON sends an authenticated request with an optional JSON body and
+// decodes a 2xx JSON response into out (if non-nil).
+func (c *Client) doJSON(method, path string, body, out interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, reqBody)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	if err := c.authorize(req); err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return decodeError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		if r.URL.Path == "/v1/skills/publish" {
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, `{"name": "retried", "version": "1.0.0"}`)
+			return
+		}
+		io.WriteString(w, `{"name": "retried", "owner": "alice"}`)
+	}))
+	defer ts.Close()
+	client := NewClient(ts.URL, "")
+
+	// Rejected requests are sent again, bodies included.
+	if _, err := client.Owners("retried"); err != nil || calls.Load() != 2 {
+		t.Fatalf("Owners() error = %v after %d calls, want success after a retry", err, calls.Load())
+	}
+	bundlePath := filepath.Join(t.TempDir(), "retried.tar.gz")
+	os.WriteFile(bundlePath, []byte("bundle bytes"), 0o644)
+	calls.Store(0)
+	bodies = nil
+	if _, err := client.Publish(bundlePath, ""); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if len(bodies) != 2 || !strings.Contains(bodies[1], "bundle bytes") {
+		t.Errorf("published bodies = %q, want the bundle sent twice", bodies)
+	}
+
+	// Long waits are left to the user.
+	ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	calls.Store(0)
+	err := client.RevokeToken("t1")
+	if apiErr, ok := err.(*APIError); !ok || apiErr.StatusCode != http.StatusTooManyRequests || calls.Load() != 1 {
+		t.Errorf("RevokeToken() error = %v after %d calls, want 429 without retrying", err, calls.Load())
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for value, want := range map[string]time.Duration{
+		"7":                             7 * time.Second,
+		"Fri, 02 Jan 2026 03:04:35 GMT": 30 * time.Second,
+		"Fri, 02 Jan 2026 03:00:00 GMT": 0,
+	} {
+		if got, ok := parseRetryAfter(value, now); !ok || got != want {
+			t.Errorf("parseRetryAfter(%q) = %v, %v, want %v", value, got, ok, want)
+		}
+	}
+	for _, bad := range []string{"", "soon"} {
+		if _, ok := parseRetryAfter(bad, now); ok {
+			t.Errorf("parseRetryAfter(%q) succeeded", bad)
+		}
+	}
+}
@@ -1,43 +1,66 @@
 package api
 
 import (
+	"fmt"
 	"io"
 	"mime/multipart"
+	"os"
+	"path/filepath"
 )
 
-type multipartPipe struct {
-	pw          *io.PipeWriter
-	reader      io.Reader
-	filename    string
+// multipartForm streams a file and form fields as a multipart request body
+// without loading the file into memory.
+type multipartForm struct {
+	path        string
+	fields      map[string]string
+	boundary    string
 	contentType string
-	writer      *multipart.Writer
 }
 
-func newMultipartWriter(pw *io.PipeWriter, filename string, reader io.Reader) *multipartPipe {
-	w := multipart.NewWriter(pw)
-	return &multipartPipe{
-		pw:          pw,
-		reader:      reader,
-		filename:    filename,
+func newMultipartForm(path string, fields map[string]string) *multipartForm {
+	w := multipart.NewWriter(io.Discard)
+	return &multipartForm{
+		path:        path,
+		fields:      fields,
+		boundary:    w.Boundary(),
 		contentType: w.FormDataContentType(),
-		writer:      w,
 	}
 }
 
-func (m *multipartPipe) write() {
-	defer func() {
-		m.writer.Close()
-		m.pw.Close()
-	}()
+// open starts writing the form into a pipe and returns its reading end. It
+// reopens the file on every call, so that it can serve as the GetBody of a
+// request that is sent again.
+func (m *multipartForm) open() (io.ReadCloser, error) {
+	f, err := os.Open(m.path)
+	if err != nil {
+		return nil, fmt.Errorf("opening bundle: %w", err)
+	}
+	pr, pw := io.Pipe()
+	go m.write(pw, f)
+	return pr, nil
+}
+
+func (m *multipartForm) write(pw *io.PipeWriter, f *os.File) {
+	defer f.Close()
+	w := multipart.NewWriter(pw)
+	w.SetBoundary(m.boundary)
+
+	for name, value := range m.fields {
+		if err := w.WriteField(name, value); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+	}
 
-	part, err := m.writer.CreateFormFile("file", m.filename)
+	part, err := w.CreateFormFile("file", filepath.Base(m.path))
 	if err != nil {
-		m.pw.CloseWithError(err)
+		pw.CloseWithError(err)
 		return
 	}
 
-	if _, err := io.Copy(part, m.reader); err != nil {
-		m.pw.CloseWithError(err)
+	if _, err := io.Copy(part, f); err != nil {
+		pw.CloseWithError(err)
 		return
 	}
+	pw.CloseWithError(w.Close())
 }
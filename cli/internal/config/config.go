@@ -4,13 +4,70 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
+const (
+	// DefaultProfile is used when neither --profile nor default_profile is set.
+	DefaultProfile = "default"
+
+	// DefaultAPIURL is the registry of a profile without an api_url.
+	DefaultAPIURL = "http://localhost:8000"
+)
+
+// Config is the resolved configuration; see Resolve.
 type Config struct {
+	Profile string
+	APIURL  string
+	Token   string
+
+	// CredentialHelper is the command that stores tokens outside the config
+	// file; see package credential.
+	CredentialHelper string
+
+	// Origins describes where the value of each key came from, e.g.
+	// "env AGENTSKILLS_API_URL" or "flag --profile".
+	Origins map[string]string
+
+	// Warnings are problems with the configuration that did not stop it
+	// from resolving.
+	Warnings []string
+}
+
+// File is the content of ~/.agentskills/config.yaml:
+//
+//	default_profile: corp
+//	profiles:
+//	  corp:
+//	    api_url: https://skills.corp.example.com
+//	  public:
+//	    api_url: https://registry.agentskills.dev
+//	tokens:
+//	  https://skills.corp.example.com: ask_...
+//	credential_helper: vault-agentskills
+//
+// Tokens are keyed by registry URL rather than by profile, so that every
+// profile and lock file source pointing at a registry shares its token.
+// With a credential helper, login stores tokens with the helper instead.
+type File struct {
+	DefaultProfile string             `yaml:"default_profile,omitempty"`
+	Profiles       map[string]Profile `yaml:"profiles,omitempty"`
+	Tokens         map[string]string  `yaml:"tokens,omitempty"`
+
+	CredentialHelper string `yaml:"credential_helper,omitempty"`
+
+	// APIURL and Token hold the single-registry format of earlier versions.
+	// LoadFile moves them into the default profile.
+	APIURL string `yaml:"api_url,omitempty"`
+	Token  string `yaml:"token,omitempty"`
+}
+
+// Profile is a named registry.
+type Profile struct {
 	APIURL string `yaml:"api_url"`
-	Token  string `yaml:"token"`
 }
 
 func configDir() string {
@@ -25,32 +82,44 @@ func configPath() string {
 	return filepath.Join(configDir(), "config.yaml")
 }
 
-func Load() (Config, error) {
-	cfg := Config{
-		APIURL: "http://localhost:8000",
-	}
-
+// LoadFile reads the config file. A missing file yields an empty File.
+func LoadFile() (*File, error) {
+	f := &File{}
 	data, err := os.ReadFile(configPath())
 	if err != nil {
 		if os.IsNotExist(err) {
-			return cfg, nil
+			return f, nil
 		}
-		return cfg, fmt.Errorf("reading config: %w", err)
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("parsing config: %w", err)
 	}
 
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return cfg, fmt.Errorf("parsing config: %w", err)
+	if f.APIURL != "" || f.Token != "" {
+		apiURL := f.APIURL
+		if apiURL == "" {
+			apiURL = DefaultAPIURL
+		}
+		if _, ok := f.Profiles[DefaultProfile]; !ok {
+			f.SetProfile(DefaultProfile, apiURL)
+		}
+		if f.Token != "" && f.TokenFor(apiURL) == "" {
+			f.SetToken(apiURL, f.Token)
+		}
+		f.APIURL, f.Token = "", ""
 	}
-	return cfg, nil
+	return f, nil
 }
 
-func Save(cfg Config) error {
+// Save writes the config file, readable by the current user only.
+func (f *File) Save() error {
 	dir := configDir()
 	if err := os.MkdirAll(dir, 0o700); err != nil {
 		return fmt.Errorf("creating config dir: %w", err)
 	}
 
-	data, err := yaml.Marshal(cfg)
+	data, err := yaml.Marshal(f)
 	if err != nil {
 		return fmt.Errorf("marshaling config: %w", err)
 	}
@@ -60,3 +129,57 @@ func Save(cfg Config) error {
 	}
 	return nil
 }
+
+// ProfileName returns name, or the default profile if name is empty.
+func (f *File) ProfileName(name string) string {
+	switch {
+	case name != "":
+		return name
+	case f.DefaultProfile != "":
+		return f.DefaultProfile
+	}
+	return DefaultProfile
+}
+
+// SetProfile creates or updates a profile.
+func (f *File) SetProfile(name, apiURL string) {
+	if f.Profiles == nil {
+		f.Profiles = make(map[string]Profile)
+	}
+	f.Profiles[name] = Profile{APIURL: normalizeURL(apiURL)}
+}
+
+// TokenFor returns the token saved for a registry URL.
+func (f *File) TokenFor(apiURL string) string {
+	return f.Tokens[normalizeURL(apiURL)]
+}
+
+// SetToken saves the token for a registry URL; an empty token removes it.
+func (f *File) SetToken(apiURL, token string) {
+	apiURL = normalizeURL(apiURL)
+	if token == "" {
+		delete(f.Tokens, apiURL)
+		return
+	}
+	if f.Tokens == nil {
+		f.Tokens = make(map[string]string)
+	}
+	f.Tokens[apiURL] = token
+}
+
+func (f *File) profileList() string {
+	if len(f.Profiles) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(f.Profiles))
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// normalizeURL makes "https://host/" and "https://host" the same key.
+func normalizeURL(apiURL string) string {
+	return strings.TrimRight(apiURL, "/")
+}
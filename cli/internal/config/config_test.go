@@ -0,0 +1,88 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// useTempHome points the config file at a fresh home directory.
+// It also clears the environment overrides and leaves the working directory
+// so that no project config file is found.
+func useTempHome(t *testing.T) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, env := range []string{EnvProfile, EnvAPIURL, EnvToken, EnvCredentialHelper} {
+		t.Setenv(env, "")
+	}
+	t.Chdir(home)
+}
+
+func TestLoadWithoutFile(t *testing.T) {
+	useTempHome(t)
+	cfg, err := Resolve(Overrides{})
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if cfg.Profile != DefaultProfile || cfg.APIURL != DefaultAPIURL || cfg.Token != "" {
+		t.Errorf("Resolve() = %+v", cfg)
+	}
+	if _, err := Resolve(Overrides{Profile: "corp"}); err == nil {
+		t.Error("Resolve() of an unknown profile should fail")
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	useTempHome(t)
+	f, _ := LoadFile()
+	f.SetProfile("corp", "https://skills.corp.example.com/")
+	f.SetProfile("mirror", "https://skills.corp.example.com")
+	f.SetProfile("public", "https://registry.example.org")
+	f.SetToken("https://skills.corp.example.com", "corp-token")
+	f.DefaultProfile = "public"
+	if err := f.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	tests := []struct {
+		profile, apiURL, token string
+	}{
+		{"", "https://registry.example.org", ""},
+		{"corp", "https://skills.corp.example.com", "corp-token"},
+		{"mirror", "https://skills.corp.example.com", "corp-token"},
+	}
+	for _, tt := range tests {
+		cfg, err := Resolve(Overrides{Profile: tt.profile})
+		if err != nil {
+			t.Errorf("Resolve(%q) error = %v", tt.profile, err)
+			continue
+		}
+		if cfg.APIURL != tt.apiURL || cfg.Token != tt.token {
+			t.Errorf("Resolve(%q) = %+v, want %s with token %q", tt.profile, cfg, tt.apiURL, tt.token)
+		}
+	}
+}
+
+func TestLoadLegacyFile(t *testing.T) {
+	useTempHome(t)
+	home, _ := os.UserHomeDir()
+	os.MkdirAll(filepath.Join(home, ".agentskills"), 0o700)
+	legacy := "api_url: http://registry.internal:8000\ntoken: old-token\n"
+	if err := os.WriteFile(configPath(), []byte(legacy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Resolve(Overrides{})
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if cfg.Profile != DefaultProfile || cfg.APIURL != "http://registry.internal:8000" || cfg.Token != "old-token" {
+		t.Errorf("Resolve() = %+v", cfg)
+	}
+
+	f, _ := LoadFile()
+	if f.APIURL != "" || f.Token != "" || f.TokenFor("http://registry.internal:8000/") != "old-token" {
+		t.Errorf("legacy fields were not migrated: %+v", f)
+	}
+}
@@ -0,0 +1,206 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectFilename is the per-project config file, looked up in the working
+// directory and its parents.
+const ProjectFilename = ".agentskills.yaml"
+
+// Environment variables that override config files.
+const (
+	EnvProfile = "AGENTSKILLS_PROFILE"
+	EnvAPIURL  = "AGENTSKILLS_API_URL"
+	EnvToken   = "AGENTSKILLS_TOKEN"
+
+	EnvCredentialHelper = "AGENTSKILLS_CREDENTIAL_HELPER"
+)
+
+// Keys of the resolved configuration.
+const (
+	KeyProfile = "profile"
+	KeyAPIURL  = "api_url"
+	KeyToken   = "token"
+
+	KeyCredentialHelper = "credential_helper"
+)
+
+// Keys lists the configuration keys in display order.
+var Keys = []string{KeyProfile, KeyAPIURL, KeyToken, KeyCredentialHelper}
+
+// ProjectFile is the content of .agentskills.yaml. It may pick a profile or
+// registry for everyone working in the project, but never holds tokens or
+// a credential helper, which would run commands from a cloned repository.
+// Nor is $AGENTSKILLS_TOKEN sent to a registry that only the project file
+// names.
+type ProjectFile struct {
+	Profile string `yaml:"profile,omitempty"`
+	APIURL  string `yaml:"api_url,omitempty"`
+}
+
+// Overrides are values given on the command line; empty fields are unset.
+type Overrides struct {
+	Profile string
+	APIURL  string
+
+	// NewProfile allows the resolved profile not to exist yet, as when
+	// login creates it.
+	NewProfile bool
+}
+
+// Resolve merges the configuration layers, from lowest to highest
+// precedence: built-in defaults, ~/.agentskills/config.yaml, the nearest
+// .agentskills.yaml, environment variables and command-line overrides.
+//
+// The profile is resolved first; api_url then defaults to the profile's
+// registry, and token to the token saved for the resolved api_url. A
+// credential helper is only consulted by the caller, when it needs a token
+// that none of the layers set.
+func Resolve(o Overrides) (Config, error) {
+	f, err := LoadFile()
+	if err != nil {
+		return Config{}, err
+	}
+	projectPath, project, err := LoadProjectFile()
+	if err != nil {
+		return Config{}, err
+	}
+	return resolve(f, projectPath, project, o)
+}
+
+func resolve(f *File, projectPath string, project *ProjectFile, o Overrides) (Config, error) {
+	cfg := Config{Origins: make(map[string]string)}
+	userOrigin := "user config " + configPath()
+	projectOrigin := "project config " + projectPath
+
+	layer := func(key, value, origin string) {
+		if value == "" {
+			return
+		}
+		switch key {
+		case KeyProfile:
+			cfg.Profile = value
+		case KeyAPIURL:
+			cfg.APIURL = value
+		case KeyToken:
+			cfg.Token = value
+		case KeyCredentialHelper:
+			cfg.CredentialHelper = value
+		}
+		cfg.Origins[key] = origin
+	}
+
+	layer(KeyProfile, DefaultProfile, "default")
+	layer(KeyProfile, f.DefaultProfile, userOrigin)
+	if project != nil {
+		layer(KeyProfile, project.Profile, projectOrigin)
+	}
+	layer(KeyProfile, os.Getenv(EnvProfile), "env "+EnvProfile)
+	layer(KeyProfile, o.Profile, "flag --profile")
+
+	p, ok := f.Profiles[cfg.Profile]
+	// Only the default profile may be used before it is configured.
+	if !ok && !o.NewProfile && cfg.Profile != f.ProfileName("") {
+		return cfg, fmt.Errorf("profile %q (from %s) not found (have: %s)", cfg.Profile, cfg.Origins[KeyProfile], f.profileList())
+	}
+	layer(KeyAPIURL, DefaultAPIURL, "default")
+	layer(KeyAPIURL, p.APIURL, fmt.Sprintf("%s (profile %s)", userOrigin, cfg.Profile))
+	if project != nil {
+		layer(KeyAPIURL, project.APIURL, projectOrigin)
+	}
+	layer(KeyAPIURL, os.Getenv(EnvAPIURL), "env "+EnvAPIURL)
+	layer(KeyAPIURL, o.APIURL, "flag --api-url")
+
+	layer(KeyToken, f.TokenFor(cfg.APIURL), userOrigin)
+	if env := os.Getenv(EnvToken); env != "" && cfg.Origins[KeyAPIURL] == projectOrigin {
+		// A cloned repository could otherwise point the token at any host.
+		cfg.Warnings = append(cfg.Warnings, fmt.Sprintf(
+			"ignoring %s for %s, set by %s; pass --api-url or set %s to use it",
+			EnvToken, cfg.APIURL, projectOrigin, EnvAPIURL))
+	} else {
+		layer(KeyToken, env, "env "+EnvToken)
+	}
+
+	layer(KeyCredentialHelper, f.CredentialHelper, userOrigin)
+	layer(KeyCredentialHelper, os.Getenv(EnvCredentialHelper), "env "+EnvCredentialHelper)
+	return cfg, nil
+}
+
+// Get returns the value of a configuration key.
+func (c Config) Get(key string) (string, error) {
+	switch key {
+	case KeyProfile:
+		return c.Profile, nil
+	case KeyAPIURL:
+		return c.APIURL, nil
+	case KeyToken:
+		return c.Token, nil
+	case KeyCredentialHelper:
+		return c.CredentialHelper, nil
+	}
+	return "", fmt.Errorf("unknown config key %q (want one of %s)", key, strings.Join(Keys, ", "))
+}
+
+// FindProjectFile returns the path of the nearest .agentskills.yaml in the
+// working directory or its parents, or "" if there is none.
+func FindProjectFile() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("getting working directory: %w", err)
+	}
+	for {
+		path := filepath.Join(dir, ProjectFilename)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// LoadProjectFile finds and reads the nearest project config file. It
+// returns "" and nil if there is none.
+func LoadProjectFile() (string, *ProjectFile, error) {
+	path, err := FindProjectFile()
+	if err != nil || path == "" {
+		return "", nil, err
+	}
+	p, err := readProjectFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+	return path, p, nil
+}
+
+func readProjectFile(path string) (*ProjectFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading project config: %w", err)
+	}
+	var p ProjectFile
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("parsing project config %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+// SaveProjectFile writes a project config file.
+func SaveProjectFile(path string, p *ProjectFile) error {
+	data, err := yaml.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("marshaling project config: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing project config: %w", err)
+	}
+	return nil
+}
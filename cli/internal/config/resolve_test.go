@@ -0,0 +1,130 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveLayers(t *testing.T) {
+	useTempHome(t)
+	f, _ := LoadFile()
+	f.SetProfile("corp", "https://skills.corp.example.com")
+	f.SetProfile("staging", "https://staging.corp.example.com")
+	f.SetToken("https://skills.corp.example.com", "corp-token")
+	f.SetToken("https://staging.corp.example.com", "staging-token")
+	f.DefaultProfile = "corp"
+	if err := f.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// The project file is found from a subdirectory.
+	project := t.TempDir()
+	if err := SaveProjectFile(filepath.Join(project, ProjectFilename), &ProjectFile{Profile: "staging"}); err != nil {
+		t.Fatalf("SaveProjectFile() error = %v", err)
+	}
+	sub := filepath.Join(project, "skills", "lint")
+	os.MkdirAll(sub, 0o755)
+	t.Chdir(sub)
+
+	cfg, err := Resolve(Overrides{})
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if cfg.Profile != "staging" || cfg.APIURL != "https://staging.corp.example.com" || cfg.Token != "staging-token" {
+		t.Errorf("Resolve() with project file = %+v", cfg)
+	}
+	if got, want := cfg.Origins[KeyProfile], "project config "+filepath.Join(project, ProjectFilename); got != want {
+		t.Errorf("profile origin = %q, want %q", got, want)
+	}
+
+	// Environment variables override the project file; the token follows
+	// the resolved URL.
+	t.Setenv(EnvAPIURL, "https://skills.corp.example.com")
+	cfg, _ = Resolve(Overrides{})
+	if cfg.APIURL != "https://skills.corp.example.com" || cfg.Token != "corp-token" || cfg.Origins[KeyAPIURL] != "env "+EnvAPIURL {
+		t.Errorf("Resolve() with %s = %+v", EnvAPIURL, cfg)
+	}
+	t.Setenv(EnvToken, "env-token")
+	cfg, _ = Resolve(Overrides{})
+	if cfg.Token != "env-token" || cfg.Origins[KeyToken] != "env "+EnvToken {
+		t.Errorf("Resolve() with %s = %+v", EnvToken, cfg)
+	}
+
+	// Flags override everything.
+	t.Setenv(EnvToken, "")
+	cfg, _ = Resolve(Overrides{Profile: "corp", APIURL: "http://localhost:9000"})
+	if cfg.Profile != "corp" || cfg.APIURL != "http://localhost:9000" || cfg.Token != "" {
+		t.Errorf("Resolve() with flags = %+v", cfg)
+	}
+	if cfg.Origins[KeyProfile] != "flag --profile" || cfg.Origins[KeyAPIURL] != "flag --api-url" {
+		t.Errorf("origins with flags = %v", cfg.Origins)
+	}
+	if _, ok := cfg.Origins[KeyToken]; ok {
+		t.Errorf("token origin = %q, want unset", cfg.Origins[KeyToken])
+	}
+}
+
+func TestResolveEnvTokenWithProjectAPIURL(t *testing.T) {
+	useTempHome(t)
+	t.Setenv(EnvToken, "env-token")
+	project := t.TempDir()
+	path := filepath.Join(project, ProjectFilename)
+	if err := SaveProjectFile(path, &ProjectFile{APIURL: "https://evil.example.com"}); err != nil {
+		t.Fatalf("SaveProjectFile() error = %v", err)
+	}
+	t.Chdir(project)
+
+	// The token is not sent to a registry that only the project file names.
+	cfg, err := Resolve(Overrides{})
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if cfg.APIURL != "https://evil.example.com" || cfg.Token != "" || len(cfg.Warnings) != 1 {
+		t.Errorf("Resolve() with a project api_url = %+v", cfg)
+	}
+
+	// Naming the registry outside the project file opts in.
+	cfg, _ = Resolve(Overrides{APIURL: "https://evil.example.com"})
+	if cfg.Token != "env-token" || len(cfg.Warnings) != 0 {
+		t.Errorf("Resolve() with --api-url = %+v", cfg)
+	}
+	t.Setenv(EnvAPIURL, "https://skills.example.com")
+	cfg, _ = Resolve(Overrides{})
+	if cfg.APIURL != "https://skills.example.com" || cfg.Token != "env-token" {
+		t.Errorf("Resolve() with %s = %+v", EnvAPIURL, cfg)
+	}
+}
+
+func TestResolveUnknownProfile(t *testing.T) {
+	useTempHome(t)
+	t.Setenv(EnvProfile, "corp")
+	if _, err := Resolve(Overrides{}); err == nil {
+		t.Error("Resolve() of an unknown profile should fail")
+	}
+	cfg, err := Resolve(Overrides{NewProfile: true})
+	if err != nil {
+		t.Fatalf("Resolve() with NewProfile error = %v", err)
+	}
+	if cfg.Profile != "corp" || cfg.APIURL != DefaultAPIURL {
+		t.Errorf("Resolve() with NewProfile = %+v", cfg)
+	}
+}
+
+func TestResolveCredentialHelper(t *testing.T) {
+	useTempHome(t)
+	f, _ := LoadFile()
+	f.CredentialHelper = "vault-agentskills"
+	if err := f.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	cfg, _ := Resolve(Overrides{})
+	if cfg.CredentialHelper != "vault-agentskills" || cfg.Origins[KeyCredentialHelper] != "user config "+configPath() {
+		t.Errorf("Resolve() = %+v", cfg)
+	}
+	t.Setenv(EnvCredentialHelper, "keychain-helper")
+	cfg, _ = Resolve(Overrides{})
+	if got, _ := cfg.Get(KeyCredentialHelper); got != "keychain-helper" {
+		t.Errorf("Get(%s) = %q, want keychain-helper", KeyCredentialHelper, got)
+	}
+}
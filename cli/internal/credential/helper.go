@@ -0,0 +1,118 @@
+// Package credential talks to external credential helpers, in the spirit of
+// git's credential helpers, so that tokens can live in a vault or OS keychain
+// instead of ~/.agentskills/config.yaml.
+//
+// A helper is an executable run with one action argument: get, store or
+// erase. It reads key=value lines from stdin, terminated by a blank line:
+//
+//	url=https://skills.corp.example.com
+//	token=ask_...        (store only)
+//
+// For get it prints the token on stdout, either as a token=<value> line or
+// as a bare token; printing nothing means it has no token for the URL. For
+// store and erase its output is ignored. A non-zero exit status is an error.
+package credential
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Helper actions.
+const (
+	ActionGet   = "get"
+	ActionStore = "store"
+	ActionErase = "erase"
+)
+
+// Helper runs a configured credential helper.
+type Helper struct {
+	// Command is the helper executable followed by optional arguments,
+	// separated by spaces, e.g. "vault-agentskills --mount ci". The action
+	// is appended as the last argument.
+	Command string
+}
+
+// New returns a helper for command, or nil if command is empty.
+func New(command string) *Helper {
+	if strings.TrimSpace(command) == "" {
+		return nil
+	}
+	return &Helper{Command: command}
+}
+
+// Get asks the helper for the token of a registry. It returns "" if the
+// helper has none.
+func (h *Helper) Get(apiURL string) (string, error) {
+	out, err := h.run(ActionGet, apiURL, "")
+	if err != nil {
+		return "", err
+	}
+	return parseToken(out)
+}
+
+// Store hands a registry's token to the helper.
+func (h *Helper) Store(apiURL, token string) error {
+	_, err := h.run(ActionStore, apiURL, token)
+	return err
+}
+
+// Erase tells the helper to forget a registry's token.
+func (h *Helper) Erase(apiURL string) error {
+	_, err := h.run(ActionErase, apiURL, "")
+	return err
+}
+
+func (h *Helper) run(action, apiURL, token string) ([]byte, error) {
+	args := strings.Fields(h.Command)
+	if len(args) == 0 {
+		return nil, errors.New("credential helper: no command configured")
+	}
+
+	var in bytes.Buffer
+	fmt.Fprintf(&in, "url=%s\n", strings.TrimRight(apiURL, "/"))
+	if token != "" {
+		fmt.Fprintf(&in, "token=%s\n", token)
+	}
+	in.WriteString("\n")
+
+	var stdout, stderr bytes.Buffer
+	c := exec.Command(args[0], append(args[1:], action)...)
+	c.Stdin = &in
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	if err := c.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("credential helper %s %s: %w: %s", args[0], action, err, msg)
+		}
+		return nil, fmt.Errorf("credential helper %s %s: %w", args[0], action, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// parseToken extracts the token from the output of a get action.
+func parseToken(out []byte) (string, error) {
+	var lines []string
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		if value, ok := strings.CutPrefix(line, "token="); ok {
+			return value, nil
+		}
+		lines = append(lines, line)
+	}
+	switch {
+	case len(lines) == 0:
+		return "", nil
+	case len(lines) == 1 && !strings.Contains(lines[0], "="):
+		return lines[0], nil
+	}
+	return "", errors.New("credential helper: output has no token=<value> line")
+}
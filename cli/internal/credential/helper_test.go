@@ -0,0 +1,109 @@
+package credential
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeHelper writes a helper script that keeps one token per URL in files
+// under dir, and logs every action to dir/log.
+func fakeHelper(t *testing.T) (*Helper, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("helper script needs a POSIX shell")
+	}
+	dir := t.TempDir()
+	script := `#!/bin/sh
+dir=$(dirname "$0")
+while IFS='=' read -r key value; do
+  [ -z "$key" ] && break
+  eval "in_$key=\$value"
+done
+file="$dir/$(printf %s "$in_url" | tr -c 'a-zA-Z0-9' _)"
+echo "$1 $in_url" >> "$dir/log"
+case "$1" in
+  get)   [ -f "$file" ] && printf 'token=%s\n' "$(cat "$file")" ;;
+  store) printf %s "$in_token" > "$file" ;;
+  erase) rm -f "$file" ;;
+  *)     echo "unknown action $1" >&2; exit 2 ;;
+esac
+exit 0
+`
+	path := filepath.Join(dir, "helper")
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return New(path), dir
+}
+
+func TestHelperRoundTrip(t *testing.T) {
+	h, dir := fakeHelper(t)
+	const url = "https://skills.corp.example.com"
+
+	if token, err := h.Get(url); err != nil || token != "" {
+		t.Fatalf("Get() before store = %q, %v; want empty", token, err)
+	}
+	if err := h.Store(url+"/", "ask_secret"); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	if token, err := h.Get(url); err != nil || token != "ask_secret" {
+		t.Errorf("Get() = %q, %v; want ask_secret", token, err)
+	}
+	if err := h.Erase(url); err != nil {
+		t.Fatalf("Erase() error = %v", err)
+	}
+	if token, _ := h.Get(url); token != "" {
+		t.Errorf("Get() after erase = %q, want empty", token)
+	}
+
+	log, _ := os.ReadFile(filepath.Join(dir, "log"))
+	want := "get " + url + "\nstore " + url + "\nget " + url + "\nerase " + url + "\nget " + url + "\n"
+	if string(log) != want {
+		t.Errorf("helper log = %q, want %q", log, want)
+	}
+}
+
+func TestHelperFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("helper script needs a POSIX shell")
+	}
+	path := filepath.Join(t.TempDir(), "helper")
+	os.WriteFile(path, []byte("#!/bin/sh\necho vault is sealed >&2\nexit 1\n"), 0o755)
+	_, err := New(path).Get("https://example.com")
+	if err == nil {
+		t.Fatal("Get() should fail when the helper exits non-zero")
+	}
+	if got := err.Error(); !strings.Contains(got, "vault is sealed") {
+		t.Errorf("error %q should include the helper's stderr", got)
+	}
+}
+
+func TestParseToken(t *testing.T) {
+	tests := []struct {
+		out, want string
+		wantErr   bool
+	}{
+		{"", "", false},
+		{"\n\n", "", false},
+		{"token=ask_abc\n", "ask_abc", false},
+		{"url=https://example.com\ntoken=ask_abc\n", "ask_abc", false},
+		{"ask_abc\n", "ask_abc", false},
+		{"username=ci\n", "", true},
+		{"one\ntwo\n", "", true},
+	}
+	for _, tt := range tests {
+		got, err := parseToken([]byte(tt.out))
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseToken(%q) = %q, %v; want %q, error %v", tt.out, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	if New("  ") != nil {
+		t.Error("New() of an empty command should be nil")
+	}
+}
@@ -20,9 +20,10 @@ type LockFile struct {
 
 // LockedSkill represents a single locked skill entry.
 type LockedSkill struct {
-	Version  string `yaml:"version"`
-	Checksum string `yaml:"checksum"`
-	Source   string `yaml:"source"`
+	Version    string `yaml:"version"`
+	Constraint string `yaml:"constraint,omitempty"` // requested range or dist-tag, e.g. "^1.2"
+	Checksum   string `yaml:"checksum"`
+	Source     string `yaml:"source"`
 }
 
 // Load reads the lock file from the given path.
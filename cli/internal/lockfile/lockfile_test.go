@@ -19,9 +19,10 @@ func TestSaveAndLoad(t *testing.T) {
 				Source:   "http://localhost:8000",
 			},
 			"data-analysis": {
-				Version:  "2.0.0",
-				Checksum: "sha256:def456",
-				Source:   "http://my-server:8000",
+				Version:    "2.0.0",
+				Constraint: "^2",
+				Checksum:   "sha256:def456",
+				Source:     "http://my-server:8000",
 			},
 		},
 	}
@@ -60,6 +61,25 @@ func TestSaveAndLoad(t *testing.T) {
 	if cr.Checksum != "sha256:abc123" {
 		t.Errorf("code-review checksum = %s, want sha256:abc123", cr.Checksum)
 	}
+	if da, _ := loaded.Get("data-analysis"); da.Constraint != "^2" {
+		t.Errorf("data-analysis constraint = %q, want ^2", da.Constraint)
+	}
+}
+
+func TestScopedNameKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), Filename)
+	lf := &LockFile{}
+	lf.Set("@acme/code-review", LockedSkill{Version: "1.0.0", Checksum: "sha256:abc", Source: "http://localhost:8000"})
+	if err := Save(path, lf); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if s, ok := loaded.Get("@acme/code-review"); !ok || s.Version != "1.0.0" {
+		t.Errorf("Get(@acme/code-review) = %+v, %v", s, ok)
+	}
 }
 
 func TestLoadNonExistent(t *testing.T) {
@@ -7,6 +7,7 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/liuyukai/agentskills-cli/internal/semver"
 	"gopkg.in/yaml.v3"
 )
 
@@ -23,6 +24,10 @@ type SkillMeta struct {
 	Tags            []string `yaml:"tags"`
 	License         string   `yaml:"license"`
 	MinAgentVersion string   `yaml:"min_agent_version"`
+	Visibility      string   `yaml:"visibility"`
+
+	// Frontmatter holds every frontmatter field, including unknown ones.
+	Frontmatter map[string]interface{} `yaml:"-"`
 }
 
 // ParseSkillFile reads and validates SKILL.md from the given directory.
@@ -46,6 +51,57 @@ func ParseSkillFile(dir string) (*SkillMeta, error) {
 	return meta, nil
 }
 
+// ValidateName checks a skill name: either a bare name such as
+// "code-review", or a name scoped to an organization such as
+// "@acme/code-review".
+func ValidateName(name string) error {
+	if name == "" {
+		return fmt.Errorf("name is required")
+	}
+	scope, base := SplitName(name)
+	if strings.HasPrefix(name, "@") {
+		if !strings.Contains(name, "/") {
+			return fmt.Errorf("scoped name must have the form @scope/name: %q", name)
+		}
+		if err := ValidateScope(scope); err != nil {
+			return err
+		}
+	}
+	// name: [a-z0-9\-]{3,64}, no consecutive --
+	if len(base) < 3 || len(base) > 64 {
+		return fmt.Errorf("name must be 3-64 characters, got %d", len(base))
+	}
+	if !nameRegex.MatchString(base) {
+		return fmt.Errorf("name must match [a-z0-9-] pattern: %q", base)
+	}
+	if strings.Contains(base, "--") {
+		return fmt.Errorf("name must not contain consecutive dashes: %q", base)
+	}
+	return nil
+}
+
+// ValidateScope checks an organization scope, given without the leading
+// "@": [a-z0-9-]{2,32}, not starting or ending with a dash and without
+// consecutive dashes.
+func ValidateScope(scope string) error {
+	if len(scope) < 2 || len(scope) > 32 || !nameRegex.MatchString(scope) || strings.Contains(scope, "--") {
+		return fmt.Errorf("invalid scope %q: must be 2-32 characters of [a-z0-9-] without leading, trailing or consecutive dashes", scope)
+	}
+	return nil
+}
+
+// SplitName splits "@acme/code-review" into its scope "acme" and base name
+// "code-review". Unscoped names have an empty scope.
+func SplitName(name string) (scope, base string) {
+	if rest, ok := strings.CutPrefix(name, "@"); ok {
+		if scope, base, ok := strings.Cut(rest, "/"); ok {
+			return scope, base
+		}
+		return rest, ""
+	}
+	return "", name
+}
+
 func extractFrontmatter(content string) (*SkillMeta, error) {
 	content = strings.TrimSpace(content)
 	if !strings.HasPrefix(content, "---") {
@@ -62,31 +118,24 @@ func extractFrontmatter(content string) (*SkillMeta, error) {
 	if err := yaml.Unmarshal([]byte(yamlContent), &meta); err != nil {
 		return nil, fmt.Errorf("parsing frontmatter YAML: %w", err)
 	}
+	if err := yaml.Unmarshal([]byte(yamlContent), &meta.Frontmatter); err != nil {
+		return nil, fmt.Errorf("parsing frontmatter YAML: %w", err)
+	}
 
 	return &meta, nil
 }
 
 func validate(meta *SkillMeta) error {
-	// name: required, [a-z0-9\-]{3,64}, no consecutive --
-	if meta.Name == "" {
-		return fmt.Errorf("name is required")
-	}
-	if len(meta.Name) < 3 || len(meta.Name) > 64 {
-		return fmt.Errorf("name must be 3-64 characters, got %d", len(meta.Name))
-	}
-	if !nameRegex.MatchString(meta.Name) {
-		return fmt.Errorf("name must match [a-z0-9-] pattern: %q", meta.Name)
-	}
-	if strings.Contains(meta.Name, "--") {
-		return fmt.Errorf("name must not contain consecutive dashes: %q", meta.Name)
+	if err := ValidateName(meta.Name); err != nil {
+		return err
 	}
 
 	// version: required, semver
 	if meta.Version == "" {
 		return fmt.Errorf("version is required")
 	}
-	if !isValidSemver(meta.Version) {
-		return fmt.Errorf("version must be valid semver (MAJOR.MINOR.PATCH): %q", meta.Version)
+	if !semver.IsValid(meta.Version) {
+		return fmt.Errorf("version must be valid semver (MAJOR.MINOR.PATCH[-PRERELEASE][+BUILD]): %q", meta.Version)
 	}
 
 	// description: required, 1-256 chars
@@ -112,27 +161,12 @@ func validate(meta *SkillMeta) error {
 		}
 	}
 
-	return nil
-}
-
-func isValidSemver(v string) bool {
-	parts := strings.Split(v, ".")
-	if len(parts) != 3 {
-		return false
-	}
-	for _, p := range parts {
-		if p == "" {
-			return false
-		}
-		for _, c := range p {
-			if c < '0' || c > '9' {
-				return false
-			}
-		}
-		// No leading zeros (except "0" itself)
-		if len(p) > 1 && p[0] == '0' {
-			return false
-		}
+	// visibility: optional
+	switch meta.Visibility {
+	case "", "public", "internal", "private":
+	default:
+		return fmt.Errorf("visibility must be public, internal or private, got %q", meta.Visibility)
 	}
-	return true
+
+	return nil
 }
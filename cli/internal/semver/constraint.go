@@ -0,0 +1,242 @@
+package semver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Constraint is a version range such as "^1.2", "~1.2.3" or ">=1.0.0 <2.0.0".
+//
+// Space-separated comparators must all match; alternatives are separated by
+// "||". Supported forms, following npm:
+//
+//	1.2.3          exactly 1.2.3
+//	1.2, 1.2.x     >=1.2.0 <1.3.0
+//	^1.2.3         >=1.2.3 <2.0.0 (^0.2.3 is >=0.2.3 <0.3.0)
+//	~1.2.3         >=1.2.3 <1.3.0
+//	>, >=, <, <=   comparisons; missing parts are treated as zero
+//	*, x           any version
+//
+// A prerelease version only satisfies a constraint if one of its comparators
+// names a prerelease of the same MAJOR.MINOR.PATCH, so "^1.2" never selects
+// 1.3.0-beta.1.
+type Constraint struct {
+	raw  string
+	sets [][]comparator
+}
+
+type comparator struct {
+	op string // one of "=", ">", ">=", "<", "<="
+	v  Version
+}
+
+// ParseConstraint parses a version range.
+func ParseConstraint(s string) (*Constraint, error) {
+	c := &Constraint{raw: strings.TrimSpace(s)}
+	for _, alt := range strings.Split(s, "||") {
+		set, err := parseComparatorSet(alt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version constraint %q: %w", s, err)
+		}
+		c.sets = append(c.sets, set)
+	}
+	return c, nil
+}
+
+func (c *Constraint) String() string {
+	return c.raw
+}
+
+// Check reports whether v satisfies the constraint.
+func (c *Constraint) Check(v Version) bool {
+	for _, set := range c.sets {
+		if setMatches(set, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func setMatches(set []comparator, v Version) bool {
+	for _, cmp := range set {
+		if !cmp.matches(v) {
+			return false
+		}
+	}
+	if !v.IsPrerelease() {
+		return true
+	}
+	for _, cmp := range set {
+		if cmp.v.IsPrerelease() && cmp.v.Major == v.Major && cmp.v.Minor == v.Minor && cmp.v.Patch == v.Patch {
+			return true
+		}
+	}
+	return false
+}
+
+func (c comparator) matches(v Version) bool {
+	r := v.Compare(c.v)
+	switch c.op {
+	case "=":
+		return r == 0
+	case ">":
+		return r > 0
+	case ">=":
+		return r >= 0
+	case "<":
+		return r < 0
+	case "<=":
+		return r <= 0
+	}
+	return false
+}
+
+func parseComparatorSet(s string) ([]comparator, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty range")
+	}
+
+	var set []comparator
+	for i := 0; i < len(fields); i++ {
+		tok := fields[i]
+		// Allow a space between an operator and its version: ">= 1.0.0".
+		if strings.Trim(tok, "<>=^~") == "" && i+1 < len(fields) {
+			i++
+			tok += fields[i]
+		}
+		cmps, err := parseComparator(tok)
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, cmps...)
+	}
+	return set, nil
+}
+
+func parseComparator(tok string) ([]comparator, error) {
+	op := ""
+	for _, prefix := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(tok, prefix) {
+			op = prefix
+			break
+		}
+	}
+	v, n, err := parsePartial(strings.TrimPrefix(tok, op))
+	if err != nil {
+		return nil, err
+	}
+
+	// next returns the first version past part (0 major, 1 minor, 2 patch) of v.
+	next := func(part int) Version {
+		switch part {
+		case 0:
+			return Version{Major: v.Major + 1}
+		case 1:
+			return Version{Major: v.Major, Minor: v.Minor + 1}
+		}
+		return Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+	}
+	rangeOf := func(upper Version) []comparator {
+		return []comparator{{">=", v}, {"<", upper}}
+	}
+	anyVersion := []comparator{{">=", Version{}}}
+
+	switch op {
+	case "", "=":
+		switch n {
+		case 0:
+			return anyVersion, nil
+		case 3:
+			return []comparator{{"=", v}}, nil
+		}
+		return rangeOf(next(n - 1)), nil
+	case "^":
+		switch {
+		case n == 0:
+			return anyVersion, nil
+		case v.Major > 0 || n == 1:
+			return rangeOf(next(0)), nil
+		case v.Minor > 0 || n == 2:
+			return rangeOf(next(1)), nil
+		}
+		return rangeOf(next(2)), nil
+	case "~":
+		switch n {
+		case 0:
+			return anyVersion, nil
+		case 1:
+			return rangeOf(next(0)), nil
+		}
+		return rangeOf(next(1)), nil
+	case ">":
+		switch n {
+		case 0:
+			return nil, fmt.Errorf("%q matches no version", tok)
+		case 3:
+			return []comparator{{">", v}}, nil
+		}
+		return []comparator{{">=", next(n - 1)}}, nil
+	case ">=":
+		return []comparator{{">=", v}}, nil
+	case "<":
+		if n == 0 {
+			return nil, fmt.Errorf("%q matches no version", tok)
+		}
+		return []comparator{{"<", v}}, nil
+	case "<=":
+		switch n {
+		case 0:
+			return anyVersion, nil
+		case 3:
+			return []comparator{{"<=", v}}, nil
+		}
+		return []comparator{{"<", next(n - 1)}}, nil
+	}
+	return nil, fmt.Errorf("unsupported operator in %q", tok)
+}
+
+// parsePartial parses a possibly incomplete version like "1", "1.2", "1.x"
+// or "*" and reports how many numeric parts were given.
+func parsePartial(s string) (Version, int, error) {
+	if s == "" {
+		return Version{}, 0, fmt.Errorf("missing version")
+	}
+	core, rest := s, ""
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		core, rest = s[:i], s[i:]
+	}
+
+	parts := strings.Split(core, ".")
+	if len(parts) > 3 {
+		return Version{}, 0, fmt.Errorf("%q is not a version", s)
+	}
+	n := 0
+	for _, p := range parts {
+		if p == "x" || p == "X" || p == "*" {
+			break
+		}
+		n++
+	}
+	for _, p := range parts[n:] {
+		if p != "x" && p != "X" && p != "*" {
+			return Version{}, 0, fmt.Errorf("%q is not a version", s)
+		}
+	}
+	if rest != "" && n < 3 {
+		return Version{}, 0, fmt.Errorf("prerelease or build in partial version %q", s)
+	}
+
+	full := make([]string, 3)
+	for i := range full {
+		full[i] = "0"
+		if i < n {
+			full[i] = parts[i]
+		}
+	}
+	v, err := Parse(strings.Join(full, ".") + rest)
+	if err != nil {
+		return Version{}, 0, err
+	}
+	return v, n, nil
+}
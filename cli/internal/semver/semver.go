@@ -0,0 +1,195 @@
+// Package semver parses and orders Semantic Versioning 2.0.0 versions.
+package semver
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed MAJOR.MINOR.PATCH[-PRERELEASE][+BUILD] version.
+type Version struct {
+	Major, Minor, Patch uint64
+	Prerelease          []string
+	Build               []string
+}
+
+// Parse parses a semver string. A leading "v" is not accepted.
+func Parse(s string) (Version, error) {
+	var v Version
+	rest := s
+	if i := strings.IndexByte(rest, '+'); i >= 0 {
+		build, err := identifiers(rest[i+1:], false)
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid build metadata in %q: %w", s, err)
+		}
+		v.Build = build
+		rest = rest[:i]
+	}
+	if i := strings.IndexByte(rest, '-'); i >= 0 {
+		pre, err := identifiers(rest[i+1:], true)
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid prerelease in %q: %w", s, err)
+		}
+		v.Prerelease = pre
+		rest = rest[:i]
+	}
+
+	parts := strings.Split(rest, ".")
+	if len(parts) != 3 {
+		return Version{}, fmt.Errorf("%q is not MAJOR.MINOR.PATCH", s)
+	}
+	nums := [3]*uint64{&v.Major, &v.Minor, &v.Patch}
+	for i, p := range parts {
+		n, err := parseNumber(p)
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid version %q: %w", s, err)
+		}
+		*nums[i] = n
+	}
+	return v, nil
+}
+
+// IsValid reports whether s is a valid semver string.
+func IsValid(s string) bool {
+	_, err := Parse(s)
+	return err == nil
+}
+
+func identifiers(s string, noLeadingZeros bool) ([]string, error) {
+	ids := strings.Split(s, ".")
+	for _, id := range ids {
+		if id == "" {
+			return nil, fmt.Errorf("empty identifier")
+		}
+		for _, c := range id {
+			if !isAlnum(c) && c != '-' {
+				return nil, fmt.Errorf("identifier %q must match [0-9A-Za-z-]", id)
+			}
+		}
+		if noLeadingZeros && isNumeric(id) && len(id) > 1 && id[0] == '0' {
+			return nil, fmt.Errorf("numeric identifier %q has a leading zero", id)
+		}
+	}
+	return ids, nil
+}
+
+func parseNumber(s string) (uint64, error) {
+	if s == "" || !isNumeric(s) {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	if len(s) > 1 && s[0] == '0' {
+		return 0, fmt.Errorf("%q has a leading zero", s)
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func isNumeric(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isAlnum(c rune) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// String formats v in canonical form.
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Prerelease) > 0 {
+		s += "-" + strings.Join(v.Prerelease, ".")
+	}
+	if len(v.Build) > 0 {
+		s += "+" + strings.Join(v.Build, ".")
+	}
+	return s
+}
+
+// IsPrerelease reports whether v has a prerelease component.
+func (v Version) IsPrerelease() bool {
+	return len(v.Prerelease) > 0
+}
+
+// Compare returns -1, 0 or +1 as v is lower than, equal to or higher than o
+// in semver precedence. Build metadata is ignored.
+func (v Version) Compare(o Version) int {
+	for _, d := range [3][2]uint64{{v.Major, o.Major}, {v.Minor, o.Minor}, {v.Patch, o.Patch}} {
+		if d[0] != d[1] {
+			if d[0] < d[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	// A version without prerelease has higher precedence than one with.
+	switch {
+	case len(v.Prerelease) == 0 && len(o.Prerelease) == 0:
+		return 0
+	case len(v.Prerelease) == 0:
+		return 1
+	case len(o.Prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.Prerelease) && i < len(o.Prerelease); i++ {
+		if c := compareIdentifier(v.Prerelease[i], o.Prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(v.Prerelease) < len(o.Prerelease):
+		return -1
+	case len(v.Prerelease) > len(o.Prerelease):
+		return 1
+	}
+	return 0
+}
+
+// compareIdentifier orders prerelease identifiers: numeric ones compare
+// numerically and sort before alphanumeric ones, which compare in ASCII order.
+func compareIdentifier(a, b string) int {
+	an, bn := isNumeric(a), isNumeric(b)
+	switch {
+	case an && bn:
+		if len(a) != len(b) {
+			if len(a) < len(b) {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a, b)
+	case an:
+		return -1
+	case bn:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// Compare compares two version strings. Invalid versions sort before valid
+// ones and are compared as plain strings among themselves.
+func Compare(a, b string) int {
+	va, errA := Parse(a)
+	vb, errB := Parse(b)
+	switch {
+	case errA != nil && errB != nil:
+		return strings.Compare(a, b)
+	case errA != nil:
+		return -1
+	case errB != nil:
+		return 1
+	}
+	return va.Compare(vb)
+}
+
+// Sort sorts version strings in ascending semver order.
+func Sort(versions []string) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		return Compare(versions[i], versions[j]) < 0
+	})
+}
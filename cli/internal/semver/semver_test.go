@@ -0,0 +1,102 @@
+package semver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	valid := []string{"0.0.0", "1.2.3", "10.20.30", "2.0.0-beta.1", "1.0.0-alpha-a.b-c", "1.0.0+build.5", "1.0.0-rc.1+sha.abc123"}
+	for _, s := range valid {
+		v, err := Parse(s)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", s, err)
+			continue
+		}
+		if v.String() != s {
+			t.Errorf("Parse(%q).String() = %q", s, v.String())
+		}
+	}
+
+	invalid := []string{"", "1", "1.2", "1.2.3.4", "01.2.3", "1.02.3", "v1.2.3", "1.2.3-", "1.2.3-01", "1.2.3-a..b", "1.2.3+", "1.2.3-a_b", "1.2.x"}
+	for _, s := range invalid {
+		if IsValid(s) {
+			t.Errorf("IsValid(%q) = true, want false", s)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	// Precedence example from the semver 2.0.0 specification, ascending.
+	ordered := []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta",
+		"1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.4.1", "2.0.0",
+	}
+	for i := 0; i < len(ordered)-1; i++ {
+		if c := Compare(ordered[i], ordered[i+1]); c != -1 {
+			t.Errorf("Compare(%q, %q) = %d, want -1", ordered[i], ordered[i+1], c)
+		}
+		if c := Compare(ordered[i+1], ordered[i]); c != 1 {
+			t.Errorf("Compare(%q, %q) = %d, want 1", ordered[i+1], ordered[i], c)
+		}
+	}
+	if c := Compare("1.0.0+a", "1.0.0+b"); c != 0 {
+		t.Errorf("build metadata should not affect precedence, got %d", c)
+	}
+}
+
+func TestSort(t *testing.T) {
+	got := []string{"2.0.0", "1.4.1", "10.0.0", "2.0.0-beta.1", "1.10.0", "bogus"}
+	Sort(got)
+	want := []string{"bogus", "1.4.1", "1.10.0", "2.0.0-beta.1", "2.0.0", "10.0.0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Sort() = %v, want %v", got, want)
+	}
+}
+
+func TestConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		match      []string
+		noMatch    []string
+	}{
+		{"^1.2", []string{"1.2.0", "1.9.9"}, []string{"1.1.9", "2.0.0", "1.3.0-beta.1"}},
+		{"^1", []string{"1.0.0", "1.99.0"}, []string{"0.9.0", "2.0.0"}},
+		{"^0.2.3", []string{"0.2.3", "0.2.9"}, []string{"0.3.0", "0.2.2"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4"}},
+		{"~1.2.3", []string{"1.2.3", "1.2.10"}, []string{"1.3.0", "1.2.2"}},
+		{"~1", []string{"1.0.0", "1.5.0"}, []string{"2.0.0"}},
+		{">=1.0.0 <2.0.0", []string{"1.0.0", "1.9.9"}, []string{"0.9.9", "2.0.0", "2.0.0-rc.1"}},
+		{">= 1.0.0", []string{"1.0.0", "5.0.0"}, []string{"0.1.0"}},
+		{">1.2", []string{"1.3.0"}, []string{"1.2.9"}},
+		{"<=1.2", []string{"1.2.9"}, []string{"1.3.0"}},
+		{"1.2.x", []string{"1.2.0", "1.2.5"}, []string{"1.3.0"}},
+		{"*", []string{"0.0.1", "9.0.0"}, []string{"1.0.0-alpha"}},
+		{"1.2.3", []string{"1.2.3"}, []string{"1.2.4"}},
+		{"^2.0.0-beta.1", []string{"2.0.0-beta.2", "2.0.0", "2.1.0"}, []string{"2.0.0-alpha", "2.1.0-beta.1", "3.0.0"}},
+		{"^1 || ^3", []string{"1.5.0", "3.0.0"}, []string{"2.0.0"}},
+	}
+	for _, tt := range tests {
+		c, err := ParseConstraint(tt.constraint)
+		if err != nil {
+			t.Errorf("ParseConstraint(%q) error = %v", tt.constraint, err)
+			continue
+		}
+		for _, s := range tt.match {
+			if v, _ := Parse(s); !c.Check(v) {
+				t.Errorf("%q should match %s", tt.constraint, s)
+			}
+		}
+		for _, s := range tt.noMatch {
+			if v, _ := Parse(s); c.Check(v) {
+				t.Errorf("%q should not match %s", tt.constraint, s)
+			}
+		}
+	}
+
+	for _, bad := range []string{"", "^", "abc", "^1.2.3.4", ">=1.x.2", "1.2-beta", "<*"} {
+		if _, err := ParseConstraint(bad); err == nil {
+			t.Errorf("ParseConstraint(%q) should fail", bad)
+		}
+	}
+}
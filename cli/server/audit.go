@@ -0,0 +1,106 @@
+//go:build server
+
+package server
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// AuditEvent is one entry of the audit log. Every successful mutation of
+// the registry appends one; entries are never changed or removed.
+type AuditEvent struct {
+	ID      string    `json:"id"`
+	Time    time.Time `json:"time"`
+	Actor   string    `json:"actor"`              // username, "admin-token", "anonymous" or RetentionActor
+	TokenID string    `json:"token_id,omitempty"` // the per-user token used, if any
+	Action  string    `json:"action"`             // one of the Audit constants
+	// Target is the skill name for skill actions, "@<org>" for
+	// organization actions and the token id for token actions.
+	Target   string `json:"target"`
+	Version  string `json:"version,omitempty"`
+	Checksum string `json:"checksum,omitempty"`
+	// Detail holds action-specific context, such as the dist-tag, the
+	// collaborator or the yank reason.
+	Detail   string `json:"detail,omitempty"`
+	ClientIP string `json:"client_ip"`
+}
+
+// Audit actions.
+const (
+	AuditPublish            = "skill.publish"
+	AuditYank               = "skill.yank"
+	AuditUnyank             = "skill.unyank"
+	AuditDeprecate          = "skill.deprecate"
+	AuditPrune              = "skill.prune"
+	AuditVisibility         = "skill.visibility"
+	AuditDistTagSet         = "dist_tag.set"
+	AuditDistTagRemove      = "dist_tag.remove"
+	AuditCollaboratorAdd    = "owner.add_collaborator"
+	AuditCollaboratorRemove = "owner.remove_collaborator"
+	AuditTransfer           = "owner.transfer"
+	AuditTokenCreate        = "token.create"
+	AuditTokenRevoke        = "token.revoke"
+	AuditOrgCreate          = "org.create"
+	AuditOrgMemberSet       = "org.set_member"
+	AuditOrgMemberRemove    = "org.remove_member"
+)
+
+// RetentionActor is the actor of the events recorded by Prune.
+const RetentionActor = "retention-policy"
+
+// AuditFilter selects audit events; zero fields match everything.
+type AuditFilter struct {
+	Target string
+	Actor  string
+	Since  time.Time
+	// Limit keeps only the newest Limit matching events; 0 keeps all.
+	Limit int
+}
+
+// Matches reports whether e passes the filter, ignoring Limit.
+func (f AuditFilter) Matches(e *AuditEvent) bool {
+	return (f.Target == "" || e.Target == f.Target) &&
+		(f.Actor == "" || e.Actor == f.Actor) &&
+		(f.Since.IsZero() || !e.Time.Before(f.Since))
+}
+
+// audit records a successful mutation made by caller. The event's ID,
+// time, actor, token and client IP are filled in here. The change is
+// already saved, so a failure to record it is logged rather than returned
+// to the client.
+func (h *Handler) audit(r *http.Request, caller *principal, e AuditEvent) {
+	e.ID = newID()
+	e.Time = time.Now().UTC()
+	e.Actor = caller.actor()
+	if caller.token != nil {
+		e.TokenID = caller.token.ID
+	}
+	e.ClientIP = h.clientIP(r)
+	if err := h.meta.AppendAudit(e); err != nil {
+		log.Printf("recording audit event %s on %s by %s: %v", e.Action, e.Target, e.Actor, err)
+	}
+}
+
+// clientIP returns the address of the client that sent r. Behind a
+// reverse proxy, with Options.TrustProxy, it is the last address in
+// X-Forwarded-For: the one the proxy appended. Earlier addresses were
+// sent by the client and may be forged.
+func (h *Handler) clientIP(r *http.Request) string {
+	if h.opts.TrustProxy {
+		if fwd := r.Header.Values("X-Forwarded-For"); len(fwd) > 0 {
+			hops := strings.Split(fwd[len(fwd)-1], ",")
+			if last := strings.TrimSpace(hops[len(hops)-1]); last != "" {
+				return last
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,820 @@
+//go:build server
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FSMetaStore is a file-system-based metadata store.
+// Layout:
+//
+//	{dataDir}/bundles/{name}/meta.json
+//	{dataDir}/bundles/@{org}/{name}/meta.json
+//	{dataDir}/users.json
+//	{dataDir}/orgs.json
+//	{dataDir}/audit.log
+type FSMetaStore struct {
+	dataDir string
+	mu      sync.RWMutex
+}
+
+func NewFSMetaStore(dataDir string) *FSMetaStore {
+	return &FSMetaStore{dataDir: dataDir}
+}
+
+func (s *FSMetaStore) bundlesDir() string {
+	return filepath.Join(s.dataDir, "bundles")
+}
+
+func (s *FSMetaStore) skillDir(name string) string {
+	return filepath.Join(s.bundlesDir(), name)
+}
+
+func (s *FSMetaStore) metaPath(name string) string {
+	return filepath.Join(s.skillDir(name), "meta.json")
+}
+
+// PutVersion records a published version in meta.json.
+func (s *FSMetaStore) PutVersion(name, owner, description string, tags []string, vm VersionMeta, replace bool, check func(*SkillMeta) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.skillDir(name), 0o755); err != nil {
+		return fmt.Errorf("creating skill dir: %w", err)
+	}
+
+	meta, err := s.loadMetaLocked(name)
+	if err != nil {
+		return err
+	}
+	if check != nil {
+		if err := check(meta); err != nil {
+			return err
+		}
+	}
+	if meta == nil {
+		meta = &SkillMeta{Name: name}
+	}
+	if !replace && meta.FindVersion(vm.Version) != nil {
+		return ErrVersionExists
+	}
+	meta.applyVersion(owner, description, tags, vm)
+
+	return s.saveMetaLocked(name, meta)
+}
+
+// GetSkill returns metadata for a skill, or nil if not found.
+func (s *FSMetaStore) GetSkill(name string) (*SkillMeta, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.loadMetaLocked(name)
+}
+
+// UpdateSkill applies fn to meta.json under the store lock.
+func (s *FSMetaStore) UpdateSkill(name string, fn func(*SkillMeta) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	meta, err := s.loadMetaLocked(name)
+	if err != nil {
+		return err
+	}
+	if meta == nil {
+		return ErrNotFound
+	}
+	if err := fn(meta); err != nil {
+		return err
+	}
+	return s.saveMetaLocked(name, meta)
+}
+
+// IncrementDownloads increments the download counter and stamps the
+// version's last download in meta.json.
+func (s *FSMetaStore) IncrementDownloads(name, version string, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	meta, err := s.loadMetaLocked(name)
+	if err != nil || meta == nil {
+		return err
+	}
+	meta.Downloads++
+	if vm := meta.FindVersion(version); vm != nil {
+		t = t.UTC().Truncate(time.Second)
+		vm.LastDownloadedAt = &t
+	}
+	return s.saveMetaLocked(name, meta)
+}
+
+// Search scans every skill directory for matching metadata.
+func (s *FSMetaStore) Search(keyword string) ([]SkillMeta, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keyword = strings.ToLower(keyword)
+	var results []SkillMeta
+	names, err := s.skillNamesLocked()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		meta, err := s.loadMetaLocked(name)
+		if err != nil || meta == nil {
+			continue
+		}
+		if matchesKeyword(meta, keyword) {
+			results = append(results, *meta)
+		}
+	}
+	return results, nil
+}
+
+// ListSkills loads every meta.json.
+func (s *FSMetaStore) ListSkills() ([]SkillMeta, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names, err := s.skillNamesLocked()
+	if err != nil {
+		return nil, err
+	}
+	var skills []SkillMeta
+	for _, name := range names {
+		meta, err := s.loadMetaLocked(name)
+		if err != nil {
+			return nil, fmt.Errorf("loading skill %s: %w", name, err)
+		}
+		if meta != nil {
+			skills = append(skills, *meta)
+		}
+	}
+	return skills, nil
+}
+
+// Usage scans every skill directory for the skills charged to owner.
+func (s *FSMetaStore) Usage(owner string) ([]SkillUsage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names, err := s.skillNamesLocked()
+	if err != nil {
+		return nil, err
+	}
+	var usage []SkillUsage
+	for _, name := range names {
+		meta, err := s.loadMetaLocked(name)
+		if err != nil {
+			return nil, err
+		}
+		if meta == nil || quotaOwner(meta.Name, meta.Owner) != owner {
+			continue
+		}
+		u := SkillUsage{Name: meta.Name, Versions: len(meta.Versions)}
+		for _, vm := range meta.Versions {
+			u.Bytes += vm.SizeBytes
+		}
+		usage = append(usage, u)
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Name < usage[j].Name })
+	return usage, nil
+}
+
+// skillNamesLocked lists the skill directories under bundles/.
+func (s *FSMetaStore) skillNamesLocked() ([]string, error) {
+	entries, err := os.ReadDir(s.bundlesDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading bundles dir: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if !strings.HasPrefix(entry.Name(), "@") {
+			names = append(names, entry.Name())
+			continue
+		}
+		// Scoped skills live one level down, in bundles/@org/name.
+		scoped, err := os.ReadDir(filepath.Join(s.bundlesDir(), entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("reading scope dir: %w", err)
+		}
+		for _, e := range scoped {
+			if e.IsDir() {
+				names = append(names, entry.Name()+"/"+e.Name())
+			}
+		}
+	}
+	return names, nil
+}
+
+// fsUsers is the content of users.json.
+type fsUsers struct {
+	Users  []User     `json:"users"`
+	Tokens []APIToken `json:"tokens"`
+}
+
+// fsLegacyUser is an entry of the original users.json, a plain array of
+// users with their token in clear text.
+type fsLegacyUser struct {
+	Username string `json:"username"`
+	APIToken string `json:"api_token"`
+}
+
+func (s *FSMetaStore) usersPath() string {
+	return filepath.Join(s.dataDir, "users.json")
+}
+
+// CreateUser adds a user to users.json.
+func (s *FSMetaStore) CreateUser(u User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users, err := s.loadUsersLocked()
+	if err != nil {
+		return err
+	}
+	for _, existing := range users.Users {
+		if existing.Username == u.Username {
+			return ErrUserExists
+		}
+	}
+	users.Users = append(users.Users, u)
+	return s.saveUsersLocked(users)
+}
+
+// GetUser looks the user up in users.json.
+func (s *FSMetaStore) GetUser(username string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	users, err := s.loadUsersLocked()
+	if err != nil {
+		return nil, err
+	}
+	for i := range users.Users {
+		if users.Users[i].Username == username {
+			return &users.Users[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// HasUsers reports whether users.json lists any user.
+func (s *FSMetaStore) HasUsers() (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	users, err := s.loadUsersLocked()
+	if err != nil {
+		return false, err
+	}
+	return len(users.Users) > 0, nil
+}
+
+// CreateToken appends a token to users.json.
+func (s *FSMetaStore) CreateToken(t APIToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users, err := s.loadUsersLocked()
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, u := range users.Users {
+		if u.Username == t.Username {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("creating token: user %q: %w", t.Username, ErrNotFound)
+	}
+	users.Tokens = append(users.Tokens, t)
+	return s.saveUsersLocked(users)
+}
+
+// TokenByHash scans users.json for the token hash.
+func (s *FSMetaStore) TokenByHash(hash string) (*APIToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	users, err := s.loadUsersLocked()
+	if err != nil {
+		return nil, err
+	}
+	for i := range users.Tokens {
+		if users.Tokens[i].Hash == hash {
+			return &users.Tokens[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// ListTokens returns the user's tokens in creation order.
+func (s *FSMetaStore) ListTokens(username string) ([]APIToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	users, err := s.loadUsersLocked()
+	if err != nil {
+		return nil, err
+	}
+	var tokens []APIToken
+	for _, t := range users.Tokens {
+		if t.Username == username {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens, nil
+}
+
+// DeleteToken removes a token from users.json.
+func (s *FSMetaStore) DeleteToken(username, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users, err := s.loadUsersLocked()
+	if err != nil {
+		return err
+	}
+	for i, t := range users.Tokens {
+		if t.Username == username && t.ID == id {
+			users.Tokens = append(users.Tokens[:i], users.Tokens[i+1:]...)
+			return s.saveUsersLocked(users)
+		}
+	}
+	return ErrNotFound
+}
+
+// TouchToken updates last_used_at of a token in users.json.
+func (s *FSMetaStore) TouchToken(id string, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users, err := s.loadUsersLocked()
+	if err != nil {
+		return err
+	}
+	for i := range users.Tokens {
+		if users.Tokens[i].ID == id {
+			users.Tokens[i].LastUsedAt = &t
+			return s.saveUsersLocked(users)
+		}
+	}
+	return nil
+}
+
+// fsOrgs is the content of orgs.json.
+type fsOrgs struct {
+	Orgs []Org `json:"orgs"`
+}
+
+func (s *FSMetaStore) orgsPath() string {
+	return filepath.Join(s.dataDir, "orgs.json")
+}
+
+// CreateOrg adds an organization to orgs.json.
+func (s *FSMetaStore) CreateOrg(o Org) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	orgs, err := s.loadOrgsLocked()
+	if err != nil {
+		return err
+	}
+	for _, existing := range orgs.Orgs {
+		if existing.Name == o.Name {
+			return ErrOrgExists
+		}
+	}
+	orgs.Orgs = append(orgs.Orgs, o)
+	return s.saveOrgsLocked(orgs)
+}
+
+// GetOrg looks the organization up in orgs.json.
+func (s *FSMetaStore) GetOrg(name string) (*Org, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	orgs, err := s.loadOrgsLocked()
+	if err != nil {
+		return nil, err
+	}
+	for i := range orgs.Orgs {
+		if orgs.Orgs[i].Name == name {
+			return &orgs.Orgs[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// UpdateOrg applies fn to an organization in orgs.json under the store lock.
+func (s *FSMetaStore) UpdateOrg(name string, fn func(*Org) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	orgs, err := s.loadOrgsLocked()
+	if err != nil {
+		return err
+	}
+	for i := range orgs.Orgs {
+		if orgs.Orgs[i].Name == name {
+			if err := fn(&orgs.Orgs[i]); err != nil {
+				return err
+			}
+			return s.saveOrgsLocked(orgs)
+		}
+	}
+	return ErrNotFound
+}
+
+// UserOrgs scans orgs.json for the user's memberships.
+func (s *FSMetaStore) UserOrgs(username string) (map[string]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	orgs, err := s.loadOrgsLocked()
+	if err != nil {
+		return nil, err
+	}
+	roles := make(map[string]string)
+	for i := range orgs.Orgs {
+		if role := orgs.Orgs[i].Role(username); role != "" {
+			roles[orgs.Orgs[i].Name] = role
+		}
+	}
+	return roles, nil
+}
+
+// loadOrgsLocked reads orgs.json. A missing file means no organizations.
+func (s *FSMetaStore) loadOrgsLocked() (*fsOrgs, error) {
+	data, err := os.ReadFile(s.orgsPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &fsOrgs{}, nil
+		}
+		return nil, fmt.Errorf("reading orgs: %w", err)
+	}
+	var orgs fsOrgs
+	if err := json.Unmarshal(data, &orgs); err != nil {
+		return nil, fmt.Errorf("parsing orgs: %w", err)
+	}
+	return &orgs, nil
+}
+
+func (s *FSMetaStore) saveOrgsLocked(orgs *fsOrgs) error {
+	data, err := json.MarshalIndent(orgs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling orgs: %w", err)
+	}
+	if err := os.MkdirAll(s.dataDir, 0o755); err != nil {
+		return fmt.Errorf("creating data dir: %w", err)
+	}
+	return os.WriteFile(s.orgsPath(), data, 0o644)
+}
+
+func (s *FSMetaStore) auditPath() string {
+	return filepath.Join(s.dataDir, "audit.log")
+}
+
+// AppendAudit appends the event to audit.log as a line of JSON. The file
+// is only ever opened for appending.
+func (s *FSMetaStore) AppendAudit(e AuditEvent) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshaling audit event: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(s.dataDir, 0o755); err != nil {
+		return fmt.Errorf("creating data dir: %w", err)
+	}
+	f, err := os.OpenFile(s.auditPath(), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening audit log: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("writing audit log: %w", err)
+	}
+	return f.Close()
+}
+
+// ListAudit scans audit.log for the matching events.
+func (s *FSMetaStore) ListAudit(filter AuditFilter) ([]AuditEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	f, err := os.Open(s.auditPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("opening audit log: %w", err)
+	}
+	defer f.Close()
+
+	var events []AuditEvent
+	dec := json.NewDecoder(f)
+	for {
+		var e AuditEvent
+		if err := dec.Decode(&e); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("parsing audit log: %w", err)
+		}
+		if filter.Matches(&e) {
+			events = append(events, e)
+		}
+	}
+	if filter.Limit > 0 && len(events) > filter.Limit {
+		events = events[len(events)-filter.Limit:]
+	}
+	return events, nil
+}
+
+// loadUsersLocked reads users.json. A missing file means no users. The
+// original clear-text format is converted to hashed tokens on read and
+// rewritten on the next save.
+func (s *FSMetaStore) loadUsersLocked() (*fsUsers, error) {
+	data, err := os.ReadFile(s.usersPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &fsUsers{}, nil
+		}
+		return nil, fmt.Errorf("reading users: %w", err)
+	}
+
+	var users fsUsers
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var legacy []fsLegacyUser
+		if err := json.Unmarshal(data, &legacy); err != nil {
+			return nil, fmt.Errorf("parsing users: %w", err)
+		}
+		for _, u := range legacy {
+			users.Users = append(users.Users, User{Username: u.Username})
+			if u.APIToken != "" {
+				users.Tokens = append(users.Tokens, APIToken{
+					ID:       "legacy-" + u.Username,
+					Username: u.Username,
+					Name:     "legacy",
+					Hash:     HashToken(u.APIToken),
+				})
+			}
+		}
+		return &users, nil
+	}
+	if err := json.Unmarshal(data, &users); err != nil {
+		return nil, fmt.Errorf("parsing users: %w", err)
+	}
+	return &users, nil
+}
+
+// saveUsersLocked writes users.json readable by the server user only.
+func (s *FSMetaStore) saveUsersLocked(users *fsUsers) error {
+	data, err := json.MarshalIndent(users, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling users: %w", err)
+	}
+	if err := os.MkdirAll(s.dataDir, 0o755); err != nil {
+		return fmt.Errorf("creating data dir: %w", err)
+	}
+	return os.WriteFile(s.usersPath(), data, 0o600)
+}
+
+// loadMetaLocked reads meta.json for a skill.
+// Returns nil (no error) if the file does not exist.
+func (s *FSMetaStore) loadMetaLocked(name string) (*SkillMeta, error) {
+	data, err := os.ReadFile(s.metaPath(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading meta: %w", err)
+	}
+	var meta SkillMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("parsing meta: %w", err)
+	}
+	// meta.json written before versions were kept sorted is in publish order.
+	sortVersions(meta.Versions)
+	return &meta, nil
+}
+
+func (s *FSMetaStore) saveMetaLocked(name string, meta *SkillMeta) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling meta: %w", err)
+	}
+	return os.WriteFile(s.metaPath(name), data, 0o644)
+}
+
+// FSBlobStore is a file-system-based bundle store.
+// Layout:
+//
+//	{dataDir}/blobs/sha256/{hex[:2]}/{hex}
+//
+// Bundles published before blobs were content-addressed may remain at
+//
+//	{dataDir}/bundles/{name}/{version}/bundle.tar.gz
+//	{dataDir}/bundles/@{org}/{name}/{version}/bundle.tar.gz
+//
+// until CollectGarbage moves them.
+type FSBlobStore struct {
+	dataDir string
+}
+
+func NewFSBlobStore(dataDir string) *FSBlobStore {
+	return &FSBlobStore{dataDir: dataDir}
+}
+
+func (s *FSBlobStore) blobPath(digest string) (string, error) {
+	if !digestRegex.MatchString(digest) {
+		return "", fmt.Errorf("invalid digest %q", digest)
+	}
+	return filepath.Join(s.dataDir, filepath.FromSlash(blobKey(digest))), nil
+}
+
+func (s *FSBlobStore) bundlePath(name, version string) string {
+	return filepath.Join(s.dataDir, "bundles", name, version, "bundle.tar.gz")
+}
+
+// PutBlob writes the blob to a temp file and renames it into place, so
+// concurrent readers never observe a partially written blob. A blob that
+// exists is not written again.
+func (s *FSBlobStore) PutBlob(digest string, r io.Reader) error {
+	dest, err := s.blobPath(digest)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if err := os.Chtimes(dest, now, now); err == nil {
+		return nil
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("touching blob: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return fmt.Errorf("creating blob dir: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".blob-*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating temp blob: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing blob: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing blob: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("writing blob: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return fmt.Errorf("writing blob: %w", err)
+	}
+	return nil
+}
+
+// StagingDir returns {dataDir}/blobs/uploads, which is on the same file
+// system as the blobs so that MoveBlob is a rename.
+func (s *FSBlobStore) StagingDir() (string, error) {
+	dir := filepath.Join(s.dataDir, "blobs", "uploads")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("creating staging dir: %w", err)
+	}
+	return dir, nil
+}
+
+// MoveBlob renames the file into place. Files on another file system are
+// copied by PutBlob.
+func (s *FSBlobStore) MoveBlob(digest, path string) error {
+	dest, err := s.blobPath(digest)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if err := os.Chtimes(dest, now, now); err == nil {
+		os.Remove(path)
+		return nil
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("touching blob: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return fmt.Errorf("creating blob dir: %w", err)
+	}
+	if err := os.Chmod(path, 0o644); err != nil {
+		return fmt.Errorf("moving blob: %w", err)
+	}
+	// A rename keeps the time the upload was written; count the blob from
+	// now for CollectGarbage's grace period, as PutBlob would.
+	if err := os.Chtimes(path, now, now); err != nil {
+		return fmt.Errorf("moving blob: %w", err)
+	}
+	if err := os.Rename(path, dest); err != nil {
+		if !errors.Is(err, syscall.EXDEV) {
+			return fmt.Errorf("moving blob: %w", err)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("moving blob: %w", err)
+		}
+		defer os.Remove(path)
+		defer f.Close()
+		return s.PutBlob(digest, f)
+	}
+	return nil
+}
+
+// OpenBlob opens the blob file. The returned file implements io.Seeker.
+func (s *FSBlobStore) OpenBlob(digest string) (io.ReadCloser, error) {
+	path, err := s.blobPath(digest)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	return openFile(path)
+}
+
+// DeleteBlob removes the blob file.
+func (s *FSBlobStore) DeleteBlob(digest string) error {
+	path, err := s.blobPath(digest)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("deleting blob: %w", err)
+	}
+	return nil
+}
+
+// ListBlobs walks the blobs directory. Temp files of unfinished writes
+// are skipped.
+func (s *FSBlobStore) ListBlobs(fn func(digest string, modTime time.Time) error) error {
+	root := filepath.Join(s.dataDir, filepath.FromSlash(blobPrefix))
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == root {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(s.dataDir, path)
+		if err != nil {
+			return err
+		}
+		digest := digestFromKey(filepath.ToSlash(rel))
+		if digest == "" {
+			return nil
+		}
+		info, err := d.Info()
+		if os.IsNotExist(err) {
+			return nil // deleted while walking
+		}
+		if err != nil {
+			return err
+		}
+		return fn(digest, info.ModTime())
+	})
+	if err != nil {
+		return fmt.Errorf("listing blobs: %w", err)
+	}
+	return nil
+}
+
+// OpenBundle opens a bundle in the legacy layout.
+func (s *FSBlobStore) OpenBundle(name, version string) (io.ReadCloser, error) {
+	return openFile(s.bundlePath(name, version))
+}
+
+// DeleteBundle removes a bundle in the legacy layout and its version
+// directory.
+func (s *FSBlobStore) DeleteBundle(name, version string) error {
+	path := s.bundlePath(name, version)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("deleting bundle: %w", err)
+	}
+	// The directory holds nothing else; leave it if it somehow does.
+	os.Remove(filepath.Dir(path))
+	return nil
+}
+
+func openFile(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("opening bundle: %w", err)
+	}
+	return f, nil
+}
@@ -0,0 +1,148 @@
+//go:build server
+
+package server
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// GCResult summarizes a CollectGarbage run.
+type GCResult struct {
+	// Referenced is the number of distinct blobs that versions point at.
+	Referenced int
+	// Blobs is the number of blobs found in the store.
+	Blobs int
+	// Removed are the digests of the unreferenced blobs deleted, or that
+	// would be deleted in a dry run.
+	Removed []string
+	// Migrated is the number of legacy bundles moved into the blob store.
+	Migrated int
+}
+
+// CollectGarbage deletes the blobs that no version points at, by mark and
+// sweep: it first collects the digests of every version, then lists the
+// blobs and deletes the others.
+//
+// It is safe to run while the server accepts publishes. A publish writes
+// or touches its blob before it records the version, so a blob that is
+// about to be referenced was modified recently; blobs modified within
+// grace of now are never deleted. grace must exceed the time a publish
+// takes between storing its bundle and recording its version.
+//
+// If blobs also holds bundles in the legacy layout, the ones of published
+// versions are first moved into the blob store. With dryRun, nothing is
+// changed and the result reports what would be.
+func CollectGarbage(meta MetaStore, blobs BlobStore, grace time.Duration, now time.Time, dryRun bool) (GCResult, error) {
+	var res GCResult
+
+	// Mark. A skill that fails to load would leave its blobs unmarked, so
+	// any error stops the collection.
+	skills, err := meta.ListSkills()
+	if err != nil {
+		return res, fmt.Errorf("listing skills: %w", err)
+	}
+	referenced := make(map[string]bool)
+	for _, s := range skills {
+		for _, vm := range s.Versions {
+			referenced[vm.Checksum] = true
+		}
+	}
+	res.Referenced = len(referenced)
+
+	if legacy, ok := blobs.(LegacyBundleStore); ok {
+		if res.Migrated, err = migrateLegacyBundles(blobs, legacy, skills, dryRun); err != nil {
+			return res, err
+		}
+	}
+
+	// Sweep.
+	cutoff := now.Add(-grace)
+	err = blobs.ListBlobs(func(digest string, modTime time.Time) error {
+		res.Blobs++
+		if referenced[digest] || modTime.After(cutoff) {
+			return nil
+		}
+		if !dryRun {
+			if err := blobs.DeleteBlob(digest); err != nil {
+				return err
+			}
+		}
+		res.Removed = append(res.Removed, digest)
+		return nil
+	})
+	if err != nil {
+		return res, fmt.Errorf("sweeping blobs: %w", err)
+	}
+	return res, nil
+}
+
+// migrateLegacyBundles copies the legacy bundle of every version whose
+// blob is missing into the blob store, after checking it against the
+// version's checksum, and deletes the legacy copy. It returns the number
+// of bundles moved.
+func migrateLegacyBundles(blobs BlobStore, legacy LegacyBundleStore, skills []SkillMeta, dryRun bool) (int, error) {
+	stored := make(map[string]bool)
+	err := blobs.ListBlobs(func(digest string, _ time.Time) error {
+		stored[digest] = true
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("listing blobs: %w", err)
+	}
+
+	migrated := 0
+	for _, s := range skills {
+		for _, vm := range s.Versions {
+			if stored[vm.Checksum] {
+				continue
+			}
+			ok, err := migrateLegacyBundle(blobs, legacy, s.Name, vm, dryRun)
+			if err != nil {
+				return migrated, fmt.Errorf("migrating %s@%s: %w", s.Name, vm.Version, err)
+			}
+			if ok {
+				migrated++
+			}
+		}
+	}
+	return migrated, nil
+}
+
+// migrateLegacyBundle moves one legacy bundle and reports whether there
+// was one. The bundle is read twice, to check it before anything is
+// written under its digest.
+func migrateLegacyBundle(blobs BlobStore, legacy LegacyBundleStore, name string, vm VersionMeta, dryRun bool) (bool, error) {
+	rc, err := legacy.OpenBundle(name, vm.Version)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	h := sha256.New()
+	_, err = io.Copy(h, rc)
+	rc.Close()
+	if err != nil {
+		return false, fmt.Errorf("reading bundle: %w", err)
+	}
+	if digest := fmt.Sprintf("sha256:%x", h.Sum(nil)); digest != vm.Checksum {
+		return false, fmt.Errorf("bundle has digest %s, want %s", digest, vm.Checksum)
+	}
+	if dryRun {
+		return true, nil
+	}
+
+	rc, err = legacy.OpenBundle(name, vm.Version)
+	if err != nil {
+		return false, err
+	}
+	defer rc.Close()
+	if err := blobs.PutBlob(vm.Checksum, rc); err != nil {
+		return false, err
+	}
+	return true, legacy.DeleteBundle(name, vm.Version)
+}
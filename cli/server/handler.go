@@ -4,35 +4,134 @@ package server
 
 import (
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/liuyukai/agentskills-cli/internal/bundle"
 	"github.com/liuyukai/agentskills-cli/internal/parser"
 )
 
+// Options configures a Handler.
+type Options struct {
+	// Token, if non-empty, is a shared admin token that may manage every
+	// skill. Without it, and until a user is registered, requests that
+	// carry no known user token are let through as admin (unauthenticated
+	// development mode).
+	Token string
+
+	// InsecureOpen keeps development mode on without Token even after users
+	// are registered, letting anyone act as admin.
+	InsecureOpen bool
+
+	// AllowRepublish lets a publish replace an existing version's bundle
+	// and checksum. Published versions are immutable unless the server
+	// operator opts in.
+	AllowRepublish bool
+
+	// TrustProxy takes the client IP, as recorded in the audit log and
+	// used for rate limits, from the last X-Forwarded-For address, for
+	// servers behind a reverse proxy that appends to that header.
+	TrustProxy bool
+
+	// IPRateLimits and TokenRateLimits throttle API requests per client IP
+	// and per bearer token. A request must be within both; zero limits are
+	// off.
+	IPRateLimits    RateLimits
+	TokenRateLimits RateLimits
+
+	// Quotas limit bundle sizes and the storage used by each publisher,
+	// checked at publish time.
+	Quotas Quotas
+}
+
 // Handler holds the HTTP handler dependencies.
 type Handler struct {
-	store *Store
-	token string // if non-empty, require this bearer token for publish
+	meta   MetaStore
+	blobs  BlobStore
+	opts   Options
+	limits rateLimiters
 }
 
-func NewHandler(store *Store, token string) *Handler {
-	return &Handler{store: store, token: token}
+func NewHandler(meta MetaStore, blobs BlobStore, opts Options) *Handler {
+	return &Handler{meta: meta, blobs: blobs, opts: opts, limits: newRateLimiters(opts)}
 }
 
 // RegisterRoutes registers all API routes on the given mux.
 // Uses Go 1.22+ enhanced ServeMux patterns.
+//
+// A scoped skill name such as "@acme/code-review" is a single {name}
+// segment. Clients escape its slash ("@acme%2Fcode-review"), but paths
+// with a literal slash are accepted too; see scopedNames. Every API
+// request is subject to the rate limits in Options; see rateLimited.
 func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	api := http.NewServeMux()
+	h.registerAPIRoutes(api)
+	mux.Handle("/v1/", h.rateLimited(scopedNames(api)))
+}
+
+func (h *Handler) registerAPIRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /v1/skills", h.handleSearch)
 	mux.HandleFunc("GET /v1/skills/{name}", h.handleGetSkill)
+	mux.HandleFunc("GET /v1/skills/{name}/versions", h.handleListVersions)
 	mux.HandleFunc("GET /v1/skills/{name}/versions/{version}/download", h.handleDownload)
 	mux.HandleFunc("POST /v1/skills/publish", h.handlePublish)
+	mux.HandleFunc("POST /v1/skills/{name}/versions/{version}/yank", h.handleYank)
+	mux.HandleFunc("DELETE /v1/skills/{name}/versions/{version}/yank", h.handleUnyank)
+	mux.HandleFunc("POST /v1/skills/{name}/deprecate", h.handleDeprecate)
+	mux.HandleFunc("PUT /v1/skills/{name}/visibility", h.handleSetVisibility)
+	mux.HandleFunc("GET /v1/skills/{name}/owners", h.handleListOwners)
+	mux.HandleFunc("PUT /v1/skills/{name}/collaborators/{username}", h.handleAddCollaborator)
+	mux.HandleFunc("DELETE /v1/skills/{name}/collaborators/{username}", h.handleRemoveCollaborator)
+	mux.HandleFunc("POST /v1/skills/{name}/transfer", h.handleTransfer)
+	mux.HandleFunc("GET /v1/skills/{name}/dist-tags", h.handleListDistTags)
+	mux.HandleFunc("PUT /v1/skills/{name}/dist-tags/{tag}", h.handleSetDistTag)
+	mux.HandleFunc("DELETE /v1/skills/{name}/dist-tags/{tag}", h.handleRemoveDistTag)
+	mux.HandleFunc("GET /v1/whoami", h.handleWhoAmI)
+	mux.HandleFunc("GET /v1/users/{name}/usage", h.handleUsage)
+	mux.HandleFunc("POST /v1/tokens", h.handleCreateToken)
+	mux.HandleFunc("GET /v1/tokens", h.handleListTokens)
+	mux.HandleFunc("DELETE /v1/tokens/{id}", h.handleRevokeToken)
+	mux.HandleFunc("POST /v1/orgs", h.handleCreateOrg)
+	mux.HandleFunc("GET /v1/orgs/{org}", h.handleGetOrg)
+	mux.HandleFunc("PUT /v1/orgs/{org}/members/{username}", h.handleSetOrgMember)
+	mux.HandleFunc("DELETE /v1/orgs/{org}/members/{username}", h.handleRemoveOrgMember)
+	mux.HandleFunc("GET /v1/audit", h.handleAudit)
+}
+
+// scopedNames rewrites /v1/skills/@scope/name/... so that "@scope/name"
+// is matched as one path segment, as if the client had sent
+// /v1/skills/@scope%2Fname/.... A segment starting with "@" is always
+// followed by the rest of the name, so the rewrite is unambiguous.
+func scopedNames(next http.Handler) http.Handler {
+	const prefix = "/v1/skills/@"
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		escaped := r.URL.EscapedPath()
+		rest, ok := strings.CutPrefix(escaped, prefix)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		scope, name, ok := strings.Cut(rest, "/")
+		if !ok || name == "" || strings.Contains(strings.ToUpper(scope), "%2F") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		u := *r.URL
+		u.RawPath = prefix + scope + "%2F" + name
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = &u
+		next.ServeHTTP(w, r2)
+	})
 }
 
 // --- Search ---
@@ -51,26 +150,47 @@ type searchEntry struct {
 	Downloads     int64    `json:"downloads"`
 	LatestVersion string   `json:"latest_version"`
 	Tags          []string `json:"tags"`
+	Deprecated    string   `json:"deprecated,omitempty"`
+	Visibility    string   `json:"visibility"`
 }
 
 func (h *Handler) handleSearch(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.authenticateRead(w, r)
+	if !ok {
+		return
+	}
 	keyword := r.URL.Query().Get("q")
-	skills := h.store.Search(keyword)
+	skills, err := h.meta.Search(keyword)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+		log.Printf("searching skills: %v", err)
+		return
+	}
 
 	entries := make([]searchEntry, 0, len(skills))
 	for _, s := range skills {
-		latestVer := ""
+		if !caller.canRead(&s) {
+			continue
+		}
+		// Fully yanked skills are not offered to new users.
+		if !hasUnyanked(s.Versions) {
+			continue
+		}
+		e := searchEntry{
+			Name:        s.Name,
+			Description: s.Description,
+			Owner:       s.Owner,
+			Downloads:   s.Downloads,
+			Tags:        s.Tags,
+			Deprecated:  s.Deprecated,
+			Visibility:  s.VisibilityLevel(),
+		}
+		// Skills with only prereleases stay findable but have no latest
+		// version, matching GetSkill.
 		if lv := s.LatestVersion(); lv != nil {
-			latestVer = lv.Version
-		}
-		entries = append(entries, searchEntry{
-			Name:          s.Name,
-			Description:   s.Description,
-			Owner:         s.Owner,
-			Downloads:     s.Downloads,
-			LatestVersion: latestVer,
-			Tags:          s.Tags,
-		})
+			e.LatestVersion = lv.Version
+		}
+		entries = append(entries, e)
 	}
 
 	writeJSON(w, http.StatusOK, searchResult{
@@ -81,78 +201,217 @@ func (h *Handler) handleSearch(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func hasUnyanked(versions []VersionMeta) bool {
+	for _, v := range versions {
+		if !v.Yanked {
+			return true
+		}
+	}
+	return false
+}
+
 // --- GetSkill ---
 
 type skillInfoResponse struct {
-	Name          string              `json:"name"`
-	Owner         string              `json:"owner"`
-	Downloads     int64               `json:"downloads"`
-	LatestVersion *versionInfoResponse `json:"latest_version"`
+	Name          string                 `json:"name"`
+	Description   string                 `json:"description"`
+	Owner         string                 `json:"owner"`
+	Tags          []string               `json:"tags"`
+	License       string                 `json:"license,omitempty"`
+	Downloads     int64                  `json:"downloads"`
+	Deprecated    string                 `json:"deprecated,omitempty"`
+	Visibility    string                 `json:"visibility"`
+	LatestVersion *versionInfoResponse   `json:"latest_version"`
+	DistTags      map[string]string      `json:"dist_tags"`
+	Frontmatter   map[string]interface{} `json:"frontmatter,omitempty"`
+	Versions      []*versionInfoResponse `json:"versions"`
 }
 
 type versionInfoResponse struct {
 	Version     string `json:"version"`
 	Description string `json:"description"`
+	License     string `json:"license,omitempty"`
 	Checksum    string `json:"checksum"`
 	SizeBytes   int64  `json:"size_bytes"`
 	PublishedAt string `json:"published_at"`
+	Yanked      bool   `json:"yanked,omitempty"`
+	YankReason  string `json:"yank_reason,omitempty"`
+}
+
+func newVersionInfoResponse(vm *VersionMeta) *versionInfoResponse {
+	return &versionInfoResponse{
+		Version:     vm.Version,
+		Description: vm.Description,
+		License:     vm.License(),
+		Checksum:    vm.Checksum,
+		SizeBytes:   vm.SizeBytes,
+		PublishedAt: vm.PublishedAt,
+		Yanked:      vm.Yanked,
+		YankReason:  vm.YankReason,
+	}
 }
 
 func (h *Handler) handleGetSkill(w http.ResponseWriter, r *http.Request) {
-	name := r.PathValue("name")
-	meta := h.store.GetSkill(name)
-	if meta == nil {
-		http.Error(w, fmt.Sprintf("skill %q not found", name), http.StatusNotFound)
+	meta, ok := h.readableSkill(w, r)
+	if !ok {
 		return
 	}
 
 	resp := skillInfoResponse{
-		Name:      meta.Name,
-		Owner:     meta.Owner,
-		Downloads: meta.Downloads,
+		Name:        meta.Name,
+		Description: meta.Description,
+		Owner:       meta.Owner,
+		Tags:        meta.Tags,
+		Downloads:   meta.Downloads,
+		Deprecated:  meta.Deprecated,
+		Visibility:  meta.VisibilityLevel(),
+		DistTags:    meta.AllDistTags(),
+		Versions:    make([]*versionInfoResponse, 0, len(meta.Versions)),
+	}
+	if resp.Tags == nil {
+		resp.Tags = []string{}
+	}
+	for i := range meta.Versions {
+		resp.Versions = append(resp.Versions, newVersionInfoResponse(&meta.Versions[i]))
+	}
+
+	// License and frontmatter describe the latest version, or the newest
+	// one if there is no stable version yet.
+	current := meta.LatestVersion()
+	if current != nil {
+		resp.LatestVersion = newVersionInfoResponse(current)
+	} else if len(meta.Versions) > 0 {
+		current = &meta.Versions[len(meta.Versions)-1]
+	}
+	if current != nil {
+		resp.License = current.License()
+		resp.Frontmatter = current.Metadata
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// --- ListVersions ---
+
+const (
+	defaultVersionsPerPage = 50
+	maxVersionsPerPage     = 100
+)
+
+type versionListResponse struct {
+	Name     string                 `json:"name"`
+	Total    int                    `json:"total"`
+	Page     int                    `json:"page"`
+	PerPage  int                    `json:"per_page"`
+	Versions []*versionInfoResponse `json:"versions"`
+}
+
+// handleListVersions returns published versions in semver order, including
+// yanked ones, so clients can resolve version ranges. It accepts page,
+// per_page and order=asc|desc (default asc).
+func (h *Handler) handleListVersions(w http.ResponseWriter, r *http.Request) {
+	page, perPage, ok := parsePagination(w, r, defaultVersionsPerPage, maxVersionsPerPage)
+	if !ok {
+		return
+	}
+	order := r.URL.Query().Get("order")
+	if order != "" && order != "asc" && order != "desc" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, `order must be "asc" or "desc"`)
+		return
+	}
+
+	meta, ok := h.readableSkill(w, r)
+	if !ok {
+		return
 	}
-	if lv := meta.LatestVersion(); lv != nil {
-		resp.LatestVersion = &versionInfoResponse{
-			Version:     lv.Version,
-			Description: lv.Description,
-			Checksum:    lv.Checksum,
-			SizeBytes:   lv.SizeBytes,
-			PublishedAt: lv.PublishedAt,
+
+	versions := meta.Versions
+	if order == "desc" {
+		versions = make([]VersionMeta, len(meta.Versions))
+		for i, vm := range meta.Versions {
+			versions[len(versions)-1-i] = vm
 		}
 	}
+	start := min((page-1)*perPage, len(versions))
+	end := min(start+perPage, len(versions))
 
+	resp := versionListResponse{
+		Name:     meta.Name,
+		Total:    len(versions),
+		Page:     page,
+		PerPage:  perPage,
+		Versions: make([]*versionInfoResponse, 0, end-start),
+	}
+	for i := start; i < end; i++ {
+		resp.Versions = append(resp.Versions, newVersionInfoResponse(&versions[i]))
+	}
 	writeJSON(w, http.StatusOK, resp)
 }
 
 // --- Download ---
 
 func (h *Handler) handleDownload(w http.ResponseWriter, r *http.Request) {
-	name := r.PathValue("name")
-	version := r.PathValue("version")
-
-	meta := h.store.GetSkill(name)
-	if meta == nil {
-		http.Error(w, fmt.Sprintf("skill %q not found", name), http.StatusNotFound)
+	meta, ok := h.readableSkill(w, r)
+	if !ok {
 		return
 	}
+	name := meta.Name
+	version := r.PathValue("version")
 
 	vm := meta.FindVersion(version)
 	if vm == nil {
-		http.Error(w, fmt.Sprintf("version %q not found", version), http.StatusNotFound)
+		if meta.FindPruned(version) != nil {
+			writeVersionGone(w, version)
+			return
+		}
+		writeError(w, http.StatusNotFound, codeVersionNotFound, fmt.Sprintf("version %q not found", version))
 		return
 	}
 
-	bundlePath := h.store.GetBundlePath(name, version)
-	if bundlePath == "" {
-		http.Error(w, "bundle file not found", http.StatusNotFound)
+	rc, err := h.openBundle(name, vm)
+	if errors.Is(err, ErrNotFound) {
+		writeError(w, http.StatusNotFound, codeBundleNotFound, "bundle file not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+		log.Printf("opening bundle %s@%s: %v", name, version, err)
 		return
 	}
+	defer rc.Close()
 
 	w.Header().Set("X-Checksum-SHA256", strings.TrimPrefix(vm.Checksum, "sha256:"))
 	w.Header().Set("Content-Type", "application/gzip")
-	http.ServeFile(w, r, bundlePath)
+	if vm.Yanked {
+		w.Header().Set("X-Skill-Yanked", "true")
+		w.Header().Set("X-Skill-Yank-Reason", headerSafe(vm.YankReason))
+	}
+	if meta.Deprecated != "" {
+		w.Header().Set("X-Skill-Deprecated", headerSafe(meta.Deprecated))
+	}
+	if rs, ok := rc.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "bundle.tar.gz", time.Time{}, rs)
+	} else {
+		w.Header().Set("Content-Length", strconv.FormatInt(vm.SizeBytes, 10))
+		if _, err := io.Copy(w, rc); err != nil {
+			log.Printf("streaming bundle %s@%s: %v", name, version, err)
+			return
+		}
+	}
+
+	if err := h.meta.IncrementDownloads(name, version, time.Now()); err != nil {
+		log.Printf("incrementing downloads for %s: %v", name, err)
+	}
+}
 
-	h.store.IncrementDownloads(name)
+// openBundle opens the blob of a version, falling back to the legacy
+// layout for bundles that CollectGarbage has not moved yet.
+func (h *Handler) openBundle(name string, vm *VersionMeta) (io.ReadCloser, error) {
+	rc, err := h.blobs.OpenBlob(vm.Checksum)
+	if legacy, ok := h.blobs.(LegacyBundleStore); ok && errors.Is(err, ErrNotFound) {
+		return legacy.OpenBundle(name, vm.Version)
+	}
+	return rc, err
 }
 
 // --- Publish ---
@@ -165,87 +424,189 @@ type publishResult struct {
 }
 
 func (h *Handler) handlePublish(w http.ResponseWriter, r *http.Request) {
-	// Auth check.
-	if h.token != "" {
-		auth := r.Header.Get("Authorization")
-		if !strings.HasPrefix(auth, "Bearer ") || strings.TrimPrefix(auth, "Bearer ") != h.token {
-			http.Error(w, "unauthorized", http.StatusUnauthorized)
-			return
-		}
-	}
-
-	// Parse multipart (max 50 MB).
-	if err := r.ParseMultipartForm(50 << 20); err != nil {
-		http.Error(w, "invalid multipart form: "+err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	file, _, err := r.FormFile("file")
-	if err != nil {
-		http.Error(w, "missing file field: "+err.Error(), http.StatusBadRequest)
+	caller, ok := h.authenticate(w, r)
+	if !ok {
 		return
 	}
-	defer file.Close()
 
-	// Save uploaded bundle to temp file.
-	tmpFile, err := os.CreateTemp("", "upload-*.tar.gz")
-	if err != nil {
-		http.Error(w, "server error", http.StatusInternalServerError)
-		log.Printf("creating temp file: %v", err)
+	up, ok := h.receiveUpload(w, r)
+	if !ok {
 		return
 	}
-	defer os.Remove(tmpFile.Name())
-	defer tmpFile.Close()
+	defer os.Remove(up.path)
 
-	if _, err := io.Copy(tmpFile, file); err != nil {
-		http.Error(w, "server error", http.StatusInternalServerError)
-		log.Printf("writing upload: %v", err)
+	distTag := up.fields["tag"]
+	if distTag != "" && distTag != LatestTag && !validDistTag(distTag) {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("invalid dist-tag %q", distTag))
 		return
 	}
+	checksum, size := up.checksum, up.size
 
 	// Unpack to temp dir to read SKILL.md.
 	tmpDir, err := os.MkdirTemp("", "upload-extract-*")
 	if err != nil {
-		http.Error(w, "server error", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, codeInternal, "server error")
 		log.Printf("creating temp dir: %v", err)
 		return
 	}
 	defer os.RemoveAll(tmpDir)
 
-	if err := bundle.Unpack(tmpFile.Name(), tmpDir); err != nil {
-		http.Error(w, "invalid bundle: "+err.Error(), http.StatusBadRequest)
+	if err := bundle.Unpack(up.path, tmpDir); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidBundle, "invalid bundle: "+err.Error())
 		return
 	}
 
 	// Parse and validate SKILL.md.
 	meta, err := parser.ParseSkillFile(tmpDir)
 	if err != nil {
-		http.Error(w, "invalid SKILL.md: "+err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidSkill, "invalid SKILL.md: "+err.Error())
+		return
+	}
+
+	if !checkScope(w, caller, PermPublish, meta.Name) {
+		return
+	}
+
+	// The SKILL.md author must be the authenticated publisher.
+	if !caller.admin && meta.Author != caller.user {
+		writeError(w, http.StatusForbidden, codeForbidden,
+			fmt.Sprintf("author %q in SKILL.md does not match authenticated user %q", meta.Author, caller.user))
 		return
 	}
 
-	// Calculate checksum.
-	bundleData, err := os.ReadFile(tmpFile.Name())
+	// Only members of an organization may publish into its scope.
+	if scope := skillScope(meta.Name); scope != "" {
+		org, err := h.meta.GetOrg(scope)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+			log.Printf("loading org %s: %v", scope, err)
+			return
+		}
+		if org == nil {
+			writeError(w, http.StatusNotFound, codeOrgNotFound, fmt.Sprintf("organization %q not found", scope))
+			return
+		}
+		if !caller.admin && org.Role(caller.user) == "" {
+			writeError(w, http.StatusForbidden, codeForbidden,
+				fmt.Sprintf("user %q is not a member of organization %q", caller.user, scope))
+			return
+		}
+	}
+
+	// Check ownership and reject re-publishing before touching the blob
+	// store, so an existing bundle is never overwritten.
+	existing, err := h.meta.GetSkill(meta.Name)
 	if err != nil {
-		http.Error(w, "server error", http.StatusInternalServerError)
-		log.Printf("reading bundle: %v", err)
+		writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+		log.Printf("loading skill %s: %v", meta.Name, err)
+		return
+	}
+	if existing != nil && !caller.canManage(existing) {
+		writePublishForbidden(w, existing)
+		return
+	}
+	if existing != nil && !h.opts.AllowRepublish && existing.FindVersion(meta.Version) != nil {
+		writeVersionExists(w, meta.Name, meta.Version)
+		return
+	}
+	if existing != nil && existing.FindPruned(meta.Version) != nil {
+		// Lock files may still pin the pruned bundle's checksum.
+		writeError(w, http.StatusConflict, codeVersionExists,
+			fmt.Sprintf("version %s of %q was pruned and cannot be published again", meta.Version, meta.Name))
+		return
+	}
+	if !h.checkQuotas(w, meta.Name, meta.Author, meta.Version, size, existing) {
 		return
 	}
-	checksum := fmt.Sprintf("sha256:%x", sha256.Sum256(bundleData))
 
-	// Persist.
-	if err := h.store.SaveBundle(
-		meta.Name, meta.Author, meta.Description, meta.Version,
-		checksum, int64(len(bundleData)), meta.Tags, bundleData,
-	); err != nil {
-		http.Error(w, "server error", http.StatusInternalServerError)
+	// Persist the bundle first so metadata never points at a missing blob.
+	// Identical bundles share one blob.
+	if err := h.storeUpload(up); err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, "server error")
 		log.Printf("saving bundle: %v", err)
 		return
 	}
 
-	skill := h.store.GetSkill(meta.Name)
-	vm := skill.FindVersion(meta.Version)
+	vm := VersionMeta{
+		Version:     meta.Version,
+		Description: meta.Description,
+		Checksum:    checksum,
+		SizeBytes:   size,
+		PublishedAt: time.Now().UTC().Format(time.RFC3339),
+		Metadata:    meta.Frontmatter,
+	}
+	// The skill may have been created or changed hands since it was read;
+	// check the caller again against the stored owner. The frontmatter sets
+	// the visibility of a new skill only; changing it takes admin scope.
+	var stored *SkillMeta
+	visibility := vm.Visibility()
+	changeVisibility := false
+	err = h.meta.PutVersion(meta.Name, meta.Author, meta.Description, meta.Tags, vm, h.opts.AllowRepublish,
+		func(m *SkillMeta) error {
+			if m == nil {
+				return nil
+			}
+			stored = m
+			if !caller.canManage(m) {
+				return errCannotPublish
+			}
+			changeVisibility = visibility != "" && visibility != m.VisibilityLevel()
+			if changeVisibility && !caller.can(PermAdmin, m.Name) {
+				return errVisibilityChange
+			}
+			return nil
+		})
+	if errors.Is(err, errCannotPublish) {
+		writePublishForbidden(w, stored)
+		return
+	}
+	if errors.Is(err, errVisibilityChange) {
+		writeError(w, http.StatusForbidden, codeForbidden,
+			fmt.Sprintf("changing the visibility of %q from %s to %s requires a token with the %s scope",
+				meta.Name, stored.VisibilityLevel(), visibility, PermAdmin))
+		return
+	}
+	if errors.Is(err, ErrVersionExists) {
+		writeVersionExists(w, meta.Name, meta.Version)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+		log.Printf("saving metadata: %v", err)
+		return
+	}
+
+	if changeVisibility {
+		err := h.meta.UpdateSkill(meta.Name, func(m *SkillMeta) error {
+			m.Visibility = visibility
+			return nil
+		})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+			log.Printf("setting visibility of %s: %v", meta.Name, err)
+			return
+		}
+		h.audit(r, caller, AuditEvent{Action: AuditVisibility, Target: meta.Name, Detail: visibility})
+	}
+
+	// "latest" follows the highest stable version and needs no update.
+	if distTag != "" && distTag != LatestTag {
+		err := h.meta.UpdateSkill(meta.Name, func(m *SkillMeta) error {
+			m.setDistTag(distTag, meta.Version)
+			return nil
+		})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+			log.Printf("tagging %s@%s as %s: %v", meta.Name, meta.Version, distTag, err)
+			return
+		}
+	}
 
+	e := AuditEvent{Action: AuditPublish, Target: meta.Name, Version: meta.Version, Checksum: checksum}
+	if distTag != "" {
+		e.Detail = "dist-tag " + distTag
+	}
+	h.audit(r, caller, e)
 	writeJSON(w, http.StatusCreated, publishResult{
 		Name:        meta.Name,
 		Version:     meta.Version,
@@ -254,7 +615,1493 @@ func (h *Handler) handlePublish(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// --- Helpers ---
+// multipartOverhead is the room left for multipart framing when the
+// request body is limited to the maximum bundle size.
+const multipartOverhead = 64 << 10
+
+// upload is a bundle received by handlePublish, staged in a temp file.
+type upload struct {
+	path     string
+	size     int64
+	checksum string
+	// fields are the form values sent along with the file.
+	fields map[string]string
+}
+
+// receiveUpload streams the multipart publish request into a temp file,
+// hashing the bundle as it is written, so that the bundle is never held in
+// memory. The file is created in the blob store's staging directory when
+// it has one, so that storeUpload can move it rather than copy it. On
+// failure, receiveUpload writes the error response and returns false;
+// otherwise the caller must remove the file.
+func (h *Handler) receiveUpload(w http.ResponseWriter, r *http.Request) (*upload, bool) {
+	maxSize := h.opts.Quotas.MaxBundleSize
+	if maxSize > 0 {
+		// Leave room for the multipart framing around the bundle.
+		r.Body = http.MaxBytesReader(w, r.Body, maxSize+multipartOverhead)
+	}
+	// The body may be cut off at any point below.
+	readError := func(what string, err error) {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeBundleTooLarge(w, maxSize)
+			return
+		}
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("invalid multipart form: %s: %v", what, err))
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "invalid multipart form: "+err.Error())
+		return nil, false
+	}
+
+	up := &upload{fields: make(map[string]string)}
+	done := false
+	defer func() {
+		if !done && up.path != "" {
+			os.Remove(up.path)
+		}
+	}()
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			readError("reading part", err)
+			return nil, false
+		}
+
+		name := part.FormName()
+		if name != "file" {
+			// Form values are short; the limit keeps them that way.
+			value, err := io.ReadAll(io.LimitReader(part, multipartOverhead))
+			if err != nil {
+				readError("reading "+name, err)
+				return nil, false
+			}
+			up.fields[name] = string(value)
+			continue
+		}
+		if up.path != "" {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "invalid multipart form: more than one file field")
+			return nil, false
+		}
+
+		dir := ""
+		if fileStore, ok := h.blobs.(BlobFileStore); ok {
+			if dir, err = fileStore.StagingDir(); err != nil {
+				writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+				log.Printf("staging upload: %v", err)
+				return nil, false
+			}
+		}
+		tmpFile, err := os.CreateTemp(dir, "upload-*.tar.gz")
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+			log.Printf("creating temp file: %v", err)
+			return nil, false
+		}
+		up.path = tmpFile.Name()
+
+		hash := sha256.New()
+		src := io.Reader(part)
+		if maxSize > 0 {
+			// Read one byte past the limit to tell whether it was exceeded.
+			src = io.LimitReader(part, maxSize+1)
+		}
+		up.size, err = io.Copy(io.MultiWriter(tmpFile, hash), src)
+		if closeErr := tmpFile.Close(); err == nil && closeErr != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+			log.Printf("writing upload: %v", closeErr)
+			return nil, false
+		}
+		if err != nil {
+			// Errors writing the temp file are ours; the rest are the
+			// client's.
+			var pathErr *os.PathError
+			if errors.As(err, &pathErr) {
+				writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+				log.Printf("writing upload: %v", err)
+				return nil, false
+			}
+			readError("reading file", err)
+			return nil, false
+		}
+		if maxSize > 0 && up.size > maxSize {
+			writeBundleTooLarge(w, maxSize)
+			return nil, false
+		}
+		up.checksum = fmt.Sprintf("sha256:%x", hash.Sum(nil))
+	}
+
+	if up.path == "" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "missing file field")
+		return nil, false
+	}
+	done = true
+	return up, true
+}
+
+// storeUpload puts the uploaded bundle into the blob store, moving the
+// file when the store supports it.
+func (h *Handler) storeUpload(up *upload) error {
+	if fileStore, ok := h.blobs.(BlobFileStore); ok {
+		return fileStore.MoveBlob(up.checksum, up.path)
+	}
+	f, err := os.Open(up.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return h.blobs.PutBlob(up.checksum, f)
+}
+
+// checkQuotas writes a 403 response if storing a bundle of size bytes as
+// version of the named skill would exceed the version or storage quota.
+// existing is the skill, or nil if this is its first version.
+func (h *Handler) checkQuotas(w http.ResponseWriter, name, author, version string, size int64, existing *SkillMeta) bool {
+	q := h.opts.Quotas
+	owner := quotaOwner(name, author)
+	var replaced *VersionMeta
+	if existing != nil {
+		owner = quotaOwner(name, existing.Owner)
+		replaced = existing.FindVersion(version)
+		if q.MaxVersionsPerSkill > 0 && replaced == nil && len(existing.Versions) >= q.MaxVersionsPerSkill {
+			writeError(w, http.StatusForbidden, codeQuotaExceeded,
+				fmt.Sprintf("skill %q has reached the limit of %d versions", name, q.MaxVersionsPerSkill))
+			return false
+		}
+	}
+	if q.MaxBytesPerOwner <= 0 {
+		return true
+	}
+
+	usage, err := h.meta.Usage(owner)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+		log.Printf("loading usage of %s: %v", owner, err)
+		return false
+	}
+	total := size
+	for _, u := range usage {
+		total += u.Bytes
+	}
+	if replaced != nil {
+		total -= replaced.SizeBytes
+	}
+	if total > q.MaxBytesPerOwner {
+		writeError(w, http.StatusForbidden, codeQuotaExceeded,
+			fmt.Sprintf("publishing would use %s of the %s storage quota of %s",
+				formatByteSize(total), formatByteSize(q.MaxBytesPerOwner), owner))
+		return false
+	}
+	return true
+}
+
+func writeBundleTooLarge(w http.ResponseWriter, maxSize int64) {
+	writeError(w, http.StatusRequestEntityTooLarge, codeBundleTooLarge,
+		fmt.Sprintf("bundle exceeds the maximum size of %s", formatByteSize(maxSize)))
+}
+
+// --- Yank ---
+
+type yankRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (h *Handler) handleYank(w http.ResponseWriter, r *http.Request) {
+	var req yankRequest
+	caller, ok := h.authenticate(w, r)
+	if !ok || !decodeOptionalJSON(w, r, &req) {
+		return
+	}
+	h.setYanked(w, r, caller, true, req.Reason)
+}
+
+func (h *Handler) handleUnyank(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	h.setYanked(w, r, caller, false, "")
+}
+
+func (h *Handler) setYanked(w http.ResponseWriter, r *http.Request, caller *principal, yanked bool, reason string) {
+	name, version := r.PathValue("name"), r.PathValue("version")
+	var updated VersionMeta
+	ok := h.updateSkill(w, caller, PermYank, name, version, func(meta *SkillMeta) error {
+		vm := meta.FindVersion(version)
+		if vm == nil {
+			return missingVersion(meta, version)
+		}
+		vm.Yanked = yanked
+		vm.YankReason = reason
+		updated = *vm
+		return nil
+	})
+	if ok {
+		action := AuditYank
+		if !yanked {
+			action = AuditUnyank
+		}
+		h.audit(r, caller, AuditEvent{Action: action, Target: name, Version: version, Checksum: updated.Checksum, Detail: reason})
+		writeJSON(w, http.StatusOK, newVersionInfoResponse(&updated))
+	}
+}
+
+// --- Deprecate ---
+
+type deprecateRequest struct {
+	// Message is shown to consumers; an empty message un-deprecates the skill.
+	Message string `json:"message"`
+}
+
+type deprecateResult struct {
+	Name       string `json:"name"`
+	Deprecated string `json:"deprecated"`
+}
+
+func (h *Handler) handleDeprecate(w http.ResponseWriter, r *http.Request) {
+	var req deprecateRequest
+	caller, ok := h.authenticate(w, r)
+	if !ok || !decodeOptionalJSON(w, r, &req) {
+		return
+	}
+
+	name := r.PathValue("name")
+	ok = h.updateSkill(w, caller, PermAdmin, name, "", func(meta *SkillMeta) error {
+		meta.Deprecated = req.Message
+		return nil
+	})
+	if ok {
+		h.audit(r, caller, AuditEvent{Action: AuditDeprecate, Target: name, Detail: req.Message})
+		writeJSON(w, http.StatusOK, deprecateResult{Name: name, Deprecated: req.Message})
+	}
+}
+
+// --- Visibility ---
+
+type visibilityRequest struct {
+	Visibility string `json:"visibility"`
+}
+
+type visibilityResult struct {
+	Name       string `json:"name"`
+	Visibility string `json:"visibility"`
+}
+
+func (h *Handler) handleSetVisibility(w http.ResponseWriter, r *http.Request) {
+	var req visibilityRequest
+	caller, ok := h.authenticate(w, r)
+	if !ok || !decodeOptionalJSON(w, r, &req) {
+		return
+	}
+	if !validVisibility(req.Visibility) {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "visibility must be public, internal or private")
+		return
+	}
+
+	name := r.PathValue("name")
+	ok = h.updateSkill(w, caller, PermAdmin, name, "", func(meta *SkillMeta) error {
+		meta.Visibility = req.Visibility
+		return nil
+	})
+	if ok {
+		h.audit(r, caller, AuditEvent{Action: AuditVisibility, Target: name, Detail: req.Visibility})
+		writeJSON(w, http.StatusOK, visibilityResult{Name: name, Visibility: req.Visibility})
+	}
+}
+
+// --- Owners ---
+
+type ownersResponse struct {
+	Name          string       `json:"name"`
+	Owner         string       `json:"owner"`
+	Collaborators []string     `json:"collaborators"`
+	History       []OwnerEvent `json:"history"`
+}
+
+type transferRequest struct {
+	// Owner is a username, or "@<org>" for an organization.
+	Owner string `json:"owner"`
+}
+
+func newOwnersResponse(meta *SkillMeta) ownersResponse {
+	history := meta.OwnerHistory
+	if history == nil {
+		history = []OwnerEvent{}
+	}
+	return ownersResponse{
+		Name:          meta.Name,
+		Owner:         meta.Owner,
+		Collaborators: nonNilStrings(meta.Collaborators),
+		History:       history,
+	}
+}
+
+func (h *Handler) handleListOwners(w http.ResponseWriter, r *http.Request) {
+	meta, ok := h.readableSkill(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, newOwnersResponse(meta))
+}
+
+func (h *Handler) handleAddCollaborator(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	username := r.PathValue("username")
+	if !h.checkUserExists(w, username) {
+		return
+	}
+	name := r.PathValue("name")
+	added := false
+	ok = h.updateOwners(w, caller, name, func(meta *SkillMeta) error {
+		if !caller.isOwner(meta) {
+			return errNotOwner
+		}
+		if username != meta.Owner && meta.addCollaborator(username) {
+			meta.recordOwnerEvent(OwnerActionAddCollaborator, username, "", caller.actor())
+			added = true
+		}
+		return nil
+	})
+	if ok && added {
+		h.audit(r, caller, AuditEvent{Action: AuditCollaboratorAdd, Target: name, Detail: username})
+	}
+}
+
+// handleRemoveCollaborator removes a collaborator. Owners may remove
+// anyone; collaborators may remove themselves.
+func (h *Handler) handleRemoveCollaborator(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	username, name := r.PathValue("username"), r.PathValue("name")
+	ok = h.updateOwners(w, caller, name, func(meta *SkillMeta) error {
+		if !caller.isOwner(meta) && username != caller.user {
+			return errNotOwner
+		}
+		if !meta.removeCollaborator(username) {
+			return errNotCollaborator
+		}
+		meta.recordOwnerEvent(OwnerActionRemoveCollaborator, username, "", caller.actor())
+		return nil
+	})
+	if ok {
+		h.audit(r, caller, AuditEvent{Action: AuditCollaboratorRemove, Target: name, Detail: username})
+	}
+}
+
+// handleTransfer gives a skill to another user or to an organization. The
+// previous owner keeps no rights unless they are added back.
+func (h *Handler) handleTransfer(w http.ResponseWriter, r *http.Request) {
+	var req transferRequest
+	caller, ok := h.authenticate(w, r)
+	if !ok || !decodeOptionalJSON(w, r, &req) {
+		return
+	}
+	if req.Owner == "" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "owner is required")
+		return
+	}
+	if org, ok := strings.CutPrefix(req.Owner, "@"); ok {
+		o, err := h.meta.GetOrg(org)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+			log.Printf("loading org %s: %v", org, err)
+			return
+		}
+		if o == nil {
+			writeError(w, http.StatusNotFound, codeOrgNotFound, fmt.Sprintf("organization %q not found", org))
+			return
+		}
+	} else if !h.checkUserExists(w, req.Owner) {
+		return
+	}
+	name := r.PathValue("name")
+	var from string
+	ok = h.updateOwners(w, caller, name, func(meta *SkillMeta) error {
+		if !caller.isOwner(meta) {
+			return errNotOwner
+		}
+		if meta.Owner == req.Owner {
+			return nil
+		}
+		from = meta.Owner
+		meta.Owner = req.Owner
+		meta.removeCollaborator(req.Owner)
+		meta.recordOwnerEvent(OwnerActionTransfer, req.Owner, from, caller.actor())
+		return nil
+	})
+	if ok && from != "" {
+		h.audit(r, caller, AuditEvent{Action: AuditTransfer, Target: name, Detail: fmt.Sprintf("from %s to %s", from, req.Owner)})
+	}
+}
+
+// updateOwners applies fn, which checks ownership itself, with a token
+// granting admin on the skill and responds with the resulting owners. It
+// reports whether the update was saved.
+func (h *Handler) updateOwners(w http.ResponseWriter, caller *principal, name string, fn func(*SkillMeta) error) bool {
+	var resp ownersResponse
+	ok := h.updateSkill(w, caller, PermAdmin, name, "", func(meta *SkillMeta) error {
+		if err := fn(meta); err != nil {
+			return err
+		}
+		resp = newOwnersResponse(meta)
+		return nil
+	})
+	if ok {
+		writeJSON(w, http.StatusOK, resp)
+	}
+	return ok
+}
+
+// --- Dist-tags ---
+
+type distTagRequest struct {
+	Version string `json:"version"`
+}
+
+func (h *Handler) handleListDistTags(w http.ResponseWriter, r *http.Request) {
+	meta, ok := h.readableSkill(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, meta.AllDistTags())
+}
+
+func (h *Handler) handleSetDistTag(w http.ResponseWriter, r *http.Request) {
+	var req distTagRequest
+	caller, ok := h.authenticate(w, r)
+	if !ok || !decodeOptionalJSON(w, r, &req) {
+		return
+	}
+	tag := r.PathValue("tag")
+	if !h.checkDistTag(w, tag) {
+		return
+	}
+	if req.Version == "" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "version is required")
+		return
+	}
+	name := r.PathValue("name")
+	var checksum string
+	ok = h.updateDistTags(w, caller, name, req.Version, func(meta *SkillMeta) error {
+		vm := meta.FindVersion(req.Version)
+		if vm == nil {
+			return missingVersion(meta, req.Version)
+		}
+		meta.setDistTag(tag, req.Version)
+		checksum = vm.Checksum
+		return nil
+	})
+	if ok {
+		h.audit(r, caller, AuditEvent{Action: AuditDistTagSet, Target: name, Version: req.Version, Checksum: checksum, Detail: tag})
+	}
+}
+
+func (h *Handler) handleRemoveDistTag(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	tag := r.PathValue("tag")
+	if !h.checkDistTag(w, tag) {
+		return
+	}
+	name := r.PathValue("name")
+	var version string
+	ok = h.updateDistTags(w, caller, name, "", func(meta *SkillMeta) error {
+		v, ok := meta.DistTags[tag]
+		if !ok {
+			return errDistTagNotFound
+		}
+		delete(meta.DistTags, tag)
+		version = v
+		return nil
+	})
+	if ok {
+		h.audit(r, caller, AuditEvent{Action: AuditDistTagRemove, Target: name, Version: version, Detail: tag})
+	}
+}
+
+// checkDistTag rejects tag names that cannot be set or removed by users.
+func (h *Handler) checkDistTag(w http.ResponseWriter, tag string) bool {
+	if tag == LatestTag {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest,
+			`"latest" always points at the highest stable version and cannot be changed`)
+		return false
+	}
+	if !validDistTag(tag) {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest,
+			fmt.Sprintf("invalid dist-tag %q: must match [a-z][a-z0-9-]{0,31} and not be a version range", tag))
+		return false
+	}
+	return true
+}
+
+// updateDistTags applies fn and responds with the resulting tag map. It
+// reports whether the update was saved.
+func (h *Handler) updateDistTags(w http.ResponseWriter, caller *principal, name, version string, fn func(*SkillMeta) error) bool {
+	var tags map[string]string
+	ok := h.updateSkill(w, caller, PermPublish, name, version, func(meta *SkillMeta) error {
+		if err := fn(meta); err != nil {
+			return err
+		}
+		tags = meta.AllDistTags()
+		return nil
+	})
+	if ok {
+		writeJSON(w, http.StatusOK, tags)
+	}
+	return ok
+}
+
+// --- WhoAmI ---
+
+type whoAmIResponse struct {
+	Username string `json:"username,omitempty"`
+	Admin    bool   `json:"admin"`
+	// Auth is how the request was authenticated: "token" for a user API
+	// token, "admin_token" for the shared admin token and "none" in
+	// unauthenticated development mode.
+	Auth  string             `json:"auth"`
+	Token *tokenInfoResponse `json:"token,omitempty"`
+}
+
+func (h *Handler) handleWhoAmI(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	resp := whoAmIResponse{Username: caller.user, Admin: caller.admin, Auth: "admin_token"}
+	switch {
+	case caller.anonymous:
+		resp.Auth = "none"
+	case caller.token != nil:
+		resp.Auth = "token"
+		info := newTokenInfoResponse(caller.token)
+		resp.Token = &info
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// --- Usage ---
+
+type usageResponse struct {
+	// Owner is a username or "@<org>".
+	Owner    string         `json:"owner"`
+	Bytes    int64          `json:"bytes"`
+	Versions int            `json:"versions"`
+	Skills   []SkillUsage   `json:"skills"`
+	Quotas   quotasResponse `json:"quotas"`
+}
+
+// quotasResponse reports the configured quotas; zero means unlimited.
+type quotasResponse struct {
+	MaxBundleSize       int64 `json:"max_bundle_size"`
+	MaxVersionsPerSkill int   `json:"max_versions_per_skill"`
+	MaxBytes            int64 `json:"max_bytes"`
+}
+
+// handleUsage reports the storage charged to a user, to that user and
+// admins, or to an organization ("@<org>"), to its members and admins.
+func (h *Handler) handleUsage(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	owner := r.PathValue("name")
+	if org, ok := strings.CutPrefix(owner, "@"); ok {
+		o, err := h.meta.GetOrg(org)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+			log.Printf("loading org %s: %v", org, err)
+			return
+		}
+		if o == nil || !caller.admin && o.Role(caller.user) == "" {
+			writeError(w, http.StatusNotFound, codeOrgNotFound, fmt.Sprintf("organization %q not found", org))
+			return
+		}
+	} else {
+		if !caller.admin && owner != caller.user {
+			writeError(w, http.StatusForbidden, codeForbidden, "users may only view their own usage")
+			return
+		}
+		if !h.checkUserExists(w, owner) {
+			return
+		}
+	}
+
+	usage, err := h.meta.Usage(owner)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+		log.Printf("loading usage of %s: %v", owner, err)
+		return
+	}
+	q := h.opts.Quotas
+	resp := usageResponse{
+		Owner:  owner,
+		Skills: usage,
+		Quotas: quotasResponse{
+			MaxBundleSize:       q.MaxBundleSize,
+			MaxVersionsPerSkill: q.MaxVersionsPerSkill,
+			MaxBytes:            q.MaxBytesPerOwner,
+		},
+	}
+	if resp.Skills == nil {
+		resp.Skills = []SkillUsage{}
+	}
+	for _, u := range usage {
+		resp.Bytes += u.Bytes
+		resp.Versions += u.Versions
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// --- Tokens ---
+
+type createTokenRequest struct {
+	Name      string     `json:"name"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Scopes    []string   `json:"scopes,omitempty"`
+}
+
+type tokenInfoResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	Scopes     []string   `json:"scopes"`
+}
+
+type createTokenResponse struct {
+	tokenInfoResponse
+	// Token is the secret. It is only returned here and cannot be recovered.
+	Token string `json:"token"`
+}
+
+type tokenListResponse struct {
+	Tokens []tokenInfoResponse `json:"tokens"`
+}
+
+func newTokenInfoResponse(t *APIToken) tokenInfoResponse {
+	return tokenInfoResponse{
+		ID:         t.ID,
+		Name:       t.Name,
+		CreatedAt:  t.CreatedAt,
+		LastUsedAt: t.LastUsedAt,
+		ExpiresAt:  t.ExpiresAt,
+		Scopes:     nonNilStrings(t.Scopes),
+	}
+}
+
+func (h *Handler) handleCreateToken(w http.ResponseWriter, r *http.Request) {
+	var req createTokenRequest
+	caller, ok := h.authenticateUser(w, r, PermAdmin)
+	if !ok || !decodeOptionalJSON(w, r, &req) {
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 64 {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "name is required and must be at most 64 characters")
+		return
+	}
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(time.Now()) {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "expires_at must be in the future")
+			return
+		}
+		utc := req.ExpiresAt.UTC().Truncate(time.Second)
+		req.ExpiresAt = &utc
+	}
+
+	var scopes []string
+	for _, raw := range req.Scopes {
+		scope, err := ParseScope(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+			return
+		}
+		scopes = append(scopes, scope.String())
+	}
+
+	t, secret := NewAPIToken(caller.user, req.Name, req.ExpiresAt)
+	t.Scopes = scopes
+	if err := h.meta.CreateToken(t); err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+		log.Printf("creating token for %s: %v", caller.user, err)
+		return
+	}
+	h.audit(r, caller, AuditEvent{Action: AuditTokenCreate, Target: t.ID, Detail: t.Name})
+	writeJSON(w, http.StatusCreated, createTokenResponse{tokenInfoResponse: newTokenInfoResponse(&t), Token: secret})
+}
+
+func (h *Handler) handleListTokens(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.authenticateUser(w, r, "")
+	if !ok {
+		return
+	}
+	tokens, err := h.meta.ListTokens(caller.user)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+		log.Printf("listing tokens of %s: %v", caller.user, err)
+		return
+	}
+	resp := tokenListResponse{Tokens: make([]tokenInfoResponse, 0, len(tokens))}
+	for i := range tokens {
+		resp.Tokens = append(resp.Tokens, newTokenInfoResponse(&tokens[i]))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) handleRevokeToken(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.authenticateUser(w, r, PermAdmin)
+	if !ok {
+		return
+	}
+	id := r.PathValue("id")
+	err := h.meta.DeleteToken(caller.user, id)
+	if errors.Is(err, ErrNotFound) {
+		writeError(w, http.StatusNotFound, codeTokenNotFound, fmt.Sprintf("token %q not found", id))
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+		log.Printf("revoking token %s of %s: %v", id, caller.user, err)
+		return
+	}
+	h.audit(r, caller, AuditEvent{Action: AuditTokenRevoke, Target: id})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Orgs ---
+
+type createOrgRequest struct {
+	Name string `json:"name"`
+	// Owner is the first owner of the organization. It defaults to the
+	// caller and is required with the shared admin token.
+	Owner string `json:"owner,omitempty"`
+}
+
+type orgMemberRequest struct {
+	Role string `json:"role"`
+}
+
+type orgResponse struct {
+	Name      string      `json:"name"`
+	CreatedAt time.Time   `json:"created_at"`
+	Members   []OrgMember `json:"members"`
+}
+
+func newOrgResponse(o *Org) orgResponse {
+	members := o.Members
+	if members == nil {
+		members = []OrgMember{}
+	}
+	return orgResponse{Name: o.Name, CreatedAt: o.CreatedAt, Members: members}
+}
+
+// handleCreateOrg registers an organization, making the caller (or the
+// requested owner) its first owner. Any user may create one.
+func (h *Handler) handleCreateOrg(w http.ResponseWriter, r *http.Request) {
+	var req createOrgRequest
+	caller, ok := h.authenticate(w, r)
+	if !ok || !decodeOptionalJSON(w, r, &req) || !checkScope(w, caller, PermAdmin, "") {
+		return
+	}
+	if err := ValidateOrgName(req.Name); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	owner := caller.user
+	if req.Owner != "" && req.Owner != caller.user {
+		if !caller.admin {
+			writeError(w, http.StatusForbidden, codeForbidden, "only admins may create an organization for another user")
+			return
+		}
+		owner = req.Owner
+	}
+	if owner == "" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "owner is required")
+		return
+	}
+	if !h.checkUserExists(w, owner) {
+		return
+	}
+
+	o := Org{
+		Name:      req.Name,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		Members:   []OrgMember{{Username: owner, Role: OrgRoleOwner}},
+	}
+	err := h.meta.CreateOrg(o)
+	if errors.Is(err, ErrOrgExists) {
+		writeError(w, http.StatusConflict, codeOrgExists, fmt.Sprintf("organization %q already exists", req.Name))
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+		log.Printf("creating org %s: %v", req.Name, err)
+		return
+	}
+	h.audit(r, caller, AuditEvent{Action: AuditOrgCreate, Target: "@" + o.Name, Detail: owner})
+	writeJSON(w, http.StatusCreated, newOrgResponse(&o))
+}
+
+// handleGetOrg lists an organization's members. Only members and admins
+// may see it; everyone else gets 404.
+func (h *Handler) handleGetOrg(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	name := r.PathValue("org")
+	o, err := h.meta.GetOrg(name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+		log.Printf("loading org %s: %v", name, err)
+		return
+	}
+	if o == nil || !caller.admin && o.Role(caller.user) == "" {
+		writeError(w, http.StatusNotFound, codeOrgNotFound, fmt.Sprintf("organization %q not found", name))
+		return
+	}
+	writeJSON(w, http.StatusOK, newOrgResponse(o))
+}
+
+func (h *Handler) handleSetOrgMember(w http.ResponseWriter, r *http.Request) {
+	var req orgMemberRequest
+	caller, ok := h.authenticate(w, r)
+	if !ok || !decodeOptionalJSON(w, r, &req) {
+		return
+	}
+	if req.Role == "" {
+		req.Role = OrgRoleMember
+	}
+	if !validOrgRole(req.Role) {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "role must be owner or member")
+		return
+	}
+	username := r.PathValue("username")
+	if !h.checkUserExists(w, username) {
+		return
+	}
+	org := r.PathValue("org")
+	ok = h.updateOrg(w, caller, org, "", func(o *Org) error {
+		if o.Role(username) == OrgRoleOwner && req.Role != OrgRoleOwner && o.owners() == 1 {
+			return errLastOwner
+		}
+		o.setMember(username, req.Role)
+		return nil
+	})
+	if ok {
+		h.audit(r, caller, AuditEvent{Action: AuditOrgMemberSet, Target: "@" + org, Detail: fmt.Sprintf("%s as %s", username, req.Role)})
+	}
+}
+
+// handleRemoveOrgMember removes a member. Owners may remove anyone and
+// members may remove themselves, but the last owner cannot leave.
+func (h *Handler) handleRemoveOrgMember(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	username, org := r.PathValue("username"), r.PathValue("org")
+	ok = h.updateOrg(w, caller, org, username, func(o *Org) error {
+		if o.Role(username) == OrgRoleOwner && o.owners() == 1 {
+			return errLastOwner
+		}
+		if !o.removeMember(username) {
+			return errNotMember
+		}
+		return nil
+	})
+	if ok {
+		h.audit(r, caller, AuditEvent{Action: AuditOrgMemberRemove, Target: "@" + org, Detail: username})
+	}
+}
+
+// updateOrg applies fn to an organization the caller owns, with a token
+// granting admin, and responds with the result. If self is non-empty, a
+// member who is that user may act too, e.g. to leave the organization. It
+// reports whether the update was saved.
+func (h *Handler) updateOrg(w http.ResponseWriter, caller *principal, name, self string, fn func(*Org) error) bool {
+	if !checkScope(w, caller, PermAdmin, "") {
+		return false
+	}
+	var updated Org
+	err := h.meta.UpdateOrg(name, func(o *Org) error {
+		role := o.Role(caller.user)
+		if role == "" && !caller.admin {
+			return ErrNotFound
+		}
+		if role != OrgRoleOwner && !caller.admin && (self == "" || self != caller.user) {
+			return errForbidden
+		}
+		if err := fn(o); err != nil {
+			return err
+		}
+		updated = *o
+		return nil
+	})
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusOK, newOrgResponse(&updated))
+		return true
+	case errors.Is(err, ErrNotFound):
+		writeError(w, http.StatusNotFound, codeOrgNotFound, fmt.Sprintf("organization %q not found", name))
+	case errors.Is(err, errForbidden):
+		writeError(w, http.StatusForbidden, codeForbidden,
+			fmt.Sprintf("user %q is not an owner of organization %q", caller.user, name))
+	case errors.Is(err, errLastOwner):
+		writeError(w, http.StatusConflict, codeLastOwner,
+			fmt.Sprintf("organization %q must keep at least one owner", name))
+	case errors.Is(err, errNotMember):
+		writeError(w, http.StatusNotFound, codeUserNotFound, fmt.Sprintf("user is not a member of organization %q", name))
+	default:
+		writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+		log.Printf("updating org %s: %v", name, err)
+	}
+	return false
+}
+
+// --- Audit ---
+
+type auditResponse struct {
+	Events []AuditEvent `json:"events"`
+}
+
+// handleAudit lists audit events, oldest first, to registry admins. The
+// skill parameter matches the event target, so it also accepts "@<org>"
+// or a token id. With format=jsonl the events are written as JSON lines,
+// one per event, for export.
+func (h *Handler) handleAudit(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	if !caller.admin {
+		writeError(w, http.StatusForbidden, codeForbidden, "only registry admins may read the audit log")
+		return
+	}
+	q := r.URL.Query()
+	filter := AuditFilter{Target: q.Get("skill"), Actor: q.Get("actor")}
+	if since := q.Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "since must be an RFC 3339 timestamp")
+			return
+		}
+		filter.Since = t
+	}
+	if limit := q.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "limit must be a positive integer")
+			return
+		}
+		filter.Limit = n
+	}
+	format := q.Get("format")
+	if format != "" && format != "json" && format != "jsonl" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "format must be json or jsonl")
+		return
+	}
+
+	events, err := h.meta.ListAudit(filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+		log.Printf("listing audit events: %v", err)
+		return
+	}
+	if format != "jsonl" {
+		if events == nil {
+			events = []AuditEvent{}
+		}
+		writeJSON(w, http.StatusOK, auditResponse{Events: events})
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	for i := range events {
+		if err := enc.Encode(&events[i]); err != nil {
+			log.Printf("writing audit events: %v", err)
+			return
+		}
+	}
+}
+
+// checkUserExists writes a 404 response unless username is registered.
+func (h *Handler) checkUserExists(w http.ResponseWriter, username string) bool {
+	u, err := h.meta.GetUser(username)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+		log.Printf("loading user %s: %v", username, err)
+		return false
+	}
+	if u == nil {
+		writeError(w, http.StatusNotFound, codeUserNotFound, fmt.Sprintf("user %q not found", username))
+		return false
+	}
+	return true
+}
+
+// --- Helpers ---
+
+// errVersionNotFound is returned from UpdateSkill callbacks when the
+// requested version does not exist.
+var errVersionNotFound = errors.New("version not found")
+
+// errCannotPublish is returned from PutVersion checks when the caller may
+// not manage the stored skill.
+var errCannotPublish = errors.New("cannot publish to skill")
+
+// errVisibilityChange is returned from PutVersion checks when a version's
+// frontmatter would change the visibility without admin scope.
+var errVisibilityChange = errors.New("visibility change needs admin scope")
+
+// errVersionGone is returned from UpdateSkill callbacks when the requested
+// version was pruned.
+var errVersionGone = errors.New("version pruned")
+
+// missingVersion returns the error for a version that meta does not have.
+func missingVersion(meta *SkillMeta, version string) error {
+	if meta.FindPruned(version) != nil {
+		return errVersionGone
+	}
+	return errVersionNotFound
+}
+
+// errForbidden is returned from UpdateSkill callbacks when the caller may
+// not manage the skill.
+var errForbidden = errors.New("forbidden")
+
+// errDistTagNotFound is returned from UpdateSkill callbacks when removing a
+// dist-tag that is not set.
+var errDistTagNotFound = errors.New("dist-tag not found")
+
+// errNotOwner is returned from UpdateSkill callbacks when the caller may
+// manage the skill but not change its owners.
+var errNotOwner = errors.New("not the owner")
+
+// errNotCollaborator is returned from UpdateSkill callbacks when removing
+// a user who is not a collaborator.
+var errNotCollaborator = errors.New("not a collaborator")
+
+// errLastOwner is returned from UpdateOrg callbacks that would leave an
+// organization without an owner.
+var errLastOwner = errors.New("last owner")
+
+// errNotMember is returned from UpdateOrg callbacks when removing a user
+// who is not a member.
+var errNotMember = errors.New("not a member")
+
+// errTokenExpired is returned by userPrincipal for a known token that is
+// past its expiry.
+var errTokenExpired = errors.New("token expired")
+
+// principal is the authenticated caller of a request.
+type principal struct {
+	user      string            // empty for the shared admin token and anonymous callers
+	admin     bool              // may manage every skill
+	anonymous bool              // no credentials; admin only in development mode
+	token     *APIToken         // the per-user token, if one was used
+	scopes    []Scope           // nil if the token is not scoped
+	orgs      map[string]string // the user's role in each of their organizations
+}
+
+// canManage reports whether the caller may publish to and manage a skill:
+// its owner, a collaborator, a member of the organization that owns it or
+// that it is scoped to, or an admin.
+func (p *principal) canManage(meta *SkillMeta) bool {
+	return p.admin || meta.CanManage(p.user) || p.memberOf(meta.OwnerOrg()) || p.memberOf(skillScope(meta.Name))
+}
+
+// isOwner reports whether the caller may change who manages a skill: its
+// owner, an owner of the organization that owns it or that it is scoped
+// to, or an admin. Collaborators may not.
+func (p *principal) isOwner(meta *SkillMeta) bool {
+	if p.admin || p.user != "" && p.user == meta.Owner {
+		return true
+	}
+	return p.orgs[meta.OwnerOrg()] == OrgRoleOwner || p.orgs[skillScope(meta.Name)] == OrgRoleOwner
+}
+
+// memberOf reports whether the caller is a member of org.
+func (p *principal) memberOf(org string) bool {
+	return org != "" && p.orgs[org] != ""
+}
+
+// actor names the caller in recorded history.
+func (p *principal) actor() string {
+	switch {
+	case p.user != "":
+		return p.user
+	case p.anonymous:
+		return "anonymous"
+	}
+	return "admin-token"
+}
+
+// canRead reports whether the caller may see a skill. Skills the caller
+// may not see are reported as not found, so that their existence does not
+// leak.
+func (p *principal) canRead(meta *SkillMeta) bool {
+	switch meta.VisibilityLevel() {
+	case VisibilityInternal:
+		return (p.admin || p.user != "") && p.can(PermRead, meta.Name)
+	case VisibilityPrivate:
+		return p.canManage(meta) && p.can(PermRead, meta.Name)
+	}
+	return true
+}
+
+// can reports whether the caller's token grants perm on skill, or on
+// operations not tied to a skill if skill is empty.
+func (p *principal) can(perm, skill string) bool {
+	if p.scopes == nil {
+		return true
+	}
+	for _, s := range p.scopes {
+		if s.Allows(perm, skill) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkScope writes a 403 response unless the caller's token grants perm
+// on skill.
+func checkScope(w http.ResponseWriter, caller *principal, perm, skill string) bool {
+	if caller.can(perm, skill) {
+		return true
+	}
+	msg := fmt.Sprintf("token scope does not grant %s on skill %q", perm, skill)
+	if skill == "" {
+		msg = fmt.Sprintf("token scope does not grant %s on all skills", perm)
+	}
+	writeError(w, http.StatusForbidden, codeForbidden, msg)
+	return false
+}
+
+// authenticate resolves the bearer token to a principal and writes a 401
+// response if it is missing or unknown.
+func (h *Handler) authenticate(w http.ResponseWriter, r *http.Request) (*principal, bool) {
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if h.opts.Token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(h.opts.Token)) == 1 {
+		return &principal{admin: true}, true
+	}
+	if token != "" {
+		p, err := h.userPrincipal(token)
+		switch {
+		case errors.Is(err, errTokenExpired):
+			writeError(w, http.StatusUnauthorized, codeUnauthorized, "token expired")
+			return nil, false
+		case err != nil:
+			writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+			log.Printf("authenticating: %v", err)
+			return nil, false
+		case p != nil:
+			return p, true
+		}
+	}
+	open, err := h.openMode()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+		log.Printf("authenticating: %v", err)
+		return nil, false
+	}
+	if open {
+		return &principal{admin: true, anonymous: true}, true
+	}
+	writeError(w, http.StatusUnauthorized, codeUnauthorized, "unauthorized")
+	return nil, false
+}
+
+// openMode reports whether callers without a known token act as admin: the
+// server has no shared admin token, and either no user is registered yet
+// or the operator opted in with Options.InsecureOpen.
+func (h *Handler) openMode() (bool, error) {
+	if h.opts.Token != "" {
+		return false, nil
+	}
+	if h.opts.InsecureOpen {
+		return true, nil
+	}
+	hasUsers, err := h.meta.HasUsers()
+	if err != nil {
+		return false, fmt.Errorf("checking for users: %w", err)
+	}
+	return !hasUsers, nil
+}
+
+// authenticateRead is authenticate for read endpoints, which also serve
+// callers without a token: they only see public skills. A token that is
+// sent must be valid, so that a bad token is reported instead of silently
+// hiding private skills.
+func (h *Handler) authenticateRead(w http.ResponseWriter, r *http.Request) (*principal, bool) {
+	if r.Header.Get("Authorization") != "" {
+		return h.authenticate(w, r)
+	}
+	open, err := h.openMode()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+		log.Printf("authenticating: %v", err)
+		return nil, false
+	}
+	if open {
+		return &principal{admin: true, anonymous: true}, true
+	}
+	return &principal{anonymous: true}, true
+}
+
+// readableSkill loads the skill named in the request path for a read
+// endpoint. It writes the same 404 response whether the skill does not
+// exist or the caller may not see it.
+func (h *Handler) readableSkill(w http.ResponseWriter, r *http.Request) (*SkillMeta, bool) {
+	caller, ok := h.authenticateRead(w, r)
+	if !ok {
+		return nil, false
+	}
+	name := r.PathValue("name")
+	if parser.ValidateName(name) != nil {
+		writeError(w, http.StatusNotFound, codeSkillNotFound, fmt.Sprintf("skill %q not found", name))
+		return nil, false
+	}
+	meta, err := h.meta.GetSkill(name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+		log.Printf("loading skill %s: %v", name, err)
+		return nil, false
+	}
+	if meta == nil || !caller.canRead(meta) {
+		writeError(w, http.StatusNotFound, codeSkillNotFound, fmt.Sprintf("skill %q not found", name))
+		return nil, false
+	}
+	return meta, true
+}
+
+// tokenTouchInterval limits how often a token's last-used time is written,
+// so that busy clients do not turn every read into a write.
+const tokenTouchInterval = time.Minute
+
+// userPrincipal resolves a per-user API token. It returns nil if the token
+// is unknown or its user no longer exists.
+func (h *Handler) userPrincipal(secret string) (*principal, error) {
+	t, err := h.meta.TokenByHash(HashToken(secret))
+	if err != nil || t == nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if t.Expired(now) {
+		return nil, errTokenExpired
+	}
+	u, err := h.meta.GetUser(t.Username)
+	if err != nil || u == nil {
+		return nil, err
+	}
+	p := &principal{user: u.Username, token: t}
+	for _, raw := range t.Scopes {
+		scope, err := ParseScope(raw)
+		if err != nil {
+			return nil, fmt.Errorf("token %s: %w", t.ID, err)
+		}
+		p.scopes = append(p.scopes, scope)
+	}
+	// Registry admins keep their rights only with an unrestricted admin scope.
+	p.admin = u.Admin && p.can(PermAdmin, "")
+	if p.orgs, err = h.meta.UserOrgs(u.Username); err != nil {
+		return nil, err
+	}
+	if t.LastUsedAt == nil || now.Sub(*t.LastUsedAt) >= tokenTouchInterval {
+		used := now.Truncate(time.Second)
+		if err := h.meta.TouchToken(t.ID, used); err != nil {
+			log.Printf("recording use of token %s: %v", t.ID, err)
+		} else {
+			t.LastUsedAt = &used
+		}
+	}
+	return p, nil
+}
+
+// authenticateUser is authenticate for endpoints that act on the caller's
+// own account and therefore need a per-user token granting perm. An empty
+// perm accepts a token of any scope.
+func (h *Handler) authenticateUser(w http.ResponseWriter, r *http.Request, perm string) (*principal, bool) {
+	caller, ok := h.authenticate(w, r)
+	if !ok {
+		return nil, false
+	}
+	if caller.user == "" {
+		writeError(w, http.StatusForbidden, codeForbidden, "this endpoint requires a user API token")
+		return nil, false
+	}
+	if perm != "" && !checkScope(w, caller, perm, "") {
+		return nil, false
+	}
+	return caller, true
+}
+
+// updateSkill applies fn to a skill the caller may manage with a token
+// granting perm, writing an error response on failure. It reports whether
+// the update was saved.
+func (h *Handler) updateSkill(w http.ResponseWriter, caller *principal, perm, name, version string, fn func(*SkillMeta) error) bool {
+	if !checkScope(w, caller, perm, name) {
+		return false
+	}
+	if parser.ValidateName(name) != nil {
+		writeError(w, http.StatusNotFound, codeSkillNotFound, fmt.Sprintf("skill %q not found", name))
+		return false
+	}
+	var denied *SkillMeta
+	err := h.meta.UpdateSkill(name, func(meta *SkillMeta) error {
+		if !caller.canRead(meta) {
+			return ErrNotFound
+		}
+		if !caller.canManage(meta) {
+			denied = meta
+			return errForbidden
+		}
+		return fn(meta)
+	})
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, ErrNotFound):
+		writeError(w, http.StatusNotFound, codeSkillNotFound, fmt.Sprintf("skill %q not found", name))
+	case errors.Is(err, errForbidden):
+		writeForbidden(w, caller, denied)
+	case errors.Is(err, errVersionNotFound):
+		writeError(w, http.StatusNotFound, codeVersionNotFound, fmt.Sprintf("version %q not found", version))
+	case errors.Is(err, errVersionGone):
+		writeVersionGone(w, version)
+	case errors.Is(err, errDistTagNotFound):
+		writeError(w, http.StatusNotFound, codeDistTagNotFound, "dist-tag not found")
+	case errors.Is(err, errNotOwner):
+		writeError(w, http.StatusForbidden, codeForbidden,
+			fmt.Sprintf("only the owner of skill %q may change its owners and collaborators", name))
+	case errors.Is(err, errNotCollaborator):
+		writeError(w, http.StatusNotFound, codeUserNotFound, fmt.Sprintf("user is not a collaborator on skill %q", name))
+	default:
+		writeError(w, http.StatusInternalServerError, codeInternal, "server error")
+		log.Printf("updating %s: %v", name, err)
+	}
+	return false
+}
+
+// writeForbidden reports that caller may not manage meta.
+func writeForbidden(w http.ResponseWriter, caller *principal, meta *SkillMeta) {
+	writeError(w, http.StatusForbidden, codeForbidden,
+		fmt.Sprintf("skill %q is owned by %q; user %q is not the owner or a collaborator", meta.Name, meta.Owner, caller.user))
+}
+
+// writePublishForbidden writes the 403 response for a publish to a skill
+// the caller may not manage. Names are unique, so any answer but success
+// shows that the name is in use; the response is the same whether or not
+// the caller may read the skill, and names neither its owner nor its
+// visibility.
+func writePublishForbidden(w http.ResponseWriter, meta *SkillMeta) {
+	writeError(w, http.StatusForbidden, codeForbidden,
+		fmt.Sprintf("skill name %q is taken; only its owner and collaborators may publish to it", meta.Name))
+}
+
+// parsePagination reads the page and per_page query parameters, writing a
+// 400 response if they are malformed.
+func parsePagination(w http.ResponseWriter, r *http.Request, defaultPerPage, maxPerPage int) (page, perPage int, ok bool) {
+	page, perPage = 1, defaultPerPage
+	q := r.URL.Query()
+	if v := q.Get("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "page must be a positive integer")
+			return 0, 0, false
+		}
+		page = n
+	}
+	if v := q.Get("per_page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPerPage {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest,
+				fmt.Sprintf("per_page must be between 1 and %d", maxPerPage))
+			return 0, 0, false
+		}
+		perPage = n
+	}
+	return page, perPage, true
+}
+
+// decodeOptionalJSON decodes a JSON request body into v, treating an empty
+// body as all defaults. It writes a 400 response on malformed input.
+func decodeOptionalJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(v)
+	if err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// headerSafe strips characters that are not allowed in HTTP header values.
+func headerSafe(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\r' || r == '\n' || r < 0x20 && r != '\t' {
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+// Machine-readable error codes returned in the "code" field of error responses.
+const (
+	codeInternal        = "internal_error"
+	codeUnauthorized    = "unauthorized"
+	codeForbidden       = "forbidden"
+	codeInvalidRequest  = "invalid_request"
+	codeInvalidBundle   = "invalid_bundle"
+	codeInvalidSkill    = "invalid_skill"
+	codeSkillNotFound   = "skill_not_found"
+	codeVersionNotFound = "version_not_found"
+	codeVersionGone     = "version_gone"
+	codeBundleNotFound  = "bundle_not_found"
+	codeVersionExists   = "version_exists"
+	codeDistTagNotFound = "dist_tag_not_found"
+	codeTokenNotFound   = "token_not_found"
+	codeUserNotFound    = "user_not_found"
+	codeOrgNotFound     = "org_not_found"
+	codeOrgExists       = "org_exists"
+	codeLastOwner       = "last_owner"
+	codeRateLimited     = "rate_limited"
+	codeBundleTooLarge  = "bundle_too_large"
+	codeQuotaExceeded   = "quota_exceeded"
+)
+
+type errorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// writeError writes a JSON error body: {"error": "...", "code": "..."}.
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, errorResponse{Error: message, Code: code})
+}
+
+func writeVersionGone(w http.ResponseWriter, version string) {
+	writeError(w, http.StatusGone, codeVersionGone,
+		fmt.Sprintf("version %q was removed by the registry's retention policy", version))
+}
+
+func writeVersionExists(w http.ResponseWriter, name, version string) {
+	writeError(w, http.StatusConflict, codeVersionExists,
+		fmt.Sprintf("version %s of %q already exists; published versions are immutable", version, name))
+}
 
 func writeJSON(w http.ResponseWriter, status int, v interface{}) {
 	w.Header().Set("Content-Type", "application/json")
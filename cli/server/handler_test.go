@@ -4,14 +4,23 @@ package server
 
 import (
 	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/json"
+	"fmt"
 	"io"
+	"maps"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/liuyukai/agentskills-cli/internal/bundle"
 )
@@ -20,8 +29,7 @@ import (
 func setupTestServer(t *testing.T) (*httptest.Server, string) {
 	t.Helper()
 	dataDir := t.TempDir()
-	store := NewStore(dataDir)
-	handler := NewHandler(store, "test-token")
+	handler := NewHandler(NewFSMetaStore(dataDir), NewFSBlobStore(dataDir), Options{Token: "test-token"})
 	mux := http.NewServeMux()
 	handler.RegisterRoutes(mux)
 	return httptest.NewServer(mux), dataDir
@@ -38,7 +46,14 @@ func createTestSkillDir(t *testing.T, name, version string) string {
 	return dir
 }
 
-func publishBundle(t *testing.T, serverURL, token, skillDir string) {
+// postBundle packs skillDir and posts it to the publish endpoint.
+func postBundle(t *testing.T, serverURL, token, skillDir string) *http.Response {
+	t.Helper()
+	return postBundleWithTag(t, serverURL, token, skillDir, "")
+}
+
+// postBundleWithTag is postBundle with a dist-tag form field.
+func postBundleWithTag(t *testing.T, serverURL, token, skillDir, distTag string) *http.Response {
 	t.Helper()
 
 	// Pack the skill directory into a bundle.
@@ -56,6 +71,9 @@ func publishBundle(t *testing.T, serverURL, token, skillDir string) {
 	// Build multipart request.
 	var body bytes.Buffer
 	writer := multipart.NewWriter(&body)
+	if distTag != "" {
+		writer.WriteField("tag", distTag)
+	}
 	part, err := writer.CreateFormFile("file", "bundle.tar.gz")
 	if err != nil {
 		t.Fatal(err)
@@ -73,6 +91,13 @@ func publishBundle(t *testing.T, serverURL, token, skillDir string) {
 	if err != nil {
 		t.Fatalf("publish request: %v", err)
 	}
+	return resp
+}
+
+func publishBundle(t *testing.T, serverURL, token, skillDir string) {
+	t.Helper()
+
+	resp := postBundle(t, serverURL, token, skillDir)
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated {
@@ -139,6 +164,15 @@ func TestGetSkill(t *testing.T) {
 	if info.LatestVersion == nil || info.LatestVersion.Version != "2.0.0" {
 		t.Fatalf("expected latest version 2.0.0, got %+v", info.LatestVersion)
 	}
+	if info.Description != "A test skill" || len(info.Tags) != 1 || info.Tags[0] != "test" {
+		t.Errorf("description/tags = %q/%v", info.Description, info.Tags)
+	}
+	if info.Frontmatter["author"] != "tester" {
+		t.Errorf("frontmatter = %v, want author tester", info.Frontmatter)
+	}
+	if len(info.Versions) != 1 || info.Versions[0].SizeBytes == 0 {
+		t.Errorf("versions = %+v, want one version with a size", info.Versions)
+	}
 }
 
 func TestDownload(t *testing.T) {
@@ -218,3 +252,1698 @@ func TestGetSkillNotFound(t *testing.T) {
 		t.Fatalf("expected 404, got %d", resp.StatusCode)
 	}
 }
+
+func TestPublishStreaming(t *testing.T) {
+	ts, dataDir := setupTestServer(t)
+	defer ts.Close()
+
+	post := func(build func(w *multipart.Writer, bundleData []byte)) *http.Response {
+		t.Helper()
+		bundlePath, err := bundle.Pack(createTestSkillDir(t, "streamed", "1.0.0"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(bundlePath)
+		bundleData, _ := os.ReadFile(bundlePath)
+
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		build(writer, bundleData)
+		writer.Close()
+		req, _ := http.NewRequest("POST", ts.URL+"/v1/skills/publish", &body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer test-token")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+	writeFile := func(w *multipart.Writer, data []byte) {
+		part, _ := w.CreateFormFile("file", "bundle.tar.gz")
+		part.Write(data)
+	}
+
+	for name, build := range map[string]func(*multipart.Writer, []byte){
+		"no file": func(w *multipart.Writer, _ []byte) { w.WriteField("tag", "beta") },
+		"two files": func(w *multipart.Writer, data []byte) {
+			writeFile(w, data)
+			writeFile(w, data)
+		},
+	} {
+		if resp := post(build); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, resp.StatusCode)
+		}
+	}
+
+	// Form values may follow the file.
+	resp := post(func(w *multipart.Writer, data []byte) {
+		writeFile(w, data)
+		w.WriteField("tag", "beta")
+	})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	info := getSkillInfo(t, ts.URL, "streamed")
+	if info.DistTags["beta"] != "1.0.0" {
+		t.Fatalf("dist_tags = %v, want beta=1.0.0", info.DistTags)
+	}
+
+	// The upload was moved into the blob store, and staged uploads of
+	// rejected publishes are removed.
+	if resp := post(writeFile); resp.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", resp.StatusCode)
+	}
+	rc, err := NewFSBlobStore(dataDir).OpenBlob(info.Versions[0].Checksum)
+	if err != nil {
+		t.Fatalf("opening blob: %v", err)
+	}
+	h := sha256.New()
+	io.Copy(h, rc)
+	rc.Close()
+	if got := fmt.Sprintf("sha256:%x", h.Sum(nil)); got != info.Versions[0].Checksum {
+		t.Fatalf("blob digest = %s, want %s", got, info.Versions[0].Checksum)
+	}
+	staged, _ := os.ReadDir(filepath.Join(dataDir, "blobs", "uploads"))
+	if len(staged) != 0 {
+		t.Fatalf("%d staged uploads left behind", len(staged))
+	}
+}
+
+func TestPublishDuplicateVersion(t *testing.T) {
+	ts, dataDir := setupTestServer(t)
+	defer ts.Close()
+
+	publishBundle(t, ts.URL, "test-token", createTestSkillDir(t, "immutable", "1.0.0"))
+	original := getSkillInfo(t, ts.URL, "immutable").Versions[0].Checksum
+
+	// Same version, different content.
+	changed := createTestSkillDir(t, "immutable", "1.0.0")
+	os.WriteFile(filepath.Join(changed, "extra.md"), []byte("changed"), 0o644)
+	resp := postBundle(t, ts.URL, "test-token", changed)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", resp.StatusCode)
+	}
+	var errResp errorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
+		t.Fatal(err)
+	}
+	if errResp.Code != codeVersionExists {
+		t.Fatalf("expected code %s, got %q", codeVersionExists, errResp.Code)
+	}
+
+	if current := getSkillInfo(t, ts.URL, "immutable").Versions[0].Checksum; current != original {
+		t.Fatal("rejected publish must not overwrite the existing bundle")
+	}
+	var stored []string
+	NewFSBlobStore(dataDir).ListBlobs(func(digest string, _ time.Time) error {
+		stored = append(stored, digest)
+		return nil
+	})
+	if !reflect.DeepEqual(stored, []string{original}) {
+		t.Fatalf("blobs = %v, want only %s", stored, original)
+	}
+}
+
+func TestPublishAllowRepublish(t *testing.T) {
+	dataDir := t.TempDir()
+	handler := NewHandler(NewFSMetaStore(dataDir), NewFSBlobStore(dataDir), Options{
+		Token:          "test-token",
+		AllowRepublish: true,
+	})
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	publishBundle(t, ts.URL, "test-token", createTestSkillDir(t, "mutable", "1.0.0"))
+	publishBundle(t, ts.URL, "test-token", createTestSkillDir(t, "mutable", "1.0.0"))
+}
+
+// memBlobStore is an in-memory BlobStore whose readers do not implement io.Seeker.
+type memBlobStore struct {
+	mu    sync.Mutex
+	blobs map[string][]byte
+}
+
+func (m *memBlobStore) PutBlob(digest string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.blobs == nil {
+		m.blobs = make(map[string][]byte)
+	}
+	m.blobs[digest] = data
+	return nil
+}
+
+func (m *memBlobStore) OpenBlob(digest string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.blobs[digest]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *memBlobStore) DeleteBlob(digest string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.blobs, digest)
+	return nil
+}
+
+func (m *memBlobStore) ListBlobs(fn func(string, time.Time) error) error {
+	m.mu.Lock()
+	digests := slices.Collect(maps.Keys(m.blobs))
+	m.mu.Unlock()
+	for _, digest := range digests {
+		if err := fn(digest, time.Time{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestCustomBlobStore(t *testing.T) {
+	dataDir := t.TempDir()
+	blobs := &memBlobStore{}
+	handler := NewHandler(NewFSMetaStore(dataDir), blobs, Options{Token: "test-token"})
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	skillDir := createTestSkillDir(t, "mem-skill", "1.0.0")
+	publishBundle(t, ts.URL, "test-token", skillDir)
+
+	if _, err := os.Stat(filepath.Join(dataDir, "blobs")); !os.IsNotExist(err) {
+		t.Fatal("bundle should not be written to the data dir")
+	}
+
+	resp, err := http.Get(ts.URL + "/v1/skills/mem-skill/versions/1.0.0/download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("download returned %d", resp.StatusCode)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	if !bytes.Equal(data, blobs.blobs[getSkillInfo(t, ts.URL, "mem-skill").Versions[0].Checksum]) {
+		t.Fatal("downloaded bundle does not match stored bundle")
+	}
+}
+
+// postJSON sends an authenticated JSON request and returns the response.
+func postJSON(t *testing.T, method, url, token, body string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func getSkillInfo(t *testing.T, serverURL, name string) skillInfoResponse {
+	t.Helper()
+	resp, err := http.Get(serverURL + "/v1/skills/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var info skillInfoResponse
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+func TestYankVersion(t *testing.T) {
+	ts, _ := setupTestServer(t)
+	defer ts.Close()
+
+	publishBundle(t, ts.URL, "test-token", createTestSkillDir(t, "yank-me", "1.0.0"))
+	publishBundle(t, ts.URL, "test-token", createTestSkillDir(t, "yank-me", "1.1.0"))
+
+	resp := postJSON(t, "POST", ts.URL+"/v1/skills/yank-me/versions/1.1.0/yank", "wrong-token", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("yank with wrong token returned %d, want 401", resp.StatusCode)
+	}
+
+	resp = postJSON(t, "POST", ts.URL+"/v1/skills/yank-me/versions/1.1.0/yank", "test-token", `{"reason":"broken"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("yank returned %d", resp.StatusCode)
+	}
+
+	info := getSkillInfo(t, ts.URL, "yank-me")
+	if info.LatestVersion == nil || info.LatestVersion.Version != "1.0.0" {
+		t.Fatalf("latest version after yank = %+v, want 1.0.0", info.LatestVersion)
+	}
+
+	// Yanked versions remain downloadable by exact version, with a warning header.
+	dl, err := http.Get(ts.URL + "/v1/skills/yank-me/versions/1.1.0/download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dl.Body.Close()
+	if dl.StatusCode != http.StatusOK {
+		t.Fatalf("download of yanked version returned %d", dl.StatusCode)
+	}
+	if dl.Header.Get("X-Skill-Yanked") != "true" || dl.Header.Get("X-Skill-Yank-Reason") != "broken" {
+		t.Fatalf("missing yank headers: %v", dl.Header)
+	}
+
+	// Yanking every version hides the skill from search.
+	resp = postJSON(t, "POST", ts.URL+"/v1/skills/yank-me/versions/1.0.0/yank", "test-token", "")
+	resp.Body.Close()
+	sr, _ := http.Get(ts.URL + "/v1/skills?q=yank-me")
+	var result searchResult
+	json.NewDecoder(sr.Body).Decode(&result)
+	sr.Body.Close()
+	if result.Total != 0 {
+		t.Fatalf("fully yanked skill should not appear in search, got %d results", result.Total)
+	}
+
+	resp = postJSON(t, "DELETE", ts.URL+"/v1/skills/yank-me/versions/1.1.0/yank", "test-token", "")
+	resp.Body.Close()
+	if info := getSkillInfo(t, ts.URL, "yank-me"); info.LatestVersion == nil || info.LatestVersion.Version != "1.1.0" {
+		t.Fatalf("latest version after unyank = %+v, want 1.1.0", info.LatestVersion)
+	}
+
+	resp = postJSON(t, "POST", ts.URL+"/v1/skills/yank-me/versions/9.9.9/yank", "test-token", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("yank of missing version returned %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestDeprecateSkill(t *testing.T) {
+	ts, _ := setupTestServer(t)
+	defer ts.Close()
+
+	publishBundle(t, ts.URL, "test-token", createTestSkillDir(t, "old-skill", "1.0.0"))
+
+	resp := postJSON(t, "POST", ts.URL+"/v1/skills/old-skill/deprecate", "test-token", `{"message":"use new-skill"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("deprecate returned %d", resp.StatusCode)
+	}
+
+	if info := getSkillInfo(t, ts.URL, "old-skill"); info.Deprecated != "use new-skill" {
+		t.Fatalf("deprecated = %q, want %q", info.Deprecated, "use new-skill")
+	}
+	dl, _ := http.Get(ts.URL + "/v1/skills/old-skill/versions/1.0.0/download")
+	dl.Body.Close()
+	if dl.Header.Get("X-Skill-Deprecated") != "use new-skill" {
+		t.Fatalf("missing deprecation header: %v", dl.Header)
+	}
+
+	resp = postJSON(t, "POST", ts.URL+"/v1/skills/old-skill/deprecate", "test-token", `{"message":""}`)
+	resp.Body.Close()
+	if info := getSkillInfo(t, ts.URL, "old-skill"); info.Deprecated != "" {
+		t.Fatalf("deprecation should be cleared, got %q", info.Deprecated)
+	}
+}
+
+func TestLatestVersionIsHighestStable(t *testing.T) {
+	ts, dataDir := setupTestServer(t)
+	defer ts.Close()
+
+	for _, v := range []string{"2.0.0", "1.4.1", "3.0.0-beta.1", "1.10.0"} {
+		publishBundle(t, ts.URL, "test-token", createTestSkillDir(t, "semver-skill", v))
+	}
+
+	info := getSkillInfo(t, ts.URL, "semver-skill")
+	if info.LatestVersion == nil || info.LatestVersion.Version != "2.0.0" {
+		t.Fatalf("latest version = %+v, want 2.0.0", info.LatestVersion)
+	}
+
+	meta, err := NewFSMetaStore(dataDir).GetSkill("semver-skill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, v := range meta.Versions {
+		got = append(got, v.Version)
+	}
+	want := []string{"1.4.1", "1.10.0", "2.0.0", "3.0.0-beta.1"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("versions = %v, want semver order %v", got, want)
+	}
+
+	// Prereleases remain installable by exact version.
+	dl, err := http.Get(ts.URL + "/v1/skills/semver-skill/versions/3.0.0-beta.1/download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dl.Body.Close()
+	if dl.StatusCode != http.StatusOK {
+		t.Fatalf("download of prerelease returned %d", dl.StatusCode)
+	}
+}
+
+func TestSearchPrereleaseOnlySkill(t *testing.T) {
+	ts, _ := setupTestServer(t)
+	defer ts.Close()
+
+	publishBundle(t, ts.URL, "test-token", createTestSkillDir(t, "early-skill", "0.1.0-alpha.1"))
+
+	resp, err := http.Get(ts.URL + "/v1/skills?q=early")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var result searchResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 1 || result.Results[0].Name != "early-skill" {
+		t.Fatalf("results = %+v, want early-skill", result.Results)
+	}
+	if result.Results[0].LatestVersion != "" {
+		t.Errorf("latest_version = %q, want none", result.Results[0].LatestVersion)
+	}
+}
+
+func TestListVersions(t *testing.T) {
+	ts, _ := setupTestServer(t)
+	defer ts.Close()
+
+	for _, v := range []string{"1.2.0", "1.10.0", "2.0.0-rc.1", "1.9.0"} {
+		publishBundle(t, ts.URL, "test-token", createTestSkillDir(t, "list-skill", v))
+	}
+	resp := postJSON(t, "POST", ts.URL+"/v1/skills/list-skill/versions/1.10.0/yank", "test-token", "")
+	resp.Body.Close()
+
+	resp, err := http.Get(ts.URL + "/v1/skills/list-skill/versions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("list versions returned %d", resp.StatusCode)
+	}
+	var list versionListResponse
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, v := range list.Versions {
+		got = append(got, v.Version)
+		if v.Yanked != (v.Version == "1.10.0") {
+			t.Errorf("%s yanked = %v", v.Version, v.Yanked)
+		}
+	}
+	if want := "1.2.0,1.9.0,1.10.0,2.0.0-rc.1"; strings.Join(got, ",") != want {
+		t.Errorf("versions = %v, want %s", got, want)
+	}
+
+	pageResp, err := http.Get(ts.URL + "/v1/skills/list-skill/versions?page=2&per_page=3&order=desc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var page versionListResponse
+	json.NewDecoder(pageResp.Body).Decode(&page)
+	pageResp.Body.Close()
+	if page.Total != 4 || page.Page != 2 || len(page.Versions) != 1 || page.Versions[0].Version != "1.2.0" {
+		t.Errorf("page 2 desc = %+v, want only 1.2.0 of 4", page)
+	}
+
+	bad, _ := http.Get(ts.URL + "/v1/skills/list-skill/versions?per_page=1000")
+	bad.Body.Close()
+	if bad.StatusCode != http.StatusBadRequest {
+		t.Errorf("per_page=1000 returned %d, want 400", bad.StatusCode)
+	}
+
+	missing, _ := http.Get(ts.URL + "/v1/skills/nope/versions")
+	missing.Body.Close()
+	if missing.StatusCode != http.StatusNotFound {
+		t.Errorf("missing skill returned %d, want 404", missing.StatusCode)
+	}
+}
+
+func TestDistTags(t *testing.T) {
+	ts, _ := setupTestServer(t)
+	defer ts.Close()
+
+	publishBundle(t, ts.URL, "test-token", createTestSkillDir(t, "tagged", "1.0.0"))
+	resp := postBundleWithTag(t, ts.URL, "test-token", createTestSkillDir(t, "tagged", "2.0.0-rc.1"), "beta")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("publish with tag returned %d", resp.StatusCode)
+	}
+
+	info := getSkillInfo(t, ts.URL, "tagged")
+	if info.DistTags["beta"] != "2.0.0-rc.1" || info.DistTags["latest"] != "1.0.0" {
+		t.Fatalf("dist_tags = %v, want beta=2.0.0-rc.1 latest=1.0.0", info.DistTags)
+	}
+
+	resp = postJSON(t, "PUT", ts.URL+"/v1/skills/tagged/dist-tags/stable", "test-token", `{"version":"1.0.0"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("set dist-tag returned %d", resp.StatusCode)
+	}
+
+	for _, tc := range []struct {
+		method, tag, body string
+		want              int
+	}{
+		{"PUT", "stable", `{"version":"9.9.9"}`, http.StatusNotFound},
+		{"PUT", "latest", `{"version":"1.0.0"}`, http.StatusBadRequest},
+		{"PUT", "1.0.0", `{"version":"1.0.0"}`, http.StatusBadRequest},
+		{"PUT", "x", `{"version":"1.0.0"}`, http.StatusBadRequest},
+		{"DELETE", "nightly", "", http.StatusNotFound},
+	} {
+		resp := postJSON(t, tc.method, ts.URL+"/v1/skills/tagged/dist-tags/"+tc.tag, "test-token", tc.body)
+		resp.Body.Close()
+		if resp.StatusCode != tc.want {
+			t.Errorf("%s dist-tag %s returned %d, want %d", tc.method, tc.tag, resp.StatusCode, tc.want)
+		}
+	}
+
+	resp = postJSON(t, "DELETE", ts.URL+"/v1/skills/tagged/dist-tags/beta", "test-token", "")
+	var tags map[string]string
+	json.NewDecoder(resp.Body).Decode(&tags)
+	resp.Body.Close()
+	if _, ok := tags["beta"]; ok || tags["stable"] != "1.0.0" {
+		t.Fatalf("tags after removing beta = %v", tags)
+	}
+}
+
+// writeTestUsers registers users with a token "<name>-token" each.
+func writeTestUsers(t *testing.T, dataDir string, names ...string) {
+	t.Helper()
+	store := NewFSMetaStore(dataDir)
+	for _, name := range names {
+		if err := store.CreateUser(User{Username: name, CreatedAt: time.Now().UTC()}); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.CreateToken(APIToken{
+			ID: "id-" + name, Username: name, Name: "test", Hash: HashToken(name + "-token"), CreatedAt: time.Now().UTC(),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// createAuthoredSkillDir is createTestSkillDir with a specific author.
+func createAuthoredSkillDir(t *testing.T, name, version, author string) string {
+	t.Helper()
+	dir := createTestSkillDir(t, name, version)
+	path := filepath.Join(dir, "SKILL.md")
+	data, _ := os.ReadFile(path)
+	data = bytes.Replace(data, []byte(`author: "tester"`), []byte(`author: "`+author+`"`), 1)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// staleMetaStore reports every skill as missing, as GetSkill would just
+// before a concurrent publish creates it.
+type staleMetaStore struct{ MetaStore }
+
+func (staleMetaStore) GetSkill(string) (*SkillMeta, error) { return nil, nil }
+
+func TestOpenMode(t *testing.T) {
+	serve := func(opts Options) (*httptest.Server, string) {
+		dataDir := t.TempDir()
+		handler := NewHandler(NewFSMetaStore(dataDir), NewFSBlobStore(dataDir), opts)
+		mux := http.NewServeMux()
+		handler.RegisterRoutes(mux)
+		return httptest.NewServer(mux), dataDir
+	}
+	status := func(resp *http.Response) int {
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Without an admin token, anyone is admin until a user is registered.
+	ts, dataDir := serve(Options{})
+	defer ts.Close()
+	if got := status(postBundle(t, ts.URL, "", createAuthoredSkillDir(t, "early", "1.0.0", "alice"))); got != http.StatusCreated {
+		t.Fatalf("anonymous publish before any user exists returned %d, want 201", got)
+	}
+	writeTestUsers(t, dataDir, "alice", "bob")
+	secretDir := createAuthoredSkillDir(t, "secret", "1.0.0", "alice")
+	path := filepath.Join(secretDir, "SKILL.md")
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, bytes.Replace(data, []byte("---\n"), []byte("---\nvisibility: private\n"), 1), 0o644)
+	publishBundle(t, ts.URL, "alice-token", secretDir)
+
+	if got := status(postBundle(t, ts.URL, "", createAuthoredSkillDir(t, "early", "1.1.0", "alice"))); got != http.StatusUnauthorized {
+		t.Errorf("anonymous publish once users exist returned %d, want 401", got)
+	}
+	if got := status(postJSON(t, "POST", ts.URL+"/v1/skills/early/transfer", "", `{"owner": "bob"}`)); got != http.StatusUnauthorized {
+		t.Errorf("anonymous transfer once users exist returned %d, want 401", got)
+	}
+	resp, err := http.Get(ts.URL + "/v1/skills/secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := status(resp); got != http.StatusNotFound {
+		t.Errorf("anonymous GET of a private skill once users exist returned %d, want 404", got)
+	}
+	if got := status(postBundle(t, ts.URL, "wrong", createAuthoredSkillDir(t, "early", "1.1.0", "alice"))); got != http.StatusUnauthorized {
+		t.Errorf("publish with an unknown token returned %d, want 401", got)
+	}
+
+	// The operator can keep it open.
+	ts2, dataDir2 := serve(Options{InsecureOpen: true})
+	defer ts2.Close()
+	writeTestUsers(t, dataDir2, "alice")
+	if got := status(postBundle(t, ts2.URL, "", createAuthoredSkillDir(t, "open", "1.0.0", "bob"))); got != http.StatusCreated {
+		t.Errorf("anonymous publish with InsecureOpen returned %d, want 201", got)
+	}
+}
+
+func TestPublishOwnershipRace(t *testing.T) {
+	dataDir := t.TempDir()
+	meta := NewFSMetaStore(dataDir)
+	handler := NewHandler(staleMetaStore{meta}, NewFSBlobStore(dataDir), Options{Token: "test-token"})
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	writeTestUsers(t, dataDir, "alice", "bob")
+
+	publishBundle(t, ts.URL, "alice-token", createAuthoredSkillDir(t, "contested", "1.0.0", "alice"))
+	resp := postBundle(t, ts.URL, "bob-token", createAuthoredSkillDir(t, "contested", "1.1.0", "bob"))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("publish to a skill created by someone else returned %d, want 403", resp.StatusCode)
+	}
+	m, _ := meta.GetSkill("contested")
+	if m.Owner != "alice" || len(m.Versions) != 1 {
+		t.Fatalf("skill = owner %q with %d versions, want alice's 1.0.0 only", m.Owner, len(m.Versions))
+	}
+}
+
+func TestPublishOwnership(t *testing.T) {
+	ts, dataDir := setupTestServer(t)
+	defer ts.Close()
+	writeTestUsers(t, dataDir, "alice", "bob")
+
+	publishBundle(t, ts.URL, "alice-token", createAuthoredSkillDir(t, "owned", "1.0.0", "alice"))
+	if info := getSkillInfo(t, ts.URL, "owned"); info.Owner != "alice" {
+		t.Fatalf("owner = %q, want alice", info.Owner)
+	}
+
+	expectStatus := func(resp *http.Response, want int, what string) {
+		t.Helper()
+		defer resp.Body.Close()
+		if resp.StatusCode != want {
+			b, _ := io.ReadAll(resp.Body)
+			t.Fatalf("%s returned %d, want %d: %s", what, resp.StatusCode, want, b)
+		}
+	}
+
+	// Bob cannot claim to be alice, nor publish to alice's skill as himself.
+	expectStatus(postBundle(t, ts.URL, "bob-token", createAuthoredSkillDir(t, "owned", "1.1.0", "alice")),
+		http.StatusForbidden, "publish with someone else's author")
+	expectStatus(postBundle(t, ts.URL, "bob-token", createAuthoredSkillDir(t, "owned", "1.1.0", "bob")),
+		http.StatusForbidden, "publish to someone else's skill")
+	expectStatus(postJSON(t, "POST", ts.URL+"/v1/skills/owned/versions/1.0.0/yank", "bob-token", ""),
+		http.StatusForbidden, "yank someone else's skill")
+	expectStatus(postBundle(t, ts.URL, "nobody-token", createAuthoredSkillDir(t, "owned", "1.1.0", "bob")),
+		http.StatusUnauthorized, "publish with unknown token")
+
+	// Collaborators may publish; the skill keeps its owner.
+	err := NewFSMetaStore(dataDir).UpdateSkill("owned", func(m *SkillMeta) error {
+		m.Collaborators = []string{"bob"}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	publishBundle(t, ts.URL, "bob-token", createAuthoredSkillDir(t, "owned", "1.1.0", "bob"))
+	if info := getSkillInfo(t, ts.URL, "owned"); info.Owner != "alice" || info.LatestVersion.Version != "1.1.0" {
+		t.Fatalf("after collaborator publish: owner %q latest %+v", info.Owner, info.LatestVersion)
+	}
+
+	// The shared admin token may manage any skill.
+	expectStatus(postJSON(t, "POST", ts.URL+"/v1/skills/owned/deprecate", "test-token", `{"message":"x"}`),
+		http.StatusOK, "admin deprecate")
+}
+
+func TestTokens(t *testing.T) {
+	ts, dataDir := setupTestServer(t)
+	defer ts.Close()
+	writeTestUsers(t, dataDir, "alice")
+
+	// Tokens belong to users; the shared admin token has none.
+	resp := postJSON(t, "GET", ts.URL+"/v1/tokens", "test-token", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("listing tokens as admin returned %d, want 403", resp.StatusCode)
+	}
+
+	resp = postJSON(t, "POST", ts.URL+"/v1/tokens", "alice-token", `{"name": "ci", "expires_at": "2999-01-01T00:00:00Z"}`)
+	if resp.StatusCode != http.StatusCreated {
+		b, _ := io.ReadAll(resp.Body)
+		t.Fatalf("creating token returned %d: %s", resp.StatusCode, b)
+	}
+	var created createTokenResponse
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if !strings.HasPrefix(created.Token, tokenPrefix) || created.Name != "ci" || created.ExpiresAt == nil {
+		t.Fatalf("created token = %+v", created)
+	}
+
+	// The new token authenticates as alice.
+	publishBundle(t, ts.URL, created.Token, createAuthoredSkillDir(t, "by-token", "1.0.0", "alice"))
+
+	resp = postJSON(t, "GET", ts.URL+"/v1/tokens", created.Token, "")
+	var list tokenListResponse
+	json.NewDecoder(resp.Body).Decode(&list)
+	resp.Body.Close()
+	if len(list.Tokens) != 2 || list.Tokens[1].ID != created.ID || list.Tokens[1].LastUsedAt == nil {
+		t.Fatalf("token list = %+v, want test and used ci token", list.Tokens)
+	}
+
+	for _, body := range []string{`{}`, `{"name": "old", "expires_at": "2000-01-01T00:00:00Z"}`} {
+		resp = postJSON(t, "POST", ts.URL+"/v1/tokens", "alice-token", body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("creating token with %s returned %d, want 400", body, resp.StatusCode)
+		}
+	}
+
+	resp = postJSON(t, "DELETE", ts.URL+"/v1/tokens/"+created.ID, "alice-token", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("revoking token returned %d, want 204", resp.StatusCode)
+	}
+	resp = postJSON(t, "DELETE", ts.URL+"/v1/tokens/"+created.ID, "alice-token", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("revoking twice returned %d, want 404", resp.StatusCode)
+	}
+	resp = postJSON(t, "GET", ts.URL+"/v1/tokens", created.Token, "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("revoked token returned %d, want 401", resp.StatusCode)
+	}
+}
+
+func TestExpiredToken(t *testing.T) {
+	ts, dataDir := setupTestServer(t)
+	defer ts.Close()
+
+	store := NewFSMetaStore(dataDir)
+	store.CreateUser(User{Username: "alice"})
+	expired := time.Now().Add(-time.Minute)
+	tok, secret := NewAPIToken("alice", "old", &expired)
+	store.CreateToken(tok)
+
+	resp := postJSON(t, "GET", ts.URL+"/v1/tokens", secret, "")
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusUnauthorized || !strings.Contains(string(body), "expired") {
+		t.Errorf("expired token returned %d: %s", resp.StatusCode, body)
+	}
+}
+
+func TestLegacyUsersFile(t *testing.T) {
+	ts, dataDir := setupTestServer(t)
+	defer ts.Close()
+	// users.json used to be a plain array with clear-text tokens.
+	legacy := `[{"username": "alice", "api_token": "alice-secret"}]`
+	if err := os.WriteFile(filepath.Join(dataDir, "users.json"), []byte(legacy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	publishBundle(t, ts.URL, "alice-secret", createAuthoredSkillDir(t, "legacy", "1.0.0", "alice"))
+
+	data, _ := os.ReadFile(filepath.Join(dataDir, "users.json"))
+	if bytes.Contains(data, []byte("alice-secret")) {
+		t.Errorf("users.json should no longer contain the clear-text token: %s", data)
+	}
+}
+
+func TestScopedTokens(t *testing.T) {
+	ts, dataDir := setupTestServer(t)
+	defer ts.Close()
+	writeTestUsers(t, dataDir, "alice")
+
+	createToken := func(scopes string) createTokenResponse {
+		t.Helper()
+		resp := postJSON(t, "POST", ts.URL+"/v1/tokens", "alice-token", `{"name": "ci", "scopes": `+scopes+`}`)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusCreated {
+			b, _ := io.ReadAll(resp.Body)
+			t.Fatalf("creating token with scopes %s returned %d: %s", scopes, resp.StatusCode, b)
+		}
+		var created createTokenResponse
+		json.NewDecoder(resp.Body).Decode(&created)
+		return created
+	}
+	expectStatus := func(resp *http.Response, want int, what string) {
+		t.Helper()
+		defer resp.Body.Close()
+		if resp.StatusCode != want {
+			b, _ := io.ReadAll(resp.Body)
+			t.Errorf("%s returned %d, want %d: %s", what, resp.StatusCode, want, b)
+		}
+	}
+
+	ci := createToken(`["publish:code-review-*"]`)
+	if len(ci.Scopes) != 1 || ci.Scopes[0] != "publish:code-review-*" {
+		t.Fatalf("scopes = %v", ci.Scopes)
+	}
+	publishBundle(t, ts.URL, ci.Token, createAuthoredSkillDir(t, "code-review-go", "1.0.0", "alice"))
+	expectStatus(postBundle(t, ts.URL, ci.Token, createAuthoredSkillDir(t, "lint", "1.0.0", "alice")),
+		http.StatusForbidden, "publishing outside the scope")
+	expectStatus(postJSON(t, "POST", ts.URL+"/v1/skills/code-review-go/versions/1.0.0/yank", ci.Token, ""),
+		http.StatusForbidden, "yanking with a publish token")
+	expectStatus(postJSON(t, "PUT", ts.URL+"/v1/skills/code-review-go/dist-tags/beta", ci.Token, `{"version": "1.0.0"}`),
+		http.StatusOK, "tagging with a publish token")
+	expectStatus(postJSON(t, "POST", ts.URL+"/v1/skills/code-review-go/deprecate", ci.Token, `{"message": "x"}`),
+		http.StatusForbidden, "deprecating with a publish token")
+	expectStatus(postJSON(t, "POST", ts.URL+"/v1/tokens", ci.Token, `{"name": "escalate"}`),
+		http.StatusForbidden, "creating a token with a publish token")
+	expectStatus(postJSON(t, "GET", ts.URL+"/v1/tokens", ci.Token, ""),
+		http.StatusOK, "listing tokens with a publish token")
+
+	yank := createToken(`["yank"]`)
+	expectStatus(postJSON(t, "POST", ts.URL+"/v1/skills/code-review-go/versions/1.0.0/yank", yank.Token, ""),
+		http.StatusOK, "yanking with a yank token")
+
+	expectStatus(postJSON(t, "POST", ts.URL+"/v1/tokens", "alice-token", `{"name": "bad", "scopes": ["write"]}`),
+		http.StatusBadRequest, "creating a token with an unknown permission")
+}
+
+func TestWhoAmI(t *testing.T) {
+	ts, dataDir := setupTestServer(t)
+	defer ts.Close()
+	writeTestUsers(t, dataDir, "alice")
+
+	whoami := func(token string) (int, whoAmIResponse) {
+		t.Helper()
+		resp := postJSON(t, "GET", ts.URL+"/v1/whoami", token, "")
+		defer resp.Body.Close()
+		var got whoAmIResponse
+		json.NewDecoder(resp.Body).Decode(&got)
+		return resp.StatusCode, got
+	}
+
+	status, got := whoami("alice-token")
+	if status != http.StatusOK || got.Username != "alice" || got.Admin || got.Auth != "token" ||
+		got.Token == nil || got.Token.Name != "test" || got.Token.LastUsedAt == nil {
+		t.Errorf("whoami as alice = %d %+v", status, got)
+	}
+	status, got = whoami("test-token")
+	if status != http.StatusOK || got.Username != "" || !got.Admin || got.Auth != "admin_token" || got.Token != nil {
+		t.Errorf("whoami with the admin token = %d %+v", status, got)
+	}
+	if status, _ := whoami("wrong"); status != http.StatusUnauthorized {
+		t.Errorf("whoami with an unknown token returned %d, want 401", status)
+	}
+}
+
+func TestSkillVisibility(t *testing.T) {
+	ts, dataDir := setupTestServer(t)
+	defer ts.Close()
+	writeTestUsers(t, dataDir, "alice", "bob")
+
+	// "secret" is private from its first version on.
+	secretDir := createAuthoredSkillDir(t, "secret", "1.0.0", "alice")
+	path := filepath.Join(secretDir, "SKILL.md")
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, bytes.Replace(data, []byte("---\n"), []byte("---\nvisibility: private\n"), 1), 0o644)
+	publishBundle(t, ts.URL, "alice-token", secretDir)
+	publishBundle(t, ts.URL, "alice-token", createAuthoredSkillDir(t, "team", "1.0.0", "alice"))
+	publishBundle(t, ts.URL, "alice-token", createAuthoredSkillDir(t, "open", "1.0.0", "alice"))
+
+	get := func(path, token string) (int, errorResponse) {
+		t.Helper()
+		req, _ := http.NewRequest("GET", ts.URL+path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var body errorResponse
+		if resp.StatusCode != http.StatusOK {
+			json.NewDecoder(resp.Body).Decode(&body)
+		}
+		return resp.StatusCode, body
+	}
+	expectStatus := func(resp *http.Response, want int, what string) {
+		t.Helper()
+		defer resp.Body.Close()
+		if resp.StatusCode != want {
+			b, _ := io.ReadAll(resp.Body)
+			t.Errorf("%s returned %d, want %d: %s", what, resp.StatusCode, want, b)
+		}
+	}
+	expectStatus(postJSON(t, "PUT", ts.URL+"/v1/skills/team/visibility", "alice-token", `{"visibility": "internal"}`),
+		http.StatusOK, "making team internal")
+	expectStatus(postJSON(t, "PUT", ts.URL+"/v1/skills/team/visibility", "alice-token", `{"visibility": "hidden"}`),
+		http.StatusBadRequest, "setting an unknown visibility")
+
+	search := func(token string) []string {
+		t.Helper()
+		req, _ := http.NewRequest("GET", ts.URL+"/v1/skills", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var result searchResult
+		json.NewDecoder(resp.Body).Decode(&result)
+		var names []string
+		for _, r := range result.Results {
+			names = append(names, r.Name)
+		}
+		return names
+	}
+	for token, want := range map[string]string{
+		"":            "open",
+		"bob-token":   "open team",
+		"alice-token": "open secret team",
+		"test-token":  "open secret team",
+	} {
+		if got := strings.Join(search(token), " "); got != want {
+			t.Errorf("search with token %q = %q, want %q", token, got, want)
+		}
+	}
+
+	// Hidden skills look exactly like missing ones.
+	_, missing := get("/v1/skills/no-such-skill", "bob-token")
+	for _, path := range []string{
+		"/v1/skills/secret",
+		"/v1/skills/secret/versions",
+		"/v1/skills/secret/versions/1.0.0/download",
+		"/v1/skills/secret/dist-tags",
+	} {
+		status, body := get(path, "bob-token")
+		body.Error = strings.Replace(body.Error, "secret", "no-such-skill", 1)
+		if status != http.StatusNotFound || body != missing {
+			t.Errorf("GET %s as bob = %d %+v, want 404 %+v", path, status, body, missing)
+		}
+		if status, _ := get(path, "alice-token"); status != http.StatusOK {
+			t.Errorf("GET %s as alice = %d, want 200", path, status)
+		}
+	}
+	if status, _ := get("/v1/skills/team", ""); status != http.StatusNotFound {
+		t.Errorf("anonymous GET of an internal skill = %d, want 404", status)
+	}
+	if status, _ := get("/v1/skills/team", "bob-token"); status != http.StatusOK {
+		t.Errorf("authenticated GET of an internal skill = %d, want 200", status)
+	}
+	if status, _ := get("/v1/skills/open", "wrong"); status != http.StatusUnauthorized {
+		t.Errorf("GET with an unknown token = %d, want 401", status)
+	}
+	expectStatus(postJSON(t, "POST", ts.URL+"/v1/skills/secret/versions/1.0.0/yank", "bob-token", ""),
+		http.StatusNotFound, "yanking a hidden skill")
+	// Publishing to a hidden skill is refused as to a visible one, without
+	// telling the two apart.
+	publishError := func(name string) string {
+		t.Helper()
+		resp := postBundle(t, ts.URL, "bob-token", createAuthoredSkillDir(t, name, "1.1.0", "bob"))
+		defer resp.Body.Close()
+		var body errorResponse
+		json.NewDecoder(resp.Body).Decode(&body)
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("publishing to %s returned %d, want 403", name, resp.StatusCode)
+		}
+		return strings.ReplaceAll(body.Error, name, "NAME")
+	}
+	if hidden, visible := publishError("secret"), publishError("open"); hidden != visible {
+		t.Errorf("publish errors differ: %q for a hidden skill, %q for a visible one", hidden, visible)
+	}
+
+	// A token scoped to other skills cannot read private ones.
+	resp := postJSON(t, "POST", ts.URL+"/v1/tokens", "alice-token", `{"name": "ci", "scopes": ["read:open"]}`)
+	var scoped createTokenResponse
+	json.NewDecoder(resp.Body).Decode(&scoped)
+	resp.Body.Close()
+	if status, _ := get("/v1/skills/secret", scoped.Token); status != http.StatusNotFound {
+		t.Errorf("GET of a private skill outside the token scope = %d, want 404", status)
+	}
+
+	// Publishing a version that declares another visibility does not change
+	// it without admin scope.
+	withVisibility := func(version, visibility string) string {
+		dir := createAuthoredSkillDir(t, "secret", version, "alice")
+		path := filepath.Join(dir, "SKILL.md")
+		data, _ := os.ReadFile(path)
+		os.WriteFile(path, bytes.Replace(data, []byte("---\n"), []byte("---\nvisibility: "+visibility+"\n"), 1), 0o644)
+		return dir
+	}
+	resp = postJSON(t, "POST", ts.URL+"/v1/tokens", "alice-token", `{"name": "publisher", "scopes": ["publish:secret"]}`)
+	var publisher createTokenResponse
+	json.NewDecoder(resp.Body).Decode(&publisher)
+	resp.Body.Close()
+	expectStatus(postBundle(t, ts.URL, publisher.Token, withVisibility("1.1.0", "public")),
+		http.StatusForbidden, "making secret public by publishing with a publish-scoped token")
+	publishBundle(t, ts.URL, publisher.Token, withVisibility("1.1.0", "private"))
+	if status, _ := get("/v1/skills/secret", "bob-token"); status != http.StatusNotFound {
+		t.Errorf("GET of secret by bob after the publishes = %d, want 404", status)
+	}
+	publishBundle(t, ts.URL, "alice-token", withVisibility("1.2.0", "internal"))
+	if status, _ := get("/v1/skills/secret", "bob-token"); status != http.StatusOK {
+		t.Errorf("GET of secret by bob after an admin publish made it internal = %d, want 200", status)
+	}
+
+	expectStatus(postJSON(t, "PUT", ts.URL+"/v1/skills/secret/visibility", "alice-token", `{"visibility": "public"}`),
+		http.StatusOK, "making secret public")
+	if info := getSkillInfo(t, ts.URL, "secret"); info.Visibility != VisibilityPublic {
+		t.Errorf("visibility after making it public = %q", info.Visibility)
+	}
+}
+
+func TestScopedSkills(t *testing.T) {
+	ts, dataDir := setupTestServer(t)
+	defer ts.Close()
+	writeTestUsers(t, dataDir, "alice", "bob", "carol")
+
+	expectStatus := func(resp *http.Response, want int, what string) {
+		t.Helper()
+		defer resp.Body.Close()
+		if resp.StatusCode != want {
+			b, _ := io.ReadAll(resp.Body)
+			t.Errorf("%s returned %d, want %d: %s", what, resp.StatusCode, want, b)
+		}
+	}
+
+	// Scopes belong to organizations, which must exist before publishing.
+	expectStatus(postBundle(t, ts.URL, "alice-token", createAuthoredSkillDir(t, "@acme/code-review", "1.0.0", "alice")),
+		http.StatusNotFound, "publishing to a missing organization")
+	expectStatus(postJSON(t, "POST", ts.URL+"/v1/orgs", "alice-token", `{"name": "acme"}`),
+		http.StatusCreated, "creating acme")
+	expectStatus(postJSON(t, "POST", ts.URL+"/v1/orgs", "bob-token", `{"name": "acme"}`),
+		http.StatusConflict, "creating acme twice")
+	expectStatus(postJSON(t, "POST", ts.URL+"/v1/orgs", "bob-token", `{"name": "Not_Valid"}`),
+		http.StatusBadRequest, "creating an invalid organization")
+
+	publishBundle(t, ts.URL, "alice-token", createAuthoredSkillDir(t, "@acme/code-review", "1.0.0", "alice"))
+	expectStatus(postBundle(t, ts.URL, "bob-token", createAuthoredSkillDir(t, "@acme/lint", "1.0.0", "bob")),
+		http.StatusForbidden, "publishing as a non-member")
+
+	// Only owners manage members; members may publish and manage every
+	// skill in the scope.
+	expectStatus(postJSON(t, "PUT", ts.URL+"/v1/orgs/acme/members/carol", "bob-token", ""),
+		http.StatusNotFound, "adding a member as a non-member")
+	expectStatus(postJSON(t, "PUT", ts.URL+"/v1/orgs/acme/members/bob", "alice-token", ""),
+		http.StatusOK, "adding bob")
+	expectStatus(postJSON(t, "PUT", ts.URL+"/v1/orgs/acme/members/carol", "bob-token", ""),
+		http.StatusForbidden, "adding a member as a non-owner")
+	expectStatus(postJSON(t, "PUT", ts.URL+"/v1/orgs/acme/members/nobody", "alice-token", ""),
+		http.StatusNotFound, "adding an unknown user")
+	publishBundle(t, ts.URL, "bob-token", createAuthoredSkillDir(t, "@acme/code-review", "1.1.0", "bob"))
+	expectStatus(postJSON(t, "POST", ts.URL+"/v1/skills/@acme/code-review/deprecate", "bob-token", `{"message": "x"}`),
+		http.StatusOK, "deprecating as a member")
+
+	// The name is one path segment, escaped or not.
+	for _, path := range []string{"/v1/skills/@acme/code-review", "/v1/skills/@acme%2Fcode-review"} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var info skillInfoResponse
+		json.NewDecoder(resp.Body).Decode(&info)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || info.Name != "@acme/code-review" || info.Owner != "alice" {
+			t.Errorf("GET %s = %d %+v", path, resp.StatusCode, info)
+		}
+	}
+	resp, err := http.Get(ts.URL + "/v1/skills/@acme/code-review/versions/1.1.0/download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("download of a scoped skill returned %d", resp.StatusCode)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "bundles", "@acme", "code-review", "meta.json")); err != nil {
+		t.Errorf("scoped skill meta: %v", err)
+	}
+
+	resp, _ = http.Get(ts.URL + "/v1/skills?q=review")
+	var result searchResult
+	json.NewDecoder(resp.Body).Decode(&result)
+	resp.Body.Close()
+	if len(result.Results) != 1 || result.Results[0].Name != "@acme/code-review" {
+		t.Errorf("search results = %+v", result.Results)
+	}
+
+	// The last owner cannot leave; members can.
+	expectStatus(postJSON(t, "DELETE", ts.URL+"/v1/orgs/acme/members/alice", "alice-token", ""),
+		http.StatusConflict, "removing the last owner")
+	expectStatus(postJSON(t, "DELETE", ts.URL+"/v1/orgs/acme/members/bob", "bob-token", ""),
+		http.StatusOK, "leaving the organization")
+	expectStatus(postBundle(t, ts.URL, "bob-token", createAuthoredSkillDir(t, "@acme/code-review", "1.2.0", "bob")),
+		http.StatusForbidden, "publishing after leaving")
+
+	resp = postJSON(t, "GET", ts.URL+"/v1/orgs/acme", "alice-token", "")
+	var org orgResponse
+	json.NewDecoder(resp.Body).Decode(&org)
+	resp.Body.Close()
+	if len(org.Members) != 1 || org.Members[0] != (OrgMember{Username: "alice", Role: OrgRoleOwner}) {
+		t.Errorf("members = %+v", org.Members)
+	}
+	expectStatus(postJSON(t, "GET", ts.URL+"/v1/orgs/acme", "bob-token", ""),
+		http.StatusNotFound, "viewing an organization as a non-member")
+}
+
+func TestSkillOwners(t *testing.T) {
+	ts, dataDir := setupTestServer(t)
+	defer ts.Close()
+	writeTestUsers(t, dataDir, "alice", "bob", "carol")
+	publishBundle(t, ts.URL, "alice-token", createAuthoredSkillDir(t, "shared", "1.0.0", "alice"))
+
+	owners := func(token, method, path, body string, want int) ownersResponse {
+		t.Helper()
+		resp := postJSON(t, method, ts.URL+"/v1/skills/shared"+path, token, body)
+		defer resp.Body.Close()
+		if resp.StatusCode != want {
+			b, _ := io.ReadAll(resp.Body)
+			t.Fatalf("%s %s as %s returned %d, want %d: %s", method, path, token, resp.StatusCode, want, b)
+		}
+		var o ownersResponse
+		json.NewDecoder(resp.Body).Decode(&o)
+		return o
+	}
+
+	// Collaborators may publish, but only owners change who manages a skill.
+	got := owners("alice-token", "PUT", "/collaborators/bob", "", http.StatusOK)
+	if got.Owner != "alice" || strings.Join(got.Collaborators, ",") != "bob" {
+		t.Fatalf("owners after adding bob = %+v", got)
+	}
+	publishBundle(t, ts.URL, "bob-token", createAuthoredSkillDir(t, "shared", "1.1.0", "bob"))
+	owners("bob-token", "PUT", "/collaborators/carol", "", http.StatusForbidden)
+	owners("bob-token", "POST", "/transfer", `{"owner": "bob"}`, http.StatusForbidden)
+	owners("carol-token", "PUT", "/collaborators/carol", "", http.StatusForbidden)
+	owners("alice-token", "PUT", "/collaborators/nobody", "", http.StatusNotFound)
+	owners("alice-token", "DELETE", "/collaborators/carol", "", http.StatusNotFound)
+
+	// Collaborators may leave on their own.
+	owners("alice-token", "PUT", "/collaborators/carol", "", http.StatusOK)
+	owners("carol-token", "DELETE", "/collaborators/carol", "", http.StatusOK)
+
+	// A transfer hands over every owner right.
+	got = owners("alice-token", "POST", "/transfer", `{"owner": "bob"}`, http.StatusOK)
+	if got.Owner != "bob" || len(got.Collaborators) != 0 {
+		t.Fatalf("owners after transfer = %+v", got)
+	}
+	owners("alice-token", "PUT", "/collaborators/alice", "", http.StatusForbidden)
+
+	// Organizations can own skills; their members manage them.
+	postJSON(t, "POST", ts.URL+"/v1/orgs", "carol-token", `{"name": "acme"}`).Body.Close()
+	owners("bob-token", "POST", "/transfer", `{"owner": "@nope"}`, http.StatusNotFound)
+	owners("bob-token", "POST", "/transfer", `{"owner": "@acme"}`, http.StatusOK)
+	publishBundle(t, ts.URL, "carol-token", createAuthoredSkillDir(t, "shared", "1.2.0", "carol"))
+	resp := postBundle(t, ts.URL, "bob-token", createAuthoredSkillDir(t, "shared", "1.3.0", "bob"))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("publish by the previous owner returned %d, want 403", resp.StatusCode)
+	}
+
+	got = owners("alice-token", "GET", "/owners", "", http.StatusOK)
+	var actions []string
+	for _, e := range got.History {
+		actions = append(actions, e.Action+" "+e.User+" by "+e.Actor)
+		if e.At.IsZero() {
+			t.Errorf("event %+v has no time", e)
+		}
+	}
+	want := []string{
+		"add_collaborator bob by alice",
+		"add_collaborator carol by alice",
+		"remove_collaborator carol by carol",
+		"transfer bob by alice",
+		"transfer @acme by bob",
+	}
+	if got.Owner != "@acme" || !reflect.DeepEqual(actions, want) {
+		t.Errorf("owners = %s, history = %q, want @acme, %q", got.Owner, actions, want)
+	}
+	if got.History[3].From != "alice" {
+		t.Errorf("transfer from = %q, want alice", got.History[3].From)
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	ts, dataDir := setupTestServer(t)
+	defer ts.Close()
+	writeTestUsers(t, dataDir, "alice", "bob")
+	publishBundle(t, ts.URL, "alice-token", createAuthoredSkillDir(t, "audited", "1.0.0", "alice"))
+	for _, c := range []struct{ method, path, token, body string }{
+		{"POST", "/v1/skills/audited/versions/1.0.0/yank", "alice-token", `{"reason": "broken"}`},
+		{"PUT", "/v1/skills/audited/dist-tags/beta", "alice-token", `{"version": "1.0.0"}`},
+		{"PUT", "/v1/skills/audited/collaborators/bob", "test-token", ""},
+		// Failed mutations are not recorded.
+		{"POST", "/v1/skills/audited/transfer", "bob-token", `{"owner": "bob"}`},
+	} {
+		postJSON(t, c.method, ts.URL+c.path, c.token, c.body).Body.Close()
+	}
+
+	audit := func(token, query string, want int) []AuditEvent {
+		t.Helper()
+		req, _ := http.NewRequest("GET", ts.URL+"/v1/audit"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != want {
+			b, _ := io.ReadAll(resp.Body)
+			t.Fatalf("GET /v1/audit%s as %s returned %d, want %d: %s", query, token, resp.StatusCode, want, b)
+		}
+		var body auditResponse
+		json.NewDecoder(resp.Body).Decode(&body)
+		return body.Events
+	}
+
+	audit("alice-token", "", http.StatusForbidden)
+	audit("test-token", "?since=yesterday", http.StatusBadRequest)
+	events := audit("test-token", "?skill=audited", http.StatusOK)
+	var got []string
+	for _, e := range events {
+		got = append(got, e.Action+" "+e.Version+" "+e.Detail+" by "+e.Actor)
+		if e.ID == "" || e.Time.IsZero() || e.ClientIP != "127.0.0.1" {
+			t.Errorf("event %+v lacks an id, time or client IP", e)
+		}
+	}
+	want := []string{
+		"skill.publish 1.0.0  by alice",
+		"skill.yank 1.0.0 broken by alice",
+		"dist_tag.set 1.0.0 beta by alice",
+		"owner.add_collaborator  bob by admin-token",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("audit events = %q, want %q", got, want)
+	}
+	if events[0].Checksum == "" || events[0].TokenID == "" || events[3].TokenID != "" {
+		t.Errorf("publish event = %+v, collaborator event = %+v", events[0], events[3])
+	}
+
+	if got := audit("test-token", "?actor=admin-token", http.StatusOK); len(got) != 1 {
+		t.Errorf("events by admin-token = %+v, want 1", got)
+	}
+	since := events[3].Time.Format(time.RFC3339Nano)
+	if got := audit("test-token", "?since="+since, http.StatusOK); len(got) != 1 {
+		t.Errorf("events since %s = %+v, want 1", since, got)
+	}
+	if got := audit("test-token", "?limit=2", http.StatusOK); len(got) != 2 || got[1].Action != AuditCollaboratorAdd {
+		t.Errorf("newest 2 events = %+v", got)
+	}
+
+	// The export is one JSON object per line, and the log outlives the handler.
+	reopened := NewHandler(NewFSMetaStore(dataDir), NewFSBlobStore(dataDir), Options{Token: "test-token"})
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/v1/audit?format=jsonl", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	mux := http.NewServeMux()
+	reopened.RegisterRoutes(mux)
+	mux.ServeHTTP(rec, req)
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if rec.Code != http.StatusOK || len(lines) != len(events) {
+		t.Fatalf("jsonl export returned %d with %d lines, want %d", rec.Code, len(lines), len(events))
+	}
+	var first AuditEvent
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil || first != events[0] {
+		t.Errorf("first exported event = %+v, %v, want %+v", first, err, events[0])
+	}
+}
+
+func TestAuditClientIP(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.2:4711"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1, 203.0.113.7")
+	if got := (&Handler{}).clientIP(req); got != "10.0.0.2" {
+		t.Errorf("clientIP() = %q, want the peer address", got)
+	}
+	if got := (&Handler{opts: Options{TrustProxy: true}}).clientIP(req); got != "203.0.113.7" {
+		t.Errorf("clientIP() behind a proxy = %q, want the address the proxy appended", got)
+	}
+
+	// The client may send X-Forwarded-For itself; the proxy appends to it.
+	dataDir := t.TempDir()
+	handler := NewHandler(NewFSMetaStore(dataDir), NewFSBlobStore(dataDir), Options{Token: "test-token", TrustProxy: true})
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	publishBundle(t, ts.URL, "test-token", createTestSkillDir(t, "proxied", "1.0.0"))
+	req, _ = http.NewRequest("POST", ts.URL+"/v1/skills/proxied/versions/1.0.0/yank", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer test-token")
+	req.Header.Set("X-Forwarded-For", "1.2.3.4, 10.9.9.9, 203.0.113.7")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	events, err := handler.meta.ListAudit(AuditFilter{})
+	if err != nil || len(events) != 2 || events[1].Action != AuditYank {
+		t.Fatalf("audit events = %+v, %v, want the publish and the yank", events, err)
+	}
+	if events[1].ClientIP != "203.0.113.7" {
+		t.Errorf("recorded client IP = %q, want 203.0.113.7", events[1].ClientIP)
+	}
+	if events[0].ClientIP != "127.0.0.1" {
+		t.Errorf("client IP without X-Forwarded-For = %q, want the peer address", events[0].ClientIP)
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	dataDir := t.TempDir()
+	handler := NewHandler(NewFSMetaStore(dataDir), NewFSBlobStore(dataDir), Options{
+		Token:           "test-token",
+		IPRateLimits:    RateLimits{Read: RateLimit{Rate: 0.01, Burst: 4}},
+		TokenRateLimits: RateLimits{Read: RateLimit{Rate: 0.01, Burst: 2}, Publish: RateLimit{Rate: 0.01, Burst: 1}},
+	})
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	writeTestUsers(t, dataDir, "alice", "bob")
+
+	get := func(token string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest("GET", ts.URL+"/v1/skills", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// Each token has its own read budget, and publishing draws on another.
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		if resp := get("alice-token"); resp.StatusCode != want {
+			t.Fatalf("read %d by alice returned %d, want %d", i+1, resp.StatusCode, want)
+		}
+	}
+	publishBundle(t, ts.URL, "alice-token", createAuthoredSkillDir(t, "limited", "1.0.0", "alice"))
+	resp := postBundle(t, ts.URL, "alice-token", createAuthoredSkillDir(t, "limited", "1.1.0", "alice"))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("second publish returned %d, want 429", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Retry-After"); got != "100" {
+		t.Errorf("Retry-After = %q, want 100", got)
+	}
+
+	// The IP budget is shared by every caller from the address: alice's
+	// three reads, including the rejected one, and bob's read use it up.
+	resp = get("bob-token")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("read by bob returned %d, want 200", resp.StatusCode)
+	}
+	if got := resp.Header.Get("RateLimit-Limit") + "/" + resp.Header.Get("RateLimit-Remaining"); got != "4/0" {
+		t.Errorf("RateLimit-Limit/Remaining = %s, want the IP budget 4/0", got)
+	}
+	resp = get("")
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("RateLimit-Reset") == "" {
+		t.Errorf("anonymous read returned %d with RateLimit-Reset %q, want 429 with a reset",
+			resp.StatusCode, resp.Header.Get("RateLimit-Reset"))
+	}
+}
+
+func TestRateLimitsBehindProxy(t *testing.T) {
+	dataDir := t.TempDir()
+	handler := NewHandler(NewFSMetaStore(dataDir), NewFSBlobStore(dataDir), Options{
+		TrustProxy:   true,
+		IPRateLimits: RateLimits{Read: RateLimit{Rate: 0.01, Burst: 2}},
+	})
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	// A client that makes up a new X-Forwarded-For address for every
+	// request still shares the budget of the address the proxy appends.
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		req, _ := http.NewRequest("GET", ts.URL+"/v1/skills", nil)
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d, 203.0.113.7", i))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("read %d returned %d, want %d", i+1, resp.StatusCode, want)
+		}
+	}
+
+	// Other clients of the proxy have their own budget.
+	req, _ := http.NewRequest("GET", ts.URL+"/v1/skills", nil)
+	req.Header.Set("X-Forwarded-For", "203.0.113.8")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("read by another client returned %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestLimiterRefill(t *testing.T) {
+	l := newLimiter(RateLimit{Rate: 2, Burst: 2})
+	start := time.Now()
+	for i, want := range []time.Duration{0, 0, 500 * time.Millisecond} {
+		if st := l.take("k", start); st.wait != want {
+			t.Errorf("take %d wait = %v, want %v", i+1, st.wait, want)
+		}
+	}
+	if st := l.take("k", start.Add(500*time.Millisecond)); st.wait != 0 || st.remaining != 0 {
+		t.Errorf("take after refill = %+v, want allowed with none left", st)
+	}
+	if st := l.take("other", start); st.wait != 0 || st.remaining != 1 {
+		t.Errorf("take on another key = %+v, want its own bucket", st)
+	}
+
+	// Full buckets are dropped by the sweep.
+	l.take("k", start.Add(time.Hour))
+	if _, ok := l.buckets["other"]; ok {
+		t.Error("idle bucket was not swept")
+	}
+}
+
+func TestParseRateLimits(t *testing.T) {
+	got, err := ParseRateLimits("read=20/s, publish=30/m:5")
+	want := RateLimits{Read: RateLimit{Rate: 20, Burst: 20}, Publish: RateLimit{Rate: 0.5, Burst: 5}}
+	if err != nil || got != want {
+		t.Errorf("ParseRateLimits() = %+v, %v, want %+v", got, err, want)
+	}
+	if got, err := ParseRateLimits(""); err != nil || got != (RateLimits{}) {
+		t.Errorf("ParseRateLimits(\"\") = %+v, %v, want no limits", got, err)
+	}
+	for _, bad := range []string{"20/s", "write=1/s", "read=0/s", "read=5/d", "read=5/s:x"} {
+		if _, err := ParseRateLimits(bad); err == nil {
+			t.Errorf("ParseRateLimits(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestQuotas(t *testing.T) {
+	dataDir := t.TempDir()
+	handler := NewHandler(NewFSMetaStore(dataDir), NewFSBlobStore(dataDir), Options{
+		Token:  "test-token",
+		Quotas: Quotas{MaxBundleSize: 64 << 10, MaxVersionsPerSkill: 2},
+	})
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+	writeTestUsers(t, dataDir, "alice", "bob")
+	postJSON(t, "POST", ts.URL+"/v1/orgs", "alice-token", `{"name": "acme"}`).Body.Close()
+
+	expectError := func(resp *http.Response, status int, code string) {
+		t.Helper()
+		defer resp.Body.Close()
+		var body errorResponse
+		json.NewDecoder(resp.Body).Decode(&body)
+		if resp.StatusCode != status || body.Code != code {
+			t.Errorf("got %d %q (%s), want %d %q", resp.StatusCode, body.Code, body.Error, status, code)
+		}
+	}
+
+	// Bundles over the limit are rejected whether or not the request body
+	// itself is cut off.
+	for _, size := range []int{100 << 10, 200 << 10} {
+		dir := createAuthoredSkillDir(t, "big", "1.0.0", "alice")
+		data := make([]byte, size)
+		rand.Read(data)
+		os.WriteFile(filepath.Join(dir, "corpus.bin"), data, 0o644)
+		expectError(postBundle(t, ts.URL, "alice-token", dir), http.StatusRequestEntityTooLarge, codeBundleTooLarge)
+	}
+
+	publishBundle(t, ts.URL, "alice-token", createAuthoredSkillDir(t, "quota", "1.0.0", "alice"))
+	publishBundle(t, ts.URL, "alice-token", createAuthoredSkillDir(t, "quota", "1.1.0", "alice"))
+	expectError(postBundle(t, ts.URL, "alice-token", createAuthoredSkillDir(t, "quota", "1.2.0", "alice")),
+		http.StatusForbidden, codeQuotaExceeded)
+
+	usage := func(token, owner string, want int) usageResponse {
+		t.Helper()
+		req, _ := http.NewRequest("GET", ts.URL+"/v1/users/"+owner+"/usage", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("usage of %s as %s returned %d, want %d", owner, token, resp.StatusCode, want)
+		}
+		var u usageResponse
+		json.NewDecoder(resp.Body).Decode(&u)
+		return u
+	}
+	got := usage("alice-token", "alice", http.StatusOK)
+	if got.Versions != 2 || len(got.Skills) != 1 || got.Skills[0].Bytes != got.Bytes || got.Quotas.MaxVersionsPerSkill != 2 {
+		t.Fatalf("usage of alice = %+v", got)
+	}
+	usage("bob-token", "alice", http.StatusForbidden)
+	usage("test-token", "alice", http.StatusOK)
+
+	// Storage is charged to the owner, and scoped skills to their organization.
+	handler.opts.Quotas.MaxBytesPerOwner = got.Bytes + 100
+	expectError(postBundle(t, ts.URL, "alice-token", createAuthoredSkillDir(t, "another", "1.0.0", "alice")),
+		http.StatusForbidden, codeQuotaExceeded)
+	publishBundle(t, ts.URL, "alice-token", createAuthoredSkillDir(t, "@acme/quota", "1.0.0", "alice"))
+	if org := usage("alice-token", "@acme", http.StatusOK); len(org.Skills) != 1 || org.Skills[0].Name != "@acme/quota" {
+		t.Errorf("usage of @acme = %+v", org)
+	}
+	usage("bob-token", "@acme", http.StatusNotFound)
+	if again := usage("alice-token", "alice", http.StatusOK); again.Bytes != got.Bytes {
+		t.Errorf("usage of alice = %d bytes after publishing to @acme, want %d", again.Bytes, got.Bytes)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	ts, dataDir := setupTestServer(t)
+	defer ts.Close()
+	for _, v := range []string{"1.0.0", "1.1.0-beta.1", "1.1.0-beta.2", "1.1.0-beta.3", "1.1.0-beta.4", "1.1.0-beta.5"} {
+		publishBundle(t, ts.URL, "test-token", createTestSkillDir(t, "pruned", v))
+	}
+	meta, blobs := NewFSMetaStore(dataDir), NewFSBlobStore(dataDir)
+	// All but beta.4 were published two months ago. beta.4 stands for a
+	// version downloaded before download times were recorded: it has no
+	// LastDownloadedAt, so its publish time counts.
+	err := meta.UpdateSkill("pruned", func(m *SkillMeta) error {
+		for i := range m.Versions {
+			if m.Versions[i].Version != "1.1.0-beta.4" {
+				m.Versions[i].PublishedAt = time.Now().Add(-60 * 24 * time.Hour).UTC().Format(time.RFC3339)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	postJSON(t, "PUT", ts.URL+"/v1/skills/pruned/dist-tags/next", "test-token", `{"version": "1.1.0-beta.1"}`).Body.Close()
+	download := func(version string) *http.Response {
+		t.Helper()
+		resp, err := http.Get(ts.URL + "/v1/skills/pruned/versions/" + version + "/download")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	download("1.1.0-beta.2").Body.Close()
+
+	policy := RetentionPolicy{KeepPrereleases: 1, KeepDownloadedWithin: 30 * 24 * time.Hour}
+	versions := func(pruned []PrunedBundle) []string {
+		var got []string
+		for _, p := range pruned {
+			got = append(got, p.Version)
+		}
+		return got
+	}
+
+	// The newest prerelease, the tagged one and the downloaded ones are kept.
+	got, err := Prune(meta, blobs, policy, time.Now(), true)
+	if err != nil || !reflect.DeepEqual(versions(got), []string{"1.1.0-beta.3"}) {
+		t.Fatalf("Prune(dry run) = %v, %v", versions(got), err)
+	}
+	beta3 := getSkillInfo(t, ts.URL, "pruned").Versions[3]
+	if beta3.Version != "1.1.0-beta.3" {
+		t.Fatalf("versions[3] = %s", beta3.Version)
+	}
+	got, err = Prune(meta, blobs, policy, time.Now(), false)
+	if err != nil || !reflect.DeepEqual(versions(got), []string{"1.1.0-beta.3"}) || got[0].SizeBytes == 0 {
+		t.Fatalf("Prune() = %+v, %v", got, err)
+	}
+
+	resp := download("1.1.0-beta.3")
+	var body errorResponse
+	json.NewDecoder(resp.Body).Decode(&body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusGone || body.Code != codeVersionGone {
+		t.Errorf("download of pruned version returned %d %q, want 410 %q", resp.StatusCode, body.Code, codeVersionGone)
+	}
+	if resp := download("1.1.0-beta.9"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("download of unknown version returned %d, want 404", resp.StatusCode)
+	}
+	if resp := postJSON(t, "POST", ts.URL+"/v1/skills/pruned/versions/1.1.0-beta.3/yank", "test-token", `{}`); resp.StatusCode != http.StatusGone {
+		t.Errorf("yank of pruned version returned %d, want 410", resp.StatusCode)
+	}
+	if resp := postBundle(t, ts.URL, "test-token", createTestSkillDir(t, "pruned", "1.1.0-beta.3")); resp.StatusCode != http.StatusConflict {
+		t.Errorf("republish of pruned version returned %d, want 409", resp.StatusCode)
+	}
+	if res, err := CollectGarbage(meta, blobs, 0, time.Now().Add(time.Second), false); err != nil || !reflect.DeepEqual(res.Removed, []string{beta3.Checksum}) {
+		t.Errorf("CollectGarbage() after pruning = %+v, %v", res, err)
+	}
+	if info := getSkillInfo(t, ts.URL, "pruned"); len(info.Versions) != 5 {
+		t.Errorf("skill has %d versions after pruning, want 5", len(info.Versions))
+	}
+	events, _ := meta.ListAudit(AuditFilter{Actor: RetentionActor})
+	if len(events) != 1 || events[0].Action != AuditPrune || events[0].Version != "1.1.0-beta.3" {
+		t.Errorf("audit events of the retention policy = %+v", events)
+	}
+
+	// Once the downloads are old enough, those versions go too.
+	got, err = Prune(meta, blobs, policy, time.Now().Add(31*24*time.Hour), false, "pruned")
+	if err != nil || !reflect.DeepEqual(versions(got), []string{"1.1.0-beta.2", "1.1.0-beta.4"}) {
+		t.Errorf("Prune(later) = %v, %v", versions(got), err)
+	}
+	if _, err := Prune(meta, blobs, policy, time.Now(), true, "missing"); err == nil {
+		t.Error("Prune(missing skill) should fail")
+	}
+}
+
+func TestCollectGarbageUnreadableSkill(t *testing.T) {
+	ts, dataDir := setupTestServer(t)
+	defer ts.Close()
+	meta, blobs := NewFSMetaStore(dataDir), NewFSBlobStore(dataDir)
+	publishBundle(t, ts.URL, "test-token", createTestSkillDir(t, "readable", "1.0.0"))
+	publishBundle(t, ts.URL, "test-token", createTestSkillDir(t, "corrupt", "1.0.0"))
+	if err := os.WriteFile(filepath.Join(dataDir, "bundles", "corrupt", "meta.json"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The corrupt skill's blob would look unreferenced; nothing is swept.
+	res, err := CollectGarbage(meta, blobs, 0, time.Now().Add(time.Second), false)
+	if err == nil || len(res.Removed) != 0 {
+		t.Fatalf("CollectGarbage() = %+v, %v, want an error and nothing removed", res, err)
+	}
+	n := 0
+	blobs.ListBlobs(func(string, time.Time) error {
+		n++
+		return nil
+	})
+	if n != 2 {
+		t.Errorf("%d blobs left, want 2", n)
+	}
+}
+
+func TestCollectGarbage(t *testing.T) {
+	ts, dataDir := setupTestServer(t)
+	defer ts.Close()
+	meta, blobs := NewFSMetaStore(dataDir), NewFSBlobStore(dataDir)
+	publishBundle(t, ts.URL, "test-token", createTestSkillDir(t, "collected", "1.0.0"))
+	publishBundle(t, ts.URL, "test-token", createTestSkillDir(t, "collected", "1.1.0"))
+
+	// Two versions whose identical bundles were stored before blobs were
+	// content-addressed.
+	legacy := []byte("legacy bundle")
+	legacyDigest := fmt.Sprintf("sha256:%x", sha256.Sum256(legacy))
+	for _, v := range []string{"1.0.0", "1.0.1"} {
+		dir := filepath.Join(dataDir, "bundles", "legacy", v)
+		os.MkdirAll(dir, 0o755)
+		os.WriteFile(filepath.Join(dir, "bundle.tar.gz"), legacy, 0o644)
+		vm := VersionMeta{Version: v, Checksum: legacyDigest, SizeBytes: int64(len(legacy)), PublishedAt: time.Now().UTC().Format(time.RFC3339)}
+		meta.PutVersion("legacy", "tester", "desc", nil, vm, false, nil)
+	}
+	download := func(name, version string) []byte {
+		t.Helper()
+		resp, err := http.Get(ts.URL + "/v1/skills/" + name + "/versions/" + version + "/download")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("download of %s@%s returned %d", name, version, resp.StatusCode)
+		}
+		data, _ := io.ReadAll(resp.Body)
+		return data
+	}
+	if got := download("legacy", "1.0.1"); !bytes.Equal(got, legacy) {
+		t.Fatalf("download of legacy bundle = %q", got)
+	}
+
+	// An unreferenced blob is only removed once it is older than the grace
+	// period, as it may belong to a publish in progress.
+	orphan := func(data string, age time.Duration) string {
+		digest := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(data)))
+		blobs.PutBlob(digest, strings.NewReader(data))
+		path, _ := blobs.blobPath(digest)
+		old := time.Now().Add(-age)
+		os.Chtimes(path, old, old)
+		return digest
+	}
+	stale, fresh := orphan("stale", 2*time.Hour), orphan("fresh", time.Minute)
+
+	res, err := CollectGarbage(meta, blobs, time.Hour, time.Now(), true)
+	if err != nil || res.Migrated != 2 || res.Referenced != 3 || !reflect.DeepEqual(res.Removed, []string{stale}) {
+		t.Fatalf("CollectGarbage(dry run) = %+v, %v", res, err)
+	}
+	if _, err := blobs.OpenBlob(stale); err != nil {
+		t.Fatalf("dry run removed a blob: %v", err)
+	}
+
+	res, err = CollectGarbage(meta, blobs, time.Hour, time.Now(), false)
+	if err != nil || res.Migrated != 2 || res.Blobs != 5 || !reflect.DeepEqual(res.Removed, []string{stale}) {
+		t.Fatalf("CollectGarbage() = %+v, %v", res, err)
+	}
+	if _, err := blobs.OpenBlob(stale); err != ErrNotFound {
+		t.Errorf("OpenBlob(stale) error = %v, want ErrNotFound", err)
+	}
+	if _, err := blobs.OpenBlob(fresh); err != nil {
+		t.Errorf("OpenBlob(fresh) error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "bundles", "legacy", "1.0.0")); !os.IsNotExist(err) {
+		t.Error("migrated legacy bundle was not removed")
+	}
+	for _, v := range []string{"1.0.0", "1.0.1"} {
+		if got := download("legacy", v); !bytes.Equal(got, legacy) {
+			t.Errorf("download of migrated legacy@%s = %q", v, got)
+		}
+	}
+	download("collected", "1.1.0")
+
+	// A second run finds nothing left to do.
+	res, err = CollectGarbage(meta, blobs, time.Hour, time.Now(), false)
+	if err != nil || res.Migrated != 0 || len(res.Removed) != 0 {
+		t.Errorf("second CollectGarbage() = %+v, %v", res, err)
+	}
+
+	// A corrupt legacy bundle is left alone.
+	os.MkdirAll(filepath.Join(dataDir, "bundles", "legacy", "2.0.0"), 0o755)
+	os.WriteFile(filepath.Join(dataDir, "bundles", "legacy", "2.0.0", "bundle.tar.gz"), []byte("corrupt"), 0o644)
+	meta.PutVersion("legacy", "tester", "desc", nil, VersionMeta{Version: "2.0.0", Checksum: stale, PublishedAt: time.Now().UTC().Format(time.RFC3339)}, false, nil)
+	if _, err := CollectGarbage(meta, blobs, time.Hour, time.Now(), false); err == nil {
+		t.Error("CollectGarbage() should fail on a bundle that does not match its checksum")
+	}
+}
@@ -0,0 +1,442 @@
+//go:build server
+
+package server
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// migration is one forward-only schema change, written once per dialect.
+type migration struct {
+	version  int
+	name     string
+	sqlite   string
+	postgres string
+
+	// after, if set, runs in the same transaction once the schema change
+	// is applied, for data migrations that cannot be written in SQL.
+	after func(s *SQLMetaStore, tx *sql.Tx) error
+}
+
+// migrations are applied in order and recorded in schema_migrations.
+// Version 1 mirrors init.sql and uses IF NOT EXISTS so that databases
+// already initialized by docker-compose are adopted as-is.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "init.sql schema",
+		sqlite: `
+CREATE TABLE IF NOT EXISTS users (
+    id          TEXT PRIMARY KEY,
+    username    TEXT UNIQUE NOT NULL,
+    api_token   TEXT UNIQUE,
+    created_at  TIMESTAMP
+);
+CREATE TABLE IF NOT EXISTS skills (
+    id          TEXT PRIMARY KEY,
+    name        TEXT UNIQUE NOT NULL,
+    owner_id    TEXT NOT NULL REFERENCES users(id),
+    downloads   INTEGER DEFAULT 0,
+    created_at  TIMESTAMP,
+    updated_at  TIMESTAMP
+);
+CREATE TABLE IF NOT EXISTS skill_versions (
+    id            TEXT PRIMARY KEY,
+    skill_id      TEXT NOT NULL REFERENCES skills(id) ON DELETE CASCADE,
+    version       TEXT NOT NULL,
+    bundle_key    TEXT NOT NULL,
+    metadata      TEXT NOT NULL,
+    checksum      TEXT NOT NULL,
+    size_bytes    INTEGER NOT NULL,
+    published_at  TIMESTAMP,
+    CONSTRAINT uq_skill_version UNIQUE (skill_id, version)
+);
+CREATE INDEX IF NOT EXISTS idx_skill_versions_latest ON skill_versions (skill_id, published_at DESC);
+CREATE INDEX IF NOT EXISTS idx_skills_name ON skills (name);
+`,
+		postgres: `
+CREATE TABLE IF NOT EXISTS users (
+    id          UUID PRIMARY KEY,
+    username    VARCHAR(64)  UNIQUE NOT NULL,
+    api_token   VARCHAR(128) UNIQUE NOT NULL,
+    created_at  TIMESTAMPTZ  DEFAULT now()
+);
+CREATE TABLE IF NOT EXISTS skills (
+    id          UUID PRIMARY KEY,
+    name        VARCHAR(128) UNIQUE NOT NULL,
+    owner_id    UUID NOT NULL REFERENCES users(id),
+    downloads   BIGINT DEFAULT 0,
+    created_at  TIMESTAMPTZ DEFAULT now(),
+    updated_at  TIMESTAMPTZ DEFAULT now()
+);
+CREATE TABLE IF NOT EXISTS skill_versions (
+    id            UUID PRIMARY KEY,
+    skill_id      UUID NOT NULL REFERENCES skills(id) ON DELETE CASCADE,
+    version       VARCHAR(32) NOT NULL,
+    bundle_key    TEXT NOT NULL,
+    metadata      JSONB NOT NULL,
+    checksum      VARCHAR(64) NOT NULL,
+    size_bytes    BIGINT NOT NULL,
+    published_at  TIMESTAMPTZ DEFAULT now(),
+    CONSTRAINT uq_skill_version UNIQUE (skill_id, version)
+);
+CREATE INDEX IF NOT EXISTS idx_skill_versions_latest ON skill_versions (skill_id, published_at DESC);
+CREATE INDEX IF NOT EXISTS idx_skills_name ON skills (name);
+`,
+	},
+	{
+		// Skill owners are created on first publish, before they have a token
+		// (SQLite cannot drop NOT NULL, so its version 1 starts nullable).
+		// Description and tags back search without decoding every version.
+		version: 2,
+		name:    "skill description, tags and version ordering",
+		sqlite: `
+ALTER TABLE skills ADD COLUMN description TEXT NOT NULL DEFAULT '';
+ALTER TABLE skills ADD COLUMN tags TEXT NOT NULL DEFAULT '[]';
+ALTER TABLE skill_versions ADD COLUMN description TEXT NOT NULL DEFAULT '';
+`,
+		postgres: `
+ALTER TABLE users ALTER COLUMN api_token DROP NOT NULL;
+ALTER TABLE skills ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+ALTER TABLE skills ADD COLUMN IF NOT EXISTS tags TEXT NOT NULL DEFAULT '[]';
+ALTER TABLE skill_versions ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+ALTER TABLE skill_versions ADD COLUMN IF NOT EXISTS seq BIGSERIAL;
+`,
+	},
+	{
+		version: 3,
+		name:    "yank and deprecate",
+		sqlite: `
+ALTER TABLE skills ADD COLUMN deprecated TEXT NOT NULL DEFAULT '';
+ALTER TABLE skill_versions ADD COLUMN yanked BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE skill_versions ADD COLUMN yank_reason TEXT NOT NULL DEFAULT '';
+`,
+		postgres: `
+ALTER TABLE skills ADD COLUMN IF NOT EXISTS deprecated TEXT NOT NULL DEFAULT '';
+ALTER TABLE skill_versions ADD COLUMN IF NOT EXISTS yanked BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE skill_versions ADD COLUMN IF NOT EXISTS yank_reason TEXT NOT NULL DEFAULT '';
+`,
+	},
+	{
+		version: 4,
+		name:    "dist-tags",
+		sqlite: `
+ALTER TABLE skills ADD COLUMN dist_tags TEXT NOT NULL DEFAULT '{}';
+`,
+		postgres: `
+ALTER TABLE skills ADD COLUMN IF NOT EXISTS dist_tags TEXT NOT NULL DEFAULT '{}';
+`,
+	},
+	{
+		version: 5,
+		name:    "skill collaborators",
+		sqlite: `
+CREATE TABLE skill_collaborators (
+    skill_id  TEXT NOT NULL REFERENCES skills(id) ON DELETE CASCADE,
+    user_id   TEXT NOT NULL REFERENCES users(id),
+    PRIMARY KEY (skill_id, user_id)
+);
+`,
+		postgres: `
+CREATE TABLE IF NOT EXISTS skill_collaborators (
+    skill_id  UUID NOT NULL REFERENCES skills(id) ON DELETE CASCADE,
+    user_id   UUID NOT NULL REFERENCES users(id),
+    PRIMARY KEY (skill_id, user_id)
+);
+`,
+	},
+	{
+		// Tokens move out of users.api_token into their own table and are
+		// stored hashed; after converts tokens seeded by init.sql.
+		version: 6,
+		name:    "hashed API tokens",
+		sqlite: `
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE TABLE api_tokens (
+    id            TEXT PRIMARY KEY,
+    user_id       TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name          TEXT NOT NULL,
+    token_hash    TEXT UNIQUE NOT NULL,
+    created_at    TIMESTAMP NOT NULL,
+    last_used_at  TIMESTAMP,
+    expires_at    TIMESTAMP
+);
+CREATE INDEX idx_api_tokens_user ON api_tokens (user_id);
+`,
+		postgres: `
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id            UUID PRIMARY KEY,
+    user_id       UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name          VARCHAR(64) NOT NULL,
+    token_hash    VARCHAR(64) UNIQUE NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL,
+    last_used_at  TIMESTAMPTZ,
+    expires_at    TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_api_tokens_user ON api_tokens (user_id);
+`,
+		after: hashLegacyTokens,
+	},
+	{
+		version: 7,
+		name:    "token scopes",
+		sqlite: `
+ALTER TABLE api_tokens ADD COLUMN scopes TEXT NOT NULL DEFAULT '[]';
+`,
+		postgres: `
+ALTER TABLE api_tokens ADD COLUMN IF NOT EXISTS scopes TEXT NOT NULL DEFAULT '[]';
+`,
+	},
+	{
+		version: 8,
+		name:    "skill visibility",
+		sqlite: `
+ALTER TABLE skills ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public';
+`,
+		postgres: `
+ALTER TABLE skills ADD COLUMN IF NOT EXISTS visibility TEXT NOT NULL DEFAULT 'public';
+`,
+	},
+	{
+		version: 9,
+		name:    "organizations",
+		sqlite: `
+CREATE TABLE orgs (
+    id          TEXT PRIMARY KEY,
+    name        TEXT UNIQUE NOT NULL,
+    created_at  TIMESTAMP NOT NULL
+);
+CREATE TABLE org_members (
+    org_id   TEXT NOT NULL REFERENCES orgs(id) ON DELETE CASCADE,
+    user_id  TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role     TEXT NOT NULL,
+    PRIMARY KEY (org_id, user_id)
+);
+CREATE INDEX idx_org_members_user ON org_members (user_id);
+`,
+		postgres: `
+CREATE TABLE IF NOT EXISTS orgs (
+    id          UUID PRIMARY KEY,
+    name        VARCHAR(32) UNIQUE NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL
+);
+CREATE TABLE IF NOT EXISTS org_members (
+    org_id   UUID NOT NULL REFERENCES orgs(id) ON DELETE CASCADE,
+    user_id  UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role     VARCHAR(16) NOT NULL,
+    PRIMARY KEY (org_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_org_members_user ON org_members (user_id);
+`,
+	},
+	{
+		// owner_id keeps the last user owner while an organization owns
+		// the skill.
+		version: 10,
+		name:    "organization owners and owner history",
+		sqlite: `
+ALTER TABLE skills ADD COLUMN owner_org_id TEXT REFERENCES orgs(id);
+CREATE TABLE skill_owner_events (
+    skill_id    TEXT NOT NULL REFERENCES skills(id) ON DELETE CASCADE,
+    seq         INTEGER NOT NULL,
+    action      TEXT NOT NULL,
+    username    TEXT NOT NULL,
+    previous    TEXT NOT NULL DEFAULT '',
+    actor       TEXT NOT NULL,
+    created_at  TIMESTAMP NOT NULL,
+    PRIMARY KEY (skill_id, seq)
+);
+`,
+		postgres: `
+ALTER TABLE skills ADD COLUMN IF NOT EXISTS owner_org_id UUID REFERENCES orgs(id);
+CREATE TABLE IF NOT EXISTS skill_owner_events (
+    skill_id    UUID NOT NULL REFERENCES skills(id) ON DELETE CASCADE,
+    seq         INTEGER NOT NULL,
+    action      VARCHAR(32) NOT NULL,
+    username    VARCHAR(64) NOT NULL,
+    previous    VARCHAR(64) NOT NULL DEFAULT '',
+    actor       VARCHAR(64) NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (skill_id, seq)
+);
+`,
+	},
+	{
+		version: 11,
+		name:    "audit log",
+		sqlite: `
+CREATE TABLE audit_events (
+    seq         INTEGER PRIMARY KEY AUTOINCREMENT,
+    id          TEXT NOT NULL UNIQUE,
+    created_at  TIMESTAMP NOT NULL,
+    actor       TEXT NOT NULL,
+    token_id    TEXT NOT NULL DEFAULT '',
+    action      TEXT NOT NULL,
+    target      TEXT NOT NULL,
+    version     TEXT NOT NULL DEFAULT '',
+    checksum    TEXT NOT NULL DEFAULT '',
+    detail      TEXT NOT NULL DEFAULT '',
+    client_ip   TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX idx_audit_events_target ON audit_events(target);
+CREATE INDEX idx_audit_events_actor ON audit_events(actor);
+`,
+		postgres: `
+CREATE TABLE IF NOT EXISTS audit_events (
+    seq         BIGSERIAL PRIMARY KEY,
+    id          UUID NOT NULL UNIQUE,
+    created_at  TIMESTAMPTZ NOT NULL,
+    actor       VARCHAR(64) NOT NULL,
+    token_id    VARCHAR(64) NOT NULL DEFAULT '',
+    action      VARCHAR(32) NOT NULL,
+    target      VARCHAR(128) NOT NULL,
+    version     VARCHAR(64) NOT NULL DEFAULT '',
+    checksum    VARCHAR(128) NOT NULL DEFAULT '',
+    detail      TEXT NOT NULL DEFAULT '',
+    client_ip   VARCHAR(64) NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_audit_events_target ON audit_events(target);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor ON audit_events(actor);
+`,
+	},
+	{
+		// Pruned versions lose their skill_versions row but keep a
+		// pruned_versions row, so that lookups can tell them apart from
+		// versions that never existed.
+		version: 12,
+		name:    "retention",
+		sqlite: `
+ALTER TABLE skill_versions ADD COLUMN last_downloaded_at TIMESTAMP;
+CREATE TABLE pruned_versions (
+    skill_id    TEXT NOT NULL REFERENCES skills(id) ON DELETE CASCADE,
+    version     TEXT NOT NULL,
+    checksum    TEXT NOT NULL,
+    reason      TEXT NOT NULL DEFAULT '',
+    pruned_at   TIMESTAMP NOT NULL,
+    PRIMARY KEY (skill_id, version)
+);
+`,
+		postgres: `
+ALTER TABLE skill_versions ADD COLUMN IF NOT EXISTS last_downloaded_at TIMESTAMPTZ;
+CREATE TABLE IF NOT EXISTS pruned_versions (
+    skill_id    UUID NOT NULL REFERENCES skills(id) ON DELETE CASCADE,
+    version     VARCHAR(32) NOT NULL,
+    checksum    VARCHAR(64) NOT NULL,
+    reason      TEXT NOT NULL DEFAULT '',
+    pruned_at   TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (skill_id, version)
+);
+`,
+	},
+	{
+		// Skill owners and collaborators need a users row, but only rows
+		// created by CreateUser are accounts. Existing rows count as
+		// accounts if they belong to an admin or hold a token; a user who
+		// revoked every token can be registered again with CreateUser,
+		// which keeps their skills.
+		version: 13,
+		name:    "registered users",
+		sqlite: `
+ALTER TABLE users ADD COLUMN registered BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE users SET registered = TRUE
+ WHERE is_admin OR EXISTS (SELECT 1 FROM api_tokens t WHERE t.user_id = users.id);
+`,
+		postgres: `
+ALTER TABLE users ADD COLUMN IF NOT EXISTS registered BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE users SET registered = TRUE
+ WHERE is_admin OR EXISTS (SELECT 1 FROM api_tokens t WHERE t.user_id = users.id);
+`,
+	},
+	{
+		// Semver prerelease and build metadata have no length limit.
+		// SQLite does not enforce VARCHAR lengths.
+		version: 14,
+		name:    "unbounded versions",
+		sqlite:  `SELECT 1;`,
+		postgres: `
+ALTER TABLE skill_versions ALTER COLUMN version TYPE TEXT;
+ALTER TABLE pruned_versions ALTER COLUMN version TYPE TEXT;
+ALTER TABLE audit_events ALTER COLUMN version TYPE TEXT;
+`,
+	},
+}
+
+// hashLegacyTokens moves clear-text users.api_token values into api_tokens.
+func hashLegacyTokens(s *SQLMetaStore, tx *sql.Tx) error {
+	rows, err := s.query(tx, `SELECT id, api_token FROM users WHERE api_token IS NOT NULL AND api_token <> ''`)
+	if err != nil {
+		return fmt.Errorf("reading legacy tokens: %w", err)
+	}
+	type legacy struct{ userID, token string }
+	var tokens []legacy
+	for rows.Next() {
+		var l legacy
+		if err := rows.Scan(&l.userID, &l.token); err != nil {
+			rows.Close()
+			return fmt.Errorf("scanning legacy token: %w", err)
+		}
+		tokens = append(tokens, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("reading legacy tokens: %w", err)
+	}
+
+	now := time.Now().UTC()
+	for _, l := range tokens {
+		if _, err := s.exec(tx, `INSERT INTO api_tokens (id, user_id, name, token_hash, created_at) VALUES (?, ?, ?, ?, ?)`,
+			newID(), l.userID, "legacy", HashToken(l.token), now); err != nil {
+			return fmt.Errorf("hashing legacy token: %w", err)
+		}
+	}
+	if _, err := s.exec(tx, `UPDATE users SET api_token = NULL`); err != nil {
+		return fmt.Errorf("clearing legacy tokens: %w", err)
+	}
+	return nil
+}
+
+// migrate applies every migration newer than the recorded schema version.
+func (s *SQLMetaStore) migrate() error {
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+    version     INTEGER PRIMARY KEY,
+    name        TEXT NOT NULL,
+    applied_at  TIMESTAMP NOT NULL
+)`); err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+
+	var current int
+	if err := s.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("reading schema version: %w", err)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		stmt := m.sqlite
+		if s.dialect == dialectPostgres {
+			stmt = m.postgres
+		}
+		err := s.inTx(func(tx *sql.Tx) error {
+			if _, err := tx.Exec(stmt); err != nil {
+				return err
+			}
+			if m.after != nil {
+				if err := m.after(s, tx); err != nil {
+					return err
+				}
+			}
+			_, err := s.exec(tx, `INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+				m.version, m.name, time.Now().UTC())
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("applying migration %d (%s): %w", m.version, m.name, err)
+		}
+	}
+	return nil
+}
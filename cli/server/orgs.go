@@ -0,0 +1,95 @@
+//go:build server
+
+package server
+
+import (
+	"errors"
+	"time"
+
+	"github.com/liuyukai/agentskills-cli/internal/parser"
+)
+
+// ErrOrgExists is returned by MetaStore.CreateOrg when the name is taken.
+var ErrOrgExists = errors.New("organization already exists")
+
+// Organization roles. Owners manage the member list; every member may
+// publish and manage the skills in the organization's scope.
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleMember = "member"
+)
+
+// Org is an organization. It owns the scope of the same name: skills
+// named "@<org>/<name>" can only be published by its members.
+type Org struct {
+	Name      string      `json:"name"`
+	CreatedAt time.Time   `json:"created_at"`
+	Members   []OrgMember `json:"members"`
+}
+
+// OrgMember is a user's membership in an organization.
+type OrgMember struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// Role returns the role of user in the organization, or "" if user is
+// not a member.
+func (o *Org) Role(user string) string {
+	for _, m := range o.Members {
+		if m.Username == user {
+			return m.Role
+		}
+	}
+	return ""
+}
+
+// setMember adds user with role, or changes the role of an existing member.
+func (o *Org) setMember(user, role string) {
+	for i, m := range o.Members {
+		if m.Username == user {
+			o.Members[i].Role = role
+			return
+		}
+	}
+	o.Members = append(o.Members, OrgMember{Username: user, Role: role})
+}
+
+// removeMember removes user and reports whether they were a member.
+func (o *Org) removeMember(user string) bool {
+	for i, m := range o.Members {
+		if m.Username == user {
+			o.Members = append(o.Members[:i], o.Members[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// owners counts the members with the owner role.
+func (o *Org) owners() int {
+	n := 0
+	for _, m := range o.Members {
+		if m.Role == OrgRoleOwner {
+			n++
+		}
+	}
+	return n
+}
+
+// validOrgRole reports whether role is a known organization role.
+func validOrgRole(role string) bool {
+	return role == OrgRoleOwner || role == OrgRoleMember
+}
+
+// ValidateOrgName checks that name can be used as an organization, and
+// hence as the scope of skill names.
+func ValidateOrgName(name string) error {
+	return parser.ValidateScope(name)
+}
+
+// skillScope returns the organization a skill name is scoped to, or "".
+func skillScope(name string) string {
+	scope, _ := parser.SplitName(name)
+	return scope
+}
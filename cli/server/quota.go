@@ -0,0 +1,74 @@
+//go:build server
+
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Quotas limit the storage used by publishers. Zero fields are unlimited.
+type Quotas struct {
+	// MaxBundleSize is the largest bundle that may be uploaded, in bytes.
+	MaxBundleSize int64
+	// MaxVersionsPerSkill caps the stored versions of a skill, including
+	// yanked ones.
+	MaxVersionsPerSkill int
+	// MaxBytesPerOwner caps the bundle bytes charged to a user or an
+	// organization; see quotaOwner.
+	MaxBytesPerOwner int64
+}
+
+// SkillUsage is the storage used by the versions of one skill.
+type SkillUsage struct {
+	Name     string `json:"name"`
+	Versions int    `json:"versions"`
+	Bytes    int64  `json:"bytes"`
+}
+
+// quotaOwner returns who is charged for the storage of a skill: the
+// organization its name is scoped to, or else its owner, which is a
+// username or "@<org>".
+func quotaOwner(name, owner string) string {
+	if scope := skillScope(name); scope != "" {
+		return "@" + scope
+	}
+	return owner
+}
+
+// ParseByteSize parses a size such as "50MB", "1.5GB" or "1048576".
+// Units are powers of 1024; a plain number is in bytes.
+func ParseByteSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		size   float64
+	}{
+		{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1},
+	}
+	number, size := strings.ToUpper(strings.TrimSpace(s)), 1.0
+	for _, u := range units {
+		if n, ok := strings.CutSuffix(number, u.suffix); ok {
+			number, size = strings.TrimSpace(n), u.size
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q: want e.g. 50MB, 1.5GB or a number of bytes", s)
+	}
+	return int64(n * size), nil
+}
+
+// formatByteSize renders a byte count in the largest fitting unit.
+func formatByteSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
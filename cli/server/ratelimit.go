@@ -0,0 +1,232 @@
+//go:build server
+
+package server
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit is a token-bucket budget: a client may send Burst requests at
+// once and regains Rate requests per second after that. The zero value is
+// unlimited.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimits are the budgets of one kind of client. Read covers GET and
+// HEAD requests; Publish covers every request that changes the registry.
+type RateLimits struct {
+	Read    RateLimit
+	Publish RateLimit
+}
+
+// ParseRateLimits parses limits such as "read=20/s,publish=30/m:5". Each
+// limit is <count>/<s|m|h>, optionally followed by :<burst>, which
+// defaults to count. An empty string means no limits.
+func ParseRateLimits(s string) (RateLimits, error) {
+	var limits RateLimits
+	if s == "" {
+		return limits, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		kind, spec, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return limits, fmt.Errorf("invalid rate limit %q: want read=<limit> or publish=<limit>", part)
+		}
+		limit, err := parseRateLimit(spec)
+		if err != nil {
+			return limits, err
+		}
+		switch kind {
+		case "read":
+			limits.Read = limit
+		case "publish":
+			limits.Publish = limit
+		default:
+			return limits, fmt.Errorf("invalid rate limit %q: unknown budget %q (want read or publish)", part, kind)
+		}
+	}
+	return limits, nil
+}
+
+func parseRateLimit(spec string) (RateLimit, error) {
+	invalid := fmt.Errorf("invalid rate limit %q: want e.g. 20/s, 600/m or 100/h:10", spec)
+	rate, burst, hasBurst := strings.Cut(spec, ":")
+	count, unit, ok := strings.Cut(rate, "/")
+	n, err := strconv.Atoi(count)
+	if !ok || err != nil || n <= 0 {
+		return RateLimit{}, invalid
+	}
+	limit := RateLimit{Burst: n}
+	switch unit {
+	case "s":
+		limit.Rate = float64(n)
+	case "m":
+		limit.Rate = float64(n) / 60
+	case "h":
+		limit.Rate = float64(n) / 3600
+	default:
+		return RateLimit{}, invalid
+	}
+	if hasBurst {
+		if limit.Burst, err = strconv.Atoi(burst); err != nil || limit.Burst <= 0 {
+			return RateLimit{}, invalid
+		}
+	}
+	return limit, nil
+}
+
+// limiterSweepInterval is how often idle buckets are dropped.
+const limiterSweepInterval = time.Minute
+
+// limiter keeps one token bucket per client key.
+type limiter struct {
+	limit RateLimit
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// newLimiter returns a limiter for limit, or nil if limit is unlimited.
+func newLimiter(limit RateLimit) *limiter {
+	if limit.Rate <= 0 || limit.Burst <= 0 {
+		return nil
+	}
+	return &limiter{limit: limit, buckets: make(map[string]*bucket)}
+}
+
+// limitState is a bucket after a request, as reported in RateLimit headers.
+type limitState struct {
+	limit     int
+	remaining int
+	reset     time.Duration // until the bucket is full again
+	wait      time.Duration // until the next request is allowed; 0 if this one was
+}
+
+// take spends one request from the bucket of key at now.
+func (l *limiter) take(key string, now time.Time) limitState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.lastSweep) >= limiterSweepInterval {
+		l.sweepLocked(now)
+	}
+
+	b := l.buckets[key]
+	if b == nil {
+		b = &bucket{tokens: float64(l.limit.Burst), updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = l.refill(b, now)
+	b.updated = now
+
+	st := limitState{limit: l.limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+	} else {
+		st.wait = l.duration(1 - b.tokens)
+	}
+	st.remaining = int(b.tokens)
+	st.reset = l.duration(float64(l.limit.Burst) - b.tokens)
+	return st
+}
+
+// refill returns the tokens in b at now.
+func (l *limiter) refill(b *bucket, now time.Time) float64 {
+	tokens := b.tokens + now.Sub(b.updated).Seconds()*l.limit.Rate
+	return math.Min(tokens, float64(l.limit.Burst))
+}
+
+// duration returns how long it takes to regain tokens.
+func (l *limiter) duration(tokens float64) time.Duration {
+	return time.Duration(tokens / l.limit.Rate * float64(time.Second))
+}
+
+// sweepLocked drops the buckets that have refilled completely, which
+// behave exactly like missing ones.
+func (l *limiter) sweepLocked(now time.Time) {
+	for key, b := range l.buckets {
+		if l.refill(b, now) >= float64(l.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// rateLimiters holds the limiters configured by Options.
+type rateLimiters struct {
+	ipRead, ipPublish       *limiter
+	tokenRead, tokenPublish *limiter
+}
+
+func newRateLimiters(opts Options) rateLimiters {
+	return rateLimiters{
+		ipRead:       newLimiter(opts.IPRateLimits.Read),
+		ipPublish:    newLimiter(opts.IPRateLimits.Publish),
+		tokenRead:    newLimiter(opts.TokenRateLimits.Read),
+		tokenPublish: newLimiter(opts.TokenRateLimits.Publish),
+	}
+}
+
+// rateLimited charges each request to the bucket of its client IP and, if
+// it carries a token, to the bucket of that token, before passing it to
+// next. Requests over either budget get 429 with Retry-After. The
+// RateLimit-* headers describe whichever bucket has the fewest requests
+// left.
+func (h *Handler) rateLimited(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ipLimiter, tokenLimiter := h.limits.ipRead, h.limits.tokenRead
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			ipLimiter, tokenLimiter = h.limits.ipPublish, h.limits.tokenPublish
+		}
+
+		now := time.Now()
+		var tightest *limitState
+		check := func(l *limiter, key string) bool {
+			if l == nil {
+				return true
+			}
+			st := l.take(key, now)
+			if tightest == nil || st.wait > 0 || st.remaining < tightest.remaining {
+				tightest = &st
+			}
+			return st.wait == 0
+		}
+		allowed := check(ipLimiter, h.clientIP(r))
+		if token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); allowed && token != "" {
+			// Keyed by hash so that secrets are not kept in memory.
+			allowed = check(tokenLimiter, HashToken(token))
+		}
+
+		if tightest != nil {
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(tightest.limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(tightest.remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(tightest.reset)))
+		}
+		if !allowed {
+			retry := ceilSeconds(tightest.wait)
+			w.Header().Set("Retry-After", strconv.Itoa(retry))
+			writeError(w, http.StatusTooManyRequests, codeRateLimited,
+				fmt.Sprintf("rate limit exceeded; retry in %ds", retry))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ceilSeconds rounds d up to whole seconds, as used in HTTP headers.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
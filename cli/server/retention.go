@@ -0,0 +1,160 @@
+//go:build server
+
+package server
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/liuyukai/agentskills-cli/internal/semver"
+)
+
+// RetentionPolicy decides which versions of a skill are pruned. Stable
+// versions, versions a dist-tag points at and versions downloaded within
+// KeepDownloadedWithin are always kept; of the remaining prereleases, the
+// KeepPrereleases highest are kept. Versions downloaded only before
+// download times were recorded have no LastDownloadedAt; they count as
+// downloaded when they were published.
+type RetentionPolicy struct {
+	KeepPrereleases      int
+	KeepDownloadedWithin time.Duration
+}
+
+// Expired returns the versions of m that the policy removes at now, in
+// semver order.
+func (p RetentionPolicy) Expired(m *SkillMeta, now time.Time) []VersionMeta {
+	tagged := make(map[string]bool, len(m.DistTags))
+	for _, version := range m.DistTags {
+		tagged[version] = true
+	}
+	cutoff := now.Add(-p.KeepDownloadedWithin)
+
+	// m.Versions is in ascending order, so walk it backwards to count the
+	// highest prereleases first.
+	var expired []VersionMeta
+	prereleases := 0
+	for i := len(m.Versions) - 1; i >= 0; i-- {
+		vm := m.Versions[i]
+		v, err := semver.Parse(vm.Version)
+		if err != nil || !v.IsPrerelease() {
+			continue
+		}
+		prereleases++
+		if prereleases <= p.KeepPrereleases || tagged[vm.Version] {
+			continue
+		}
+		if used, ok := lastUsed(vm); !ok || used.After(cutoff) {
+			continue
+		}
+		expired = append([]VersionMeta{vm}, expired...)
+	}
+	return expired
+}
+
+// lastUsed returns when vm was last downloaded, or else published. It
+// reports false if neither is known.
+func lastUsed(vm VersionMeta) (time.Time, bool) {
+	if vm.LastDownloadedAt != nil {
+		return *vm.LastDownloadedAt, true
+	}
+	published, err := time.Parse(time.RFC3339, vm.PublishedAt)
+	return published, err == nil
+}
+
+// reason describes why the policy removes a version.
+func (p RetentionPolicy) reason() string {
+	return fmt.Sprintf("not among the %d newest prereleases and not downloaded in %s",
+		p.KeepPrereleases, formatDays(p.KeepDownloadedWithin))
+}
+
+// formatDays renders a duration in whole days where it is one.
+func formatDays(d time.Duration) string {
+	if d > 0 && d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	return d.String()
+}
+
+// PrunedBundle is a version removed, or to be removed, by Prune.
+type PrunedBundle struct {
+	Skill     string
+	Version   string
+	SizeBytes int64
+}
+
+// Prune applies the policy to every skill, or to the named ones. It marks
+// each expired version as pruned in the metadata, so that lookups answer
+// 410 Gone from then on, and records an audit event. Blobs may be shared
+// by other versions, so the next CollectGarbage deletes the ones that are
+// no longer referenced; bundles in the legacy layout are deleted at once.
+// With dryRun, Prune only reports what it would remove.
+func Prune(meta MetaStore, blobs BlobStore, p RetentionPolicy, now time.Time, dryRun bool, names ...string) ([]PrunedBundle, error) {
+	legacy, _ := blobs.(LegacyBundleStore)
+
+	var skills []SkillMeta
+	if len(names) == 0 {
+		all, err := meta.Search("")
+		if err != nil {
+			return nil, fmt.Errorf("listing skills: %w", err)
+		}
+		skills = all
+	}
+	for _, name := range names {
+		m, err := meta.GetSkill(name)
+		if err != nil {
+			return nil, fmt.Errorf("loading skill %s: %w", name, err)
+		}
+		if m == nil {
+			return nil, fmt.Errorf("skill %q not found", name)
+		}
+		skills = append(skills, *m)
+	}
+
+	var pruned []PrunedBundle
+	for i := range skills {
+		name := skills[i].Name
+		if dryRun {
+			for _, vm := range p.Expired(&skills[i], now) {
+				pruned = append(pruned, PrunedBundle{Skill: name, Version: vm.Version, SizeBytes: vm.SizeBytes})
+			}
+			continue
+		}
+
+		// Decide again inside the update, in case a download or a new
+		// dist-tag arrived since the skill was listed.
+		var expired []VersionMeta
+		err := meta.UpdateSkill(name, func(m *SkillMeta) error {
+			expired = p.Expired(m, now)
+			for _, vm := range expired {
+				m.prune(vm.Version, p.reason(), now)
+			}
+			return nil
+		})
+		if err != nil {
+			return pruned, fmt.Errorf("pruning %s: %w", name, err)
+		}
+		for _, vm := range expired {
+			if legacy != nil {
+				if err := legacy.DeleteBundle(name, vm.Version); err != nil {
+					return pruned, fmt.Errorf("pruning %s@%s: %w", name, vm.Version, err)
+				}
+			}
+			pruned = append(pruned, PrunedBundle{Skill: name, Version: vm.Version, SizeBytes: vm.SizeBytes})
+			e := AuditEvent{
+				ID:       newID(),
+				Time:     now.UTC(),
+				Actor:    RetentionActor,
+				Action:   AuditPrune,
+				Target:   name,
+				Version:  vm.Version,
+				Checksum: vm.Checksum,
+				Detail:   p.reason(),
+			}
+			if err := meta.AppendAudit(e); err != nil {
+				log.Printf("recording audit event %s on %s: %v", e.Action, e.Target, err)
+			}
+		}
+	}
+	return pruned, nil
+}
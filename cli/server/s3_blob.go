@@ -0,0 +1,416 @@
+//go:build server
+
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	unsignedPayload  = "UNSIGNED-PAYLOAD"
+)
+
+// S3BlobStore stores bundles in an S3-compatible bucket (AWS S3, MinIO, ...).
+// Objects are keyed "{prefix}blobs/sha256/{hex[:2]}/{hex}", matching the
+// bundle_key column. Bundles published before blobs were content-addressed
+// may remain at "{prefix}{name}/{version}.tar.gz" until CollectGarbage
+// moves them.
+type S3BlobStore struct {
+	endpoint     *url.URL // scheme and host requests are sent to
+	bucket       string
+	prefix       string
+	pathStyle    bool
+	region       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	httpClient   *http.Client
+	now          func() time.Time
+}
+
+// NewS3BlobStoreFromURL configures a store from a URL of the form
+//
+//	s3://bucket?endpoint=http://minio:9000&region=us-east-1&prefix=bundles/
+//
+// Without an endpoint, requests go to AWS using virtual-hosted-style URLs.
+// Credentials are read from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// AWS_SESSION_TOKEN; the region falls back to AWS_REGION, then us-east-1.
+func NewS3BlobStoreFromURL(raw string) (*S3BlobStore, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parsing blob store URL: %w", err)
+	}
+	if u.Scheme != "s3" || u.Host == "" {
+		return nil, fmt.Errorf("blob store URL must look like s3://bucket, got %q", raw)
+	}
+	q := u.Query()
+
+	s := &S3BlobStore{
+		bucket:       u.Host,
+		prefix:       strings.TrimPrefix(q.Get("prefix"), "/"),
+		region:       q.Get("region"),
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		httpClient:   &http.Client{},
+		now:          time.Now,
+	}
+	if s.region == "" {
+		s.region = os.Getenv("AWS_REGION")
+	}
+	if s.region == "" {
+		s.region = "us-east-1"
+	}
+
+	if ep := q.Get("endpoint"); ep != "" {
+		s.endpoint, err = url.Parse(ep)
+		if err != nil || s.endpoint.Host == "" {
+			return nil, fmt.Errorf("invalid endpoint %q", ep)
+		}
+		s.pathStyle = q.Get("path_style") != "false"
+	} else {
+		s.endpoint = &url.URL{Scheme: "https", Host: fmt.Sprintf("s3.%s.amazonaws.com", s.region)}
+		s.pathStyle = q.Get("path_style") == "true"
+	}
+	return s, nil
+}
+
+func (s *S3BlobStore) key(name, version string) string {
+	return s.prefix + bundleKey(name, version)
+}
+
+func (s *S3BlobStore) blobKey(digest string) (string, error) {
+	if !digestRegex.MatchString(digest) {
+		return "", fmt.Errorf("invalid digest %q", digest)
+	}
+	return s.prefix + blobKey(digest), nil
+}
+
+// objectURL returns the URL of an object with its path already escaped the
+// way SigV4 expects, so the bytes on the wire match the signed request.
+func (s *S3BlobStore) objectURL(key string) *url.URL {
+	u := &url.URL{Scheme: s.endpoint.Scheme, Host: s.endpoint.Host}
+	path := "/" + key
+	if s.pathStyle {
+		path = "/" + s.bucket + path
+	} else {
+		u.Host = s.bucket + "." + u.Host
+	}
+	u.Path = path
+	u.RawPath = awsURIEncode(path, false)
+	return u
+}
+
+// PutBlob uploads the blob. S3 needs the content length up front, so
+// readers of unknown length are spooled to a temp file first. A blob that
+// exists is uploaded again anyway, which refreshes its modification time
+// in one request.
+func (s *S3BlobStore) PutBlob(digest string, r io.Reader) error {
+	key, err := s.blobKey(digest)
+	if err != nil {
+		return err
+	}
+	body, size, cleanup, err := sizedReader(r)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	req, err := http.NewRequest(http.MethodPut, s.objectURL(key).String(), body)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/gzip")
+
+	resp, err := s.do(req, unsignedPayload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return s3Error("uploading bundle", resp)
+	}
+	return nil
+}
+
+// OpenBlob streams the object body. The caller must close it.
+func (s *S3BlobStore) OpenBlob(digest string) (io.ReadCloser, error) {
+	key, err := s.blobKey(digest)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	return s.open(key)
+}
+
+// DeleteBlob deletes the object.
+func (s *S3BlobStore) DeleteBlob(digest string) error {
+	key, err := s.blobKey(digest)
+	if err != nil {
+		return err
+	}
+	return s.delete(key)
+}
+
+// listResult is the part of a ListObjectsV2 response that ListBlobs uses.
+type listResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// ListBlobs pages through ListObjectsV2 under the blob prefix.
+func (s *S3BlobStore) ListBlobs(fn func(digest string, modTime time.Time) error) error {
+	var token string
+	for {
+		u := s.objectURL("")
+		q := url.Values{"list-type": {"2"}, "prefix": {s.prefix + blobPrefix}}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		u.RawQuery = q.Encode()
+		req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+		if err != nil {
+			return fmt.Errorf("creating request: %w", err)
+		}
+		resp, err := s.do(req, emptyPayloadHash)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			defer resp.Body.Close()
+			return s3Error("listing blobs", resp)
+		}
+		var result listResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("listing blobs: decoding response: %w", err)
+		}
+
+		for _, obj := range result.Contents {
+			if digest := digestFromKey(strings.TrimPrefix(obj.Key, s.prefix)); digest != "" {
+				if err := fn(digest, obj.LastModified); err != nil {
+					return err
+				}
+			}
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// OpenBundle streams a bundle in the legacy layout.
+func (s *S3BlobStore) OpenBundle(name, version string) (io.ReadCloser, error) {
+	return s.open(s.key(name, version))
+}
+
+// DeleteBundle deletes a bundle in the legacy layout.
+func (s *S3BlobStore) DeleteBundle(name, version string) error {
+	return s.delete(s.key(name, version))
+}
+
+func (s *S3BlobStore) open(key string) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, s.objectURL(key).String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, s3Error("downloading bundle", resp)
+	}
+	return resp.Body, nil
+}
+
+// delete deletes an object. S3 answers 204 whether or not it existed.
+func (s *S3BlobStore) delete(key string) error {
+	req, err := http.NewRequest(http.MethodDelete, s.objectURL(key).String(), nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return s3Error("deleting bundle", resp)
+	}
+	return nil
+}
+
+func (s *S3BlobStore) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	s.sign(req, payloadHash, s.now())
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3 %s %s: %w", req.Method, req.URL.Path, err)
+	}
+	return resp, nil
+}
+
+func s3Error(action string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	return fmt.Errorf("%s: s3 returned %d: %s", action, resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+// sizedReader returns r together with its length, spooling it to a temp
+// file when the length cannot be determined.
+func sizedReader(r io.Reader) (io.Reader, int64, func(), error) {
+	noop := func() {}
+	switch v := r.(type) {
+	case *bytes.Reader:
+		return v, int64(v.Len()), noop, nil
+	case *os.File:
+		if fi, err := v.Stat(); err == nil && fi.Mode().IsRegular() {
+			pos, err := v.Seek(0, io.SeekCurrent)
+			if err == nil {
+				return v, fi.Size() - pos, noop, nil
+			}
+		}
+	}
+
+	tmp, err := os.CreateTemp("", "s3-upload-*")
+	if err != nil {
+		return nil, 0, noop, fmt.Errorf("creating spool file: %w", err)
+	}
+	cleanup := func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}
+	n, err := io.Copy(tmp, r)
+	if err != nil {
+		cleanup()
+		return nil, 0, noop, fmt.Errorf("spooling upload: %w", err)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		return nil, 0, noop, fmt.Errorf("spooling upload: %w", err)
+	}
+	return tmp, n, cleanup, nil
+}
+
+// --- AWS Signature Version 4 ---
+
+// sign adds SigV4 headers to req. Every header already set on req is signed,
+// plus host; headers added later by the transport are not.
+func (s *S3BlobStore) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	signature := sigV4Signature(s.secretKey, s.region, amzDate, req.Method, req.URL.EscapedPath(),
+		req.URL.Query(), headers, names, payloadHash)
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", amzDate[:8], s.region)
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, strings.Join(names, ";"), signature))
+}
+
+// sigV4Signature computes the request signature over the given signed headers.
+func sigV4Signature(secretKey, region, amzDate, method, escapedPath string, query url.Values,
+	headers map[string]string, signedHeaders []string, payloadHash string) string {
+
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaders {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		escapedPath,
+		canonicalQuery(query),
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+
+	date := amzDate[:8]
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, region)
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		vals := append([]string(nil), q[k]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, awsURIEncode(k, true)+"="+awsURIEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsURIEncode percent-encodes everything except unreserved characters.
+func awsURIEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(data))
+	return m.Sum(nil)
+}
@@ -0,0 +1,648 @@
+//go:build server
+
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/liuyukai/agentskills-cli/internal/semver"
+)
+
+// openTestSQLStore opens a SQLite store in a temp dir, or a fresh schema in
+// the Postgres database named by AGENTSKILLS_TEST_POSTGRES_DSN when it is set.
+func openTestSQLStore(t *testing.T) *SQLMetaStore {
+	t.Helper()
+	dsn := "sqlite:" + filepath.Join(t.TempDir(), "registry.db")
+	if pg := os.Getenv("AGENTSKILLS_TEST_POSTGRES_DSN"); pg != "" {
+		dsn = testPostgresSchema(t, pg)
+	}
+	store, err := OpenSQLMetaStore(dsn, "")
+	if err != nil {
+		t.Fatalf("OpenSQLMetaStore() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// testPostgresSchema creates an isolated schema and returns a DSN using it.
+func testPostgresSchema(t *testing.T, dsn string) string {
+	t.Helper()
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	schema := "test_" + strings.ReplaceAll(newID(), "-", "")
+	if _, err := db.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("creating schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if db, err := sql.Open("postgres", dsn); err == nil {
+			db.Exec("DROP SCHEMA " + schema + " CASCADE")
+			db.Close()
+		}
+	})
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + "search_path=" + schema
+}
+
+func testVersion(version string) VersionMeta {
+	return VersionMeta{
+		Version:     version,
+		Description: "A test skill",
+		Checksum:    "sha256:abc123",
+		SizeBytes:   42,
+		PublishedAt: time.Now().UTC().Format(time.RFC3339),
+		Metadata:    map[string]interface{}{"name": "sql-skill", "version": version, "license": "MIT"},
+	}
+}
+
+func TestSQLMetaStorePutAndGet(t *testing.T) {
+	store := openTestSQLStore(t)
+
+	if err := store.PutVersion("sql-skill", "tester", "first", []string{"test"}, testVersion("1.0.0"), false, nil); err != nil {
+		t.Fatalf("PutVersion() error = %v", err)
+	}
+	if err := store.PutVersion("sql-skill", "tester", "second", nil, testVersion("1.1.0"), false, nil); err != nil {
+		t.Fatalf("PutVersion() error = %v", err)
+	}
+
+	meta, err := store.GetSkill("sql-skill")
+	if err != nil {
+		t.Fatalf("GetSkill() error = %v", err)
+	}
+	if meta == nil {
+		t.Fatal("GetSkill() returned nil")
+	}
+	if meta.Owner != "tester" || meta.Description != "second" {
+		t.Errorf("owner/description = %q/%q, want tester/second", meta.Owner, meta.Description)
+	}
+	if len(meta.Tags) != 1 || meta.Tags[0] != "test" {
+		t.Errorf("Tags = %v, want [test] (empty tags must not clear existing ones)", meta.Tags)
+	}
+	if len(meta.Versions) != 2 {
+		t.Fatalf("len(Versions) = %d, want 2", len(meta.Versions))
+	}
+	lv := meta.LatestVersion()
+	if lv.Version != "1.1.0" {
+		t.Errorf("LatestVersion() = %s, want 1.1.0", lv.Version)
+	}
+	if lv.Checksum != "sha256:abc123" {
+		t.Errorf("Checksum = %s, want sha256:abc123", lv.Checksum)
+	}
+	if lv.Metadata["license"] != "MIT" {
+		t.Errorf("Metadata[license] = %v, want MIT", lv.Metadata["license"])
+	}
+
+	if err := store.PutVersion("sql-skill", "tester", "again", nil, testVersion("1.0.0"), false, nil); err != ErrVersionExists {
+		t.Errorf("PutVersion(existing) error = %v, want ErrVersionExists", err)
+	}
+	if err := store.PutVersion("sql-skill", "tester", "second", nil, testVersion("1.0.0"), true, nil); err != nil {
+		t.Errorf("PutVersion(existing, replace) error = %v", err)
+	}
+
+	missing, err := store.GetSkill("missing")
+	if err != nil || missing != nil {
+		t.Errorf("GetSkill(missing) = %v, %v; want nil, nil", missing, err)
+	}
+}
+
+func TestSQLMetaStoreSearchAndDownloads(t *testing.T) {
+	store := openTestSQLStore(t)
+
+	store.PutVersion("code-review", "tester", "Reviews pull requests", []string{"github"}, testVersion("1.0.0"), false, nil)
+	store.PutVersion("data-analysis", "tester", "Analyzes 100% of data", []string{"pandas"}, testVersion("1.0.0"), false, nil)
+
+	for keyword, want := range map[string]int{"": 2, "REVIEW": 1, "pandas": 1, "100%": 1, "_": 0, "nothing": 0} {
+		results, err := store.Search(keyword)
+		if err != nil {
+			t.Fatalf("Search(%q) error = %v", keyword, err)
+		}
+		if len(results) != want {
+			t.Errorf("Search(%q) returned %d results, want %d", keyword, len(results), want)
+		}
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for i := 0; i < 3; i++ {
+		if err := store.IncrementDownloads("code-review", "1.0.0", now); err != nil {
+			t.Fatalf("IncrementDownloads() error = %v", err)
+		}
+	}
+	meta, _ := store.GetSkill("code-review")
+	if meta.Downloads != 3 {
+		t.Errorf("Downloads = %d, want 3", meta.Downloads)
+	}
+	if at := meta.FindVersion("1.0.0").LastDownloadedAt; at == nil || !at.Equal(now) {
+		t.Errorf("LastDownloadedAt = %v, want %v", at, now)
+	}
+}
+
+func TestSQLMetaStoreUpdateSkill(t *testing.T) {
+	store := openTestSQLStore(t)
+	store.PutVersion("updatable", "tester", "desc", nil, testVersion("1.0.0"), false, nil)
+	store.PutVersion("updatable", "tester", "desc", nil, testVersion("1.1.0"), false, nil)
+
+	err := store.UpdateSkill("updatable", func(meta *SkillMeta) error {
+		meta.Deprecated = "gone"
+		meta.FindVersion("1.1.0").Yanked = true
+		meta.FindVersion("1.1.0").YankReason = "broken"
+		meta.setDistTag("beta", "1.1.0")
+		meta.Collaborators = []string{"helper"}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("UpdateSkill() error = %v", err)
+	}
+
+	meta, _ := store.GetSkill("updatable")
+	if meta.Deprecated != "gone" {
+		t.Errorf("Deprecated = %q, want gone", meta.Deprecated)
+	}
+	if vm := meta.FindVersion("1.1.0"); !vm.Yanked || vm.YankReason != "broken" {
+		t.Errorf("version 1.1.0 = %+v, want yanked", vm)
+	}
+	if lv := meta.LatestVersion(); lv == nil || lv.Version != "1.0.0" {
+		t.Errorf("LatestVersion() = %+v, want 1.0.0", lv)
+	}
+	if meta.DistTags["beta"] != "1.1.0" {
+		t.Errorf("DistTags = %v, want beta=1.1.0", meta.DistTags)
+	}
+	if !meta.CanManage("helper") || meta.CanManage("stranger") {
+		t.Errorf("Collaborators = %v, want [helper]", meta.Collaborators)
+	}
+
+	if err := store.UpdateSkill("missing", func(*SkillMeta) error { return nil }); err != ErrNotFound {
+		t.Errorf("UpdateSkill(missing) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSQLMetaStoreVisibility(t *testing.T) {
+	store := openTestSQLStore(t)
+	private := testVersion("1.0.0")
+	private.Metadata["visibility"] = VisibilityPrivate
+	store.PutVersion("hidden", "tester", "desc", nil, private, false, nil)
+	store.PutVersion("hidden", "tester", "desc", nil, testVersion("1.1.0"), false, nil)
+	store.PutVersion("shown", "tester", "desc", nil, testVersion("1.0.0"), false, nil)
+
+	// Versions without a visibility keep the skill's.
+	if meta, _ := store.GetSkill("hidden"); meta.VisibilityLevel() != VisibilityPrivate {
+		t.Errorf("visibility of hidden = %q, want private", meta.Visibility)
+	}
+	if meta, _ := store.GetSkill("shown"); meta.VisibilityLevel() != VisibilityPublic {
+		t.Errorf("visibility of shown = %q, want public", meta.Visibility)
+	}
+
+	store.UpdateSkill("hidden", func(meta *SkillMeta) error {
+		meta.Visibility = VisibilityInternal
+		return nil
+	})
+	if meta, _ := store.GetSkill("hidden"); meta.Visibility != VisibilityInternal {
+		t.Errorf("visibility after UpdateSkill = %q, want internal", meta.Visibility)
+	}
+}
+
+func TestSQLMetaStoreOrgs(t *testing.T) {
+	store := openTestSQLStore(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	store.CreateUser(User{Username: "alice", CreatedAt: now})
+	store.CreateUser(User{Username: "bob", CreatedAt: now})
+
+	acme := Org{Name: "acme", CreatedAt: now, Members: []OrgMember{{Username: "alice", Role: OrgRoleOwner}}}
+	if err := store.CreateOrg(acme); err != nil {
+		t.Fatalf("CreateOrg() error = %v", err)
+	}
+	if err := store.CreateOrg(acme); err != ErrOrgExists {
+		t.Errorf("CreateOrg(duplicate) error = %v, want ErrOrgExists", err)
+	}
+	err := store.UpdateOrg("acme", func(o *Org) error {
+		o.setMember("bob", OrgRoleMember)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("UpdateOrg() error = %v", err)
+	}
+	if err := store.UpdateOrg("nope", func(*Org) error { return nil }); err != ErrNotFound {
+		t.Errorf("UpdateOrg(missing) error = %v, want ErrNotFound", err)
+	}
+
+	got, err := store.GetOrg("acme")
+	if err != nil || got == nil || !got.CreatedAt.Equal(now) || got.Role("alice") != OrgRoleOwner || got.Role("bob") != OrgRoleMember {
+		t.Fatalf("GetOrg() = %+v, %v", got, err)
+	}
+	if got, err := store.GetOrg("nope"); err != nil || got != nil {
+		t.Errorf("GetOrg(missing) = %+v, %v, want nil", got, err)
+	}
+	if orgs, err := store.UserOrgs("bob"); err != nil || !reflect.DeepEqual(orgs, map[string]string{"acme": OrgRoleMember}) {
+		t.Errorf("UserOrgs(bob) = %v, %v", orgs, err)
+	}
+
+	store.UpdateOrg("acme", func(o *Org) error {
+		o.removeMember("bob")
+		return nil
+	})
+	if orgs, _ := store.UserOrgs("bob"); len(orgs) != 0 {
+		t.Errorf("UserOrgs(bob) after removal = %v", orgs)
+	}
+}
+
+func TestSQLMetaStoreOwners(t *testing.T) {
+	store := openTestSQLStore(t)
+	store.PutVersion("owned", "alice", "desc", nil, testVersion("1.0.0"), false, nil)
+	store.CreateOrg(Org{Name: "acme", CreatedAt: time.Now().UTC()})
+
+	transfer := func(to string) error {
+		return store.UpdateSkill("owned", func(meta *SkillMeta) error {
+			meta.recordOwnerEvent(OwnerActionTransfer, to, meta.Owner, "alice")
+			meta.Owner = to
+			return nil
+		})
+	}
+	if err := transfer("@acme"); err != nil {
+		t.Fatalf("transfer to @acme: %v", err)
+	}
+	meta, _ := store.GetSkill("owned")
+	if meta.Owner != "@acme" || meta.OwnerOrg() != "acme" || len(meta.OwnerHistory) != 1 {
+		t.Fatalf("after transfer to @acme: owner %q, history %+v", meta.Owner, meta.OwnerHistory)
+	}
+	if err := transfer("@nope"); err == nil {
+		t.Error("transfer to a missing organization should fail")
+	}
+	if err := transfer("bob"); err != nil {
+		t.Fatalf("transfer to bob: %v", err)
+	}
+	meta, _ = store.GetSkill("owned")
+	if meta.Owner != "bob" || len(meta.OwnerHistory) != 2 {
+		t.Fatalf("after transfer to bob: owner %q, history %+v", meta.Owner, meta.OwnerHistory)
+	}
+	if e := meta.OwnerHistory[1]; e.Action != OwnerActionTransfer || e.User != "bob" || e.From != "@acme" || e.Actor != "alice" || e.At.IsZero() {
+		t.Errorf("history[1] = %+v", e)
+	}
+}
+
+func TestSQLMetaStoreUsage(t *testing.T) {
+	store := openTestSQLStore(t)
+	store.CreateOrg(Org{Name: "acme", CreatedAt: time.Now().UTC()})
+	store.PutVersion("mine", "alice", "desc", nil, testVersion("1.0.0"), false, nil)
+	store.PutVersion("mine", "alice", "desc", nil, testVersion("1.1.0"), false, nil)
+	store.PutVersion("moved", "alice", "desc", nil, testVersion("1.0.0"), false, nil)
+	store.PutVersion("@acme/scoped", "alice", "desc", nil, testVersion("1.0.0"), false, nil)
+	store.UpdateSkill("moved", func(meta *SkillMeta) error {
+		meta.Owner = "@acme"
+		return nil
+	})
+
+	if got, err := store.Usage("alice"); err != nil || !reflect.DeepEqual(got, []SkillUsage{{Name: "mine", Versions: 2, Bytes: 84}}) {
+		t.Errorf("Usage(alice) = %+v, %v", got, err)
+	}
+	want := []SkillUsage{{Name: "@acme/scoped", Versions: 1, Bytes: 42}, {Name: "moved", Versions: 1, Bytes: 42}}
+	if got, err := store.Usage("@acme"); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Usage(@acme) = %+v, %v", got, err)
+	}
+	if got, err := store.Usage("bob"); err != nil || len(got) != 0 {
+		t.Errorf("Usage(bob) = %+v, %v", got, err)
+	}
+}
+
+func TestSQLMetaStorePrune(t *testing.T) {
+	store := openTestSQLStore(t)
+	store.PutVersion("nightly", "alice", "desc", nil, testVersion("1.0.0-dev.1"), false, nil)
+	store.PutVersion("nightly", "alice", "desc", nil, testVersion("1.0.0-dev.2"), false, nil)
+	now := time.Now().UTC().Truncate(time.Second)
+	err := store.UpdateSkill("nightly", func(meta *SkillMeta) error {
+		meta.prune("1.0.0-dev.1", "too old", now)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("UpdateSkill() error = %v", err)
+	}
+
+	meta, _ := store.GetSkill("nightly")
+	if len(meta.Versions) != 1 || meta.Versions[0].Version != "1.0.0-dev.2" {
+		t.Errorf("versions after pruning = %+v", meta.Versions)
+	}
+	want := []PrunedVersion{{Version: "1.0.0-dev.1", Checksum: "sha256:abc123", Reason: "too old", PrunedAt: now}}
+	if !reflect.DeepEqual(meta.Pruned, want) {
+		t.Errorf("Pruned = %+v, want %+v", meta.Pruned, want)
+	}
+	if usage, _ := store.Usage("alice"); len(usage) != 1 || usage[0].Versions != 1 {
+		t.Errorf("Usage(alice) after pruning = %+v", usage)
+	}
+
+	// Saving the skill again must not record the pruned version twice.
+	if err := store.UpdateSkill("nightly", func(*SkillMeta) error { return nil }); err != nil {
+		t.Fatalf("UpdateSkill() error = %v", err)
+	}
+	if meta, _ := store.GetSkill("nightly"); len(meta.Pruned) != 1 {
+		t.Errorf("Pruned after another update = %+v", meta.Pruned)
+	}
+}
+
+func TestSQLMetaStoreAudit(t *testing.T) {
+	store := openTestSQLStore(t)
+	start := time.Now().UTC().Truncate(time.Second)
+	for i, e := range []AuditEvent{
+		{Actor: "alice", Action: AuditPublish, Target: "logged", Version: "1.0.0", Checksum: "sha256:abc123"},
+		{Actor: "bob", Action: AuditYank, Target: "logged", Version: "1.0.0", Detail: "broken"},
+		{Actor: "alice", Action: AuditOrgCreate, Target: "@acme", Detail: "alice", TokenID: "tok"},
+	} {
+		e.ID, e.Time, e.ClientIP = newID(), start.Add(time.Duration(i)*time.Minute), "192.0.2.1"
+		if err := store.AppendAudit(e); err != nil {
+			t.Fatalf("AppendAudit() error = %v", err)
+		}
+	}
+
+	actions := func(f AuditFilter) []string {
+		t.Helper()
+		events, err := store.ListAudit(f)
+		if err != nil {
+			t.Fatalf("ListAudit(%+v) error = %v", f, err)
+		}
+		var got []string
+		for _, e := range events {
+			got = append(got, e.Action)
+		}
+		return got
+	}
+	if got := actions(AuditFilter{}); !reflect.DeepEqual(got, []string{AuditPublish, AuditYank, AuditOrgCreate}) {
+		t.Errorf("all events = %v", got)
+	}
+	if got := actions(AuditFilter{Target: "logged", Actor: "alice"}); !reflect.DeepEqual(got, []string{AuditPublish}) {
+		t.Errorf("events on logged by alice = %v", got)
+	}
+	if got := actions(AuditFilter{Since: start.Add(time.Minute)}); !reflect.DeepEqual(got, []string{AuditYank, AuditOrgCreate}) {
+		t.Errorf("events since the first = %v", got)
+	}
+	if got := actions(AuditFilter{Limit: 1}); !reflect.DeepEqual(got, []string{AuditOrgCreate}) {
+		t.Errorf("newest event = %v", got)
+	}
+
+	events, _ := store.ListAudit(AuditFilter{Actor: "bob"})
+	if len(events) != 1 || !events[0].Time.Equal(start.Add(time.Minute)) || events[0].Detail != "broken" || events[0].ClientIP != "192.0.2.1" {
+		t.Errorf("events by bob = %+v", events)
+	}
+}
+
+func TestSQLMetaStoreTokens(t *testing.T) {
+	store := openTestSQLStore(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	if err := store.CreateUser(User{Username: "alice", Admin: true, CreatedAt: now}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if err := store.CreateUser(User{Username: "alice", CreatedAt: now}); err != ErrUserExists {
+		t.Errorf("CreateUser(duplicate) error = %v, want ErrUserExists", err)
+	}
+	if u, err := store.GetUser("alice"); err != nil || u == nil || !u.Admin {
+		t.Errorf("GetUser() = %+v, %v, want admin alice", u, err)
+	}
+	if u, err := store.GetUser("nobody"); err != nil || u != nil {
+		t.Errorf("GetUser(nobody) = %+v, %v, want nil", u, err)
+	}
+
+	expires := now.Add(time.Hour)
+	tok, secret := NewAPIToken("alice", "ci", &expires)
+	tok.Scopes = []string{"publish:code-review-*", "yank"}
+	if err := store.CreateToken(tok); err != nil {
+		t.Fatalf("CreateToken() error = %v", err)
+	}
+	if orphan, _ := NewAPIToken("nobody", "x", nil); store.CreateToken(orphan) == nil {
+		t.Error("CreateToken() for an unknown user should fail")
+	}
+
+	got, err := store.TokenByHash(HashToken(secret))
+	if err != nil || got == nil {
+		t.Fatalf("TokenByHash() = %v, %v", got, err)
+	}
+	if got.ID != tok.ID || got.Username != "alice" || got.Name != "ci" || got.LastUsedAt != nil ||
+		got.ExpiresAt == nil || !got.ExpiresAt.Equal(expires) || !reflect.DeepEqual(got.Scopes, tok.Scopes) {
+		t.Errorf("TokenByHash() = %+v", got)
+	}
+	if got, err := store.TokenByHash(HashToken("wrong")); err != nil || got != nil {
+		t.Errorf("TokenByHash(wrong) = %v, %v, want nil", got, err)
+	}
+
+	if err := store.TouchToken(tok.ID, now); err != nil {
+		t.Fatalf("TouchToken() error = %v", err)
+	}
+	tokens, err := store.ListTokens("alice")
+	if err != nil || len(tokens) != 1 || tokens[0].LastUsedAt == nil || !tokens[0].LastUsedAt.Equal(now) {
+		t.Fatalf("ListTokens() = %+v, %v", tokens, err)
+	}
+
+	if err := store.DeleteToken("bob", tok.ID); err != ErrNotFound {
+		t.Errorf("DeleteToken(other user) error = %v, want ErrNotFound", err)
+	}
+	if err := store.DeleteToken("alice", "not-a-uuid"); err != ErrNotFound {
+		t.Errorf("DeleteToken(bad id) error = %v, want ErrNotFound", err)
+	}
+	if err := store.DeleteToken("alice", tok.ID); err != nil {
+		t.Fatalf("DeleteToken() error = %v", err)
+	}
+	if got, _ := store.TokenByHash(HashToken(secret)); got != nil {
+		t.Error("revoked token should no longer resolve")
+	}
+}
+
+func TestSQLMetaStoreLegacyTokens(t *testing.T) {
+	store := openTestSQLStore(t)
+	// init.sql seeds users this way.
+	if _, err := store.exec(store.db, `INSERT INTO users (id, username, api_token, created_at) VALUES (?, ?, ?, ?)`,
+		newID(), "dev", "dev-token-12345", time.Now().UTC()); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.inTx(func(tx *sql.Tx) error { return hashLegacyTokens(store, tx) }); err != nil {
+		t.Fatalf("hashLegacyTokens() error = %v", err)
+	}
+
+	if got, err := store.TokenByHash(HashToken("dev-token-12345")); err != nil || got == nil || got.Username != "dev" {
+		t.Errorf("TokenByHash() = %+v, %v, want dev's token", got, err)
+	}
+	var plain sql.NullString
+	store.queryRow(store.db, `SELECT api_token FROM users WHERE username = ?`, "dev").Scan(&plain)
+	if plain.Valid {
+		t.Errorf("users.api_token = %q, want NULL", plain.String)
+	}
+}
+
+func TestSQLMetaStoreReopen(t *testing.T) {
+	dataDir := t.TempDir()
+	store, err := OpenSQLMetaStore("sqlite", dataDir)
+	if err != nil {
+		t.Fatalf("OpenSQLMetaStore() error = %v", err)
+	}
+	store.PutVersion("persisted", "tester", "kept", nil, testVersion("1.0.0"), false, nil)
+	store.Close()
+
+	// Reopening must not re-apply migrations.
+	store, err = OpenSQLMetaStore("sqlite", dataDir)
+	if err != nil {
+		t.Fatalf("reopening store: %v", err)
+	}
+	defer store.Close()
+
+	var applied int
+	store.db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied)
+	if applied != len(migrations) {
+		t.Errorf("schema_migrations has %d rows, want %d", applied, len(migrations))
+	}
+	if meta, _ := store.GetSkill("persisted"); meta == nil {
+		t.Error("skill should survive reopening the database")
+	}
+}
+
+func TestSQLMetaStoreLongVersion(t *testing.T) {
+	store := openTestSQLStore(t)
+	long := "1.0.0-rc.1.nightly.build.for.the.reference.corpus.refresh+build.20260101.abcdef0123456789abcdef"
+	if _, err := semver.Parse(long); err != nil {
+		t.Fatalf("semver.Parse(%q) error = %v", long, err)
+	}
+	if err := store.PutVersion("long-version", "tester", "desc", nil, testVersion(long), false, nil); err != nil {
+		t.Fatalf("PutVersion(%q) error = %v", long, err)
+	}
+	if err := store.AppendAudit(AuditEvent{ID: newID(), Time: time.Now().UTC(), Actor: "tester", Action: AuditPublish, Target: "long-version", Version: long}); err != nil {
+		t.Fatalf("AppendAudit() error = %v", err)
+	}
+	err := store.UpdateSkill("long-version", func(m *SkillMeta) error {
+		m.prune(long, "test", time.Now().UTC())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("pruning %q: %v", long, err)
+	}
+	m, err := store.GetSkill("long-version")
+	if err != nil || m.FindPruned(long) == nil {
+		t.Fatalf("GetSkill() = %+v, %v, want %q pruned", m, err, long)
+	}
+}
+
+func TestSQLMetaStorePutVersionCheck(t *testing.T) {
+	store := openTestSQLStore(t)
+	errDenied := errors.New("denied")
+	var seen []*SkillMeta
+	check := func(m *SkillMeta) error {
+		seen = append(seen, m)
+		if m != nil && m.Owner != "alice" {
+			return errDenied
+		}
+		return nil
+	}
+
+	if err := store.PutVersion("checked", "alice", "desc", nil, testVersion("1.0.0"), false, check); err != nil {
+		t.Fatalf("PutVersion(new) error = %v", err)
+	}
+	if err := store.PutVersion("checked", "alice", "desc", nil, testVersion("1.1.0"), false, check); err != nil {
+		t.Fatalf("PutVersion(allowed) error = %v", err)
+	}
+	if len(seen) != 2 || seen[0] != nil || seen[1] == nil || len(seen[1].Versions) != 1 {
+		t.Fatalf("check saw %+v, want nil and then the stored skill", seen)
+	}
+
+	store.UpdateSkill("checked", func(m *SkillMeta) error {
+		m.Owner = "bob"
+		return nil
+	})
+	if err := store.PutVersion("checked", "alice", "desc", nil, testVersion("1.2.0"), false, check); err != errDenied {
+		t.Fatalf("PutVersion(denied) error = %v, want the check's error", err)
+	}
+	if m, _ := store.GetSkill("checked"); len(m.Versions) != 2 {
+		t.Errorf("denied publish stored a version: %+v", m.Versions)
+	}
+}
+
+// Publishing in open mode, or as an admin, on behalf of an author who has
+// no account must not register one, on either store.
+func TestUnregisteredAuthors(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		store func(dataDir string) MetaStore
+	}{
+		{"fs", func(dataDir string) MetaStore { return NewFSMetaStore(dataDir) }},
+		{"sql", func(string) MetaStore { return openTestSQLStore(t) }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dataDir := t.TempDir()
+			meta := tc.store(dataDir)
+			handler := NewHandler(meta, NewFSBlobStore(dataDir), Options{Token: "test-token"})
+			mux := http.NewServeMux()
+			handler.RegisterRoutes(mux)
+			ts := httptest.NewServer(mux)
+			defer ts.Close()
+
+			publishBundle(t, ts.URL, "test-token", createAuthoredSkillDir(t, "ghost-skill", "1.0.0", "ghost"))
+			publishBundle(t, ts.URL, "test-token", createTestSkillDir(t, "other-skill", "1.0.0"))
+			if u, err := meta.GetUser("ghost"); err != nil || u != nil {
+				t.Fatalf("GetUser(ghost) = %+v, %v, want no account", u, err)
+			}
+
+			for _, c := range []struct{ method, path, body string }{
+				{"POST", "/v1/skills/other-skill/transfer", `{"owner": "ghost"}`},
+				{"PUT", "/v1/skills/other-skill/collaborators/ghost", ""},
+			} {
+				resp := postJSON(t, c.method, ts.URL+c.path, "test-token", c.body)
+				resp.Body.Close()
+				if resp.StatusCode != http.StatusNotFound {
+					t.Errorf("%s %s returned %d, want 404", c.method, c.path, resp.StatusCode)
+				}
+			}
+
+			if has, err := meta.HasUsers(); err != nil || has {
+				t.Fatalf("HasUsers() = %v, %v, want false", has, err)
+			}
+
+			// The author can still be registered, and keeps the skill.
+			if err := meta.CreateUser(User{Username: "ghost", CreatedAt: time.Now().UTC()}); err != nil {
+				t.Fatalf("CreateUser(ghost): %v", err)
+			}
+			if has, err := meta.HasUsers(); err != nil || !has {
+				t.Fatalf("HasUsers() after CreateUser = %v, %v, want true", has, err)
+			}
+			if err := meta.CreateUser(User{Username: "ghost", CreatedAt: time.Now().UTC()}); err != ErrUserExists {
+				t.Fatalf("second CreateUser(ghost) = %v, want ErrUserExists", err)
+			}
+			if info := getSkillInfo(t, ts.URL, "ghost-skill"); info.Owner != "ghost" {
+				t.Errorf("owner = %q, want ghost", info.Owner)
+			}
+			resp := postJSON(t, "PUT", ts.URL+"/v1/skills/other-skill/collaborators/ghost", "test-token", "")
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("adding the registered collaborator returned %d, want 200", resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestPublishWithSQLStore(t *testing.T) {
+	dataDir := t.TempDir()
+	handler := NewHandler(openTestSQLStore(t), NewFSBlobStore(dataDir), Options{Token: "test-token"})
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	publishBundle(t, ts.URL, "test-token", createTestSkillDir(t, "sql-published", "1.0.0"))
+
+	resp, err := http.Get(ts.URL + "/v1/skills/sql-published/versions/1.0.0/download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("download returned %d", resp.StatusCode)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "bundles", "sql-published", "meta.json")); !os.IsNotExist(err) {
+		t.Error("meta.json should not be written when using the SQL store")
+	}
+}
@@ -3,13 +3,24 @@
 package server
 
 import (
-	"encoding/json"
-	"fmt"
-	"os"
-	"path/filepath"
+	"errors"
+	"io"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
-	"sync"
 	"time"
+
+	"github.com/liuyukai/agentskills-cli/internal/semver"
+)
+
+var (
+	// ErrNotFound is returned when the requested skill or bundle does not exist.
+	ErrNotFound = errors.New("not found")
+
+	// ErrVersionExists is returned by MetaStore.PutVersion when the version
+	// is already published and replacing it was not requested.
+	ErrVersionExists = errors.New("version already exists")
 )
 
 // SkillMeta is the persisted metadata for a single skill.
@@ -20,6 +31,74 @@ type SkillMeta struct {
 	Tags        []string      `json:"tags"`
 	Downloads   int64         `json:"downloads"`
 	Versions    []VersionMeta `json:"versions"`
+
+	// Collaborators may publish and manage the skill alongside its owner.
+	Collaborators []string `json:"collaborators,omitempty"`
+
+	// Deprecated, if non-empty, is shown to everyone who installs the skill.
+	Deprecated string `json:"deprecated,omitempty"`
+
+	// DistTags maps channel names such as "beta" to a version. "latest" is
+	// not stored; it is always the result of LatestVersion.
+	DistTags map[string]string `json:"dist_tags,omitempty"`
+
+	// Visibility is one of the Visibility constants; empty means public.
+	Visibility string `json:"visibility,omitempty"`
+
+	// OwnerHistory records every change of owner and collaborators, oldest
+	// first.
+	OwnerHistory []OwnerEvent `json:"owner_history,omitempty"`
+
+	// Pruned lists the versions removed by the retention policy, oldest
+	// first. Their bundles are gone, but lookups answer 410 Gone.
+	Pruned []PrunedVersion `json:"pruned,omitempty"`
+}
+
+// PrunedVersion records a version removed by the retention policy.
+type PrunedVersion struct {
+	Version  string    `json:"version"`
+	Checksum string    `json:"checksum"`
+	Reason   string    `json:"reason"`
+	PrunedAt time.Time `json:"pruned_at"`
+}
+
+// OwnerEvent is one change to who may manage a skill.
+type OwnerEvent struct {
+	Action string `json:"action"` // one of the OwnerAction constants
+	// User is the collaborator added or removed, or the new owner.
+	User string `json:"user"`
+	// From is the previous owner of a transfer.
+	From  string    `json:"from,omitempty"`
+	Actor string    `json:"actor"`
+	At    time.Time `json:"at"`
+}
+
+// Owner history actions.
+const (
+	OwnerActionAddCollaborator    = "add_collaborator"
+	OwnerActionRemoveCollaborator = "remove_collaborator"
+	OwnerActionTransfer           = "transfer"
+)
+
+// Skill visibilities.
+const (
+	// VisibilityPublic skills are readable by everyone, including
+	// unauthenticated clients.
+	VisibilityPublic = "public"
+	// VisibilityInternal skills are readable by every authenticated user.
+	VisibilityInternal = "internal"
+	// VisibilityPrivate skills are readable by their owner, collaborators
+	// and registry admins only.
+	VisibilityPrivate = "private"
+)
+
+// validVisibility reports whether v is a known visibility.
+func validVisibility(v string) bool {
+	switch v {
+	case VisibilityPublic, VisibilityInternal, VisibilityPrivate:
+		return true
+	}
+	return false
 }
 
 // VersionMeta is the persisted metadata for a single version.
@@ -29,147 +108,379 @@ type VersionMeta struct {
 	Checksum    string `json:"checksum"`
 	SizeBytes   int64  `json:"size_bytes"`
 	PublishedAt string `json:"published_at"`
+
+	// Metadata is the full SKILL.md frontmatter of this version.
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+
+	// Yanked versions are skipped when resolving the latest version but
+	// remain downloadable by exact version for existing lock files.
+	Yanked     bool   `json:"yanked,omitempty"`
+	YankReason string `json:"yank_reason,omitempty"`
+
+	// LastDownloadedAt is when the bundle was last downloaded, if ever.
+	// Recently downloaded versions are never pruned.
+	LastDownloadedAt *time.Time `json:"last_downloaded_at,omitempty"`
 }
 
-// Store is a file-system-based storage backend.
-// Layout:
-//
-//	{dataDir}/bundles/{name}/meta.json
-//	{dataDir}/bundles/{name}/{version}/bundle.tar.gz
-type Store struct {
-	dataDir string
-	mu      sync.RWMutex
+// MetaStore is the metadata repository used by the handler.
+type MetaStore interface {
+	// GetSkill returns metadata for a skill.
+	// Returns nil (no error) if the skill does not exist.
+	GetSkill(name string) (*SkillMeta, error)
+
+	// Search returns skills whose name, description, or tags match the keyword.
+	Search(keyword string) ([]SkillMeta, error)
+
+	// ListSkills returns every skill. Unlike Search, it fails if any skill
+	// cannot be loaded.
+	ListSkills() ([]SkillMeta, error)
+
+	// PutVersion records a published version and updates the skill-level
+	// description and tags, creating the skill with the given owner if needed.
+	// Returns ErrVersionExists if the version exists and replace is false.
+	// If check is non-nil, it is called with the stored skill, or nil for a
+	// new one, atomically with the write; an error from check is returned
+	// and nothing is written.
+	PutVersion(name, owner, description string, tags []string, vm VersionMeta, replace bool, check func(*SkillMeta) error) error
+
+	// UpdateSkill atomically loads a skill, applies fn and saves the result.
+	// Returns ErrNotFound if the skill does not exist, or the error from fn.
+	UpdateSkill(name string, fn func(*SkillMeta) error) error
+
+	// IncrementDownloads increments the download counter of a skill and
+	// records that version was downloaded at t.
+	IncrementDownloads(name, version string, t time.Time) error
+
+	// Usage returns the storage used by each skill charged to owner, a
+	// username or "@<org>", in name order. See quotaOwner.
+	Usage(owner string) ([]SkillUsage, error)
+
+	// CreateUser registers a user.
+	// Returns ErrUserExists if the username is taken.
+	CreateUser(u User) error
+
+	// GetUser returns a registered user.
+	// Returns nil (no error) if the user does not exist.
+	GetUser(username string) (*User, error)
+
+	// HasUsers reports whether any user is registered.
+	HasUsers() (bool, error)
+
+	// CreateToken stores a new API token of a registered user.
+	CreateToken(t APIToken) error
+
+	// TokenByHash returns the token whose secret hashes to hash.
+	// Returns nil (no error) if no such token exists.
+	TokenByHash(hash string) (*APIToken, error)
+
+	// ListTokens returns the tokens of a user, oldest first.
+	ListTokens(username string) ([]APIToken, error)
+
+	// DeleteToken revokes one of a user's tokens.
+	// Returns ErrNotFound if the user has no token with that id.
+	DeleteToken(username, id string) error
+
+	// TouchToken records that a token was used at t.
+	TouchToken(id string, t time.Time) error
+
+	// CreateOrg registers an organization with its initial members.
+	// Returns ErrOrgExists if the name is taken.
+	CreateOrg(o Org) error
+
+	// GetOrg returns an organization.
+	// Returns nil (no error) if the organization does not exist.
+	GetOrg(name string) (*Org, error)
+
+	// UpdateOrg atomically loads an organization, applies fn and saves the
+	// result. Returns ErrNotFound if it does not exist, or the error from fn.
+	UpdateOrg(name string, fn func(*Org) error) error
+
+	// UserOrgs returns the user's role in each organization they are a
+	// member of, keyed by organization name.
+	UserOrgs(username string) (map[string]string, error)
+
+	// AppendAudit adds an event to the append-only audit log.
+	AppendAudit(e AuditEvent) error
+
+	// ListAudit returns the audit events matching f, oldest first.
+	ListAudit(f AuditFilter) ([]AuditEvent, error)
 }
 
-func NewStore(dataDir string) *Store {
-	return &Store{dataDir: dataDir}
+// BlobStore stores bundle archives by content. Blobs are keyed by their
+// digest, "sha256:<hex>", which is the checksum of every version that
+// points at them, so identical bundles are stored once.
+type BlobStore interface {
+	// PutBlob stores the blob with the given digest. If it is stored
+	// already, PutBlob only refreshes its modification time, so that a
+	// concurrent CollectGarbage keeps it.
+	PutBlob(digest string, r io.Reader) error
+
+	// OpenBlob opens a blob.
+	// Returns ErrNotFound if the blob does not exist.
+	OpenBlob(digest string) (io.ReadCloser, error)
+
+	// DeleteBlob removes a blob. Removing a blob that does not exist is
+	// not an error.
+	DeleteBlob(digest string) error
+
+	// ListBlobs calls fn with every stored blob and when it was last
+	// written, stopping at the first error fn returns.
+	ListBlobs(fn func(digest string, modTime time.Time) error) error
 }
 
-func (s *Store) bundlesDir() string {
-	return filepath.Join(s.dataDir, "bundles")
+// LegacyBundleStore is implemented by blob stores that may still hold
+// bundles written before blobs were content-addressed, keyed by skill name
+// and version. CollectGarbage moves them into the blob store.
+type LegacyBundleStore interface {
+	// OpenBundle opens the bundle for name@version.
+	// Returns ErrNotFound if the bundle does not exist.
+	OpenBundle(name, version string) (io.ReadCloser, error)
+
+	// DeleteBundle removes the bundle for name@version. Removing a bundle
+	// that does not exist is not an error.
+	DeleteBundle(name, version string) error
 }
 
-func (s *Store) skillDir(name string) string {
-	return filepath.Join(s.bundlesDir(), name)
+// BlobFileStore is implemented by blob stores that can take over a local
+// file instead of copying it, such as an upload staged by a publish.
+type BlobFileStore interface {
+	// StagingDir returns a directory for files to be passed to MoveBlob,
+	// creating it if needed.
+	StagingDir() (string, error)
+
+	// MoveBlob moves the file at path into the store as the blob with the
+	// given digest. If the blob exists, the file is removed instead and
+	// the blob is touched as by PutBlob.
+	MoveBlob(digest, path string) error
 }
 
-func (s *Store) metaPath(name string) string {
-	return filepath.Join(s.skillDir(name), "meta.json")
+// License returns the license declared in the version's frontmatter.
+func (vm *VersionMeta) License() string {
+	license, _ := vm.Metadata["license"].(string)
+	return license
 }
 
-func (s *Store) bundlePath(name, version string) string {
-	return filepath.Join(s.skillDir(name), version, "bundle.tar.gz")
+// Visibility returns the visibility declared in the version's frontmatter,
+// or "" if it declares none.
+func (vm *VersionMeta) Visibility() string {
+	visibility, _ := vm.Metadata["visibility"].(string)
+	return visibility
 }
 
-// SaveBundle persists a bundle file and updates skill metadata.
-func (s *Store) SaveBundle(name, owner, description, version, checksum string, sizeBytes int64, tags []string, bundleData []byte) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// VisibilityLevel returns the skill's visibility, defaulting to public.
+func (m *SkillMeta) VisibilityLevel() string {
+	if m.Visibility == "" {
+		return VisibilityPublic
+	}
+	return m.Visibility
+}
 
-	// Ensure directories exist.
-	versionDir := filepath.Join(s.skillDir(name), version)
-	if err := os.MkdirAll(versionDir, 0o755); err != nil {
-		return fmt.Errorf("creating version dir: %w", err)
+// LatestVersion returns the highest stable version that is not yanked, or
+// nil if there is none. Prereleases are only installed when asked for
+// explicitly, so they are never latest.
+func (m *SkillMeta) LatestVersion() *VersionMeta {
+	var latest *VersionMeta
+	var latestV semver.Version
+	for i := range m.Versions {
+		vm := &m.Versions[i]
+		if vm.Yanked {
+			continue
+		}
+		v, err := semver.Parse(vm.Version)
+		if err != nil || v.IsPrerelease() {
+			continue
+		}
+		if latest == nil || v.Compare(latestV) > 0 {
+			latest, latestV = vm, v
+		}
 	}
+	return latest
+}
 
-	// Write bundle file.
-	if err := os.WriteFile(s.bundlePath(name, version), bundleData, 0o644); err != nil {
-		return fmt.Errorf("writing bundle: %w", err)
+// FindVersion returns a specific version or nil.
+func (m *SkillMeta) FindVersion(version string) *VersionMeta {
+	for i := range m.Versions {
+		if m.Versions[i].Version == version {
+			return &m.Versions[i]
+		}
 	}
+	return nil
+}
 
-	// Load or create meta.
-	meta, _ := s.loadMetaLocked(name)
-	if meta == nil {
-		meta = &SkillMeta{
-			Name:  name,
-			Owner: owner,
-			Tags:  tags,
+// FindPruned returns the record of a pruned version or nil.
+func (m *SkillMeta) FindPruned(version string) *PrunedVersion {
+	for i := range m.Pruned {
+		if m.Pruned[i].Version == version {
+			return &m.Pruned[i]
 		}
 	}
-	meta.Description = description
-	meta.Owner = owner
-	if len(tags) > 0 {
-		meta.Tags = tags
-	}
-
-	// Append version (or replace if same version exists).
-	vm := VersionMeta{
-		Version:     version,
-		Description: description,
-		Checksum:    checksum,
-		SizeBytes:   sizeBytes,
-		PublishedAt: time.Now().UTC().Format(time.RFC3339),
-	}
-	replaced := false
-	for i, v := range meta.Versions {
-		if v.Version == version {
-			meta.Versions[i] = vm
-			replaced = true
-			break
+	return nil
+}
+
+// prune removes a version and records why, reporting whether it existed.
+func (m *SkillMeta) prune(version, reason string, at time.Time) bool {
+	i := slices.IndexFunc(m.Versions, func(vm VersionMeta) bool { return vm.Version == version })
+	if i < 0 {
+		return false
+	}
+	m.Pruned = append(m.Pruned, PrunedVersion{
+		Version:  version,
+		Checksum: m.Versions[i].Checksum,
+		Reason:   reason,
+		PrunedAt: at.UTC().Truncate(time.Second),
+	})
+	m.Versions = slices.Delete(m.Versions, i, i+1)
+	return true
+}
+
+// CanManage reports whether user owns the skill or is a collaborator.
+func (m *SkillMeta) CanManage(user string) bool {
+	if user == m.Owner {
+		return true
+	}
+	for _, c := range m.Collaborators {
+		if c == user {
+			return true
 		}
 	}
-	if !replaced {
-		meta.Versions = append(meta.Versions, vm)
+	return false
+}
+
+// OwnerOrg returns the organization that owns the skill, or "" if it is
+// owned by a user. Organization owners are stored as "@<org>".
+func (m *SkillMeta) OwnerOrg() string {
+	org, _ := strings.CutPrefix(m.Owner, "@")
+	if org == m.Owner {
+		return ""
 	}
+	return org
+}
 
-	return s.saveMetaLocked(name, meta)
+// addCollaborator adds user and reports whether they were not one already.
+func (m *SkillMeta) addCollaborator(user string) bool {
+	if slices.Contains(m.Collaborators, user) {
+		return false
+	}
+	m.Collaborators = append(m.Collaborators, user)
+	sort.Strings(m.Collaborators)
+	return true
 }
 
-// GetSkill returns metadata for a skill, or nil if not found.
-func (s *Store) GetSkill(name string) *SkillMeta {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	meta, _ := s.loadMetaLocked(name)
-	return meta
+// removeCollaborator removes user and reports whether they were one.
+func (m *SkillMeta) removeCollaborator(user string) bool {
+	i := slices.Index(m.Collaborators, user)
+	if i < 0 {
+		return false
+	}
+	m.Collaborators = slices.Delete(m.Collaborators, i, i+1)
+	return true
 }
 
-// GetBundlePath returns the file path for a specific version bundle, or empty if not found.
-func (s *Store) GetBundlePath(name, version string) string {
-	p := s.bundlePath(name, version)
-	if _, err := os.Stat(p); err != nil {
-		return ""
+// recordOwnerEvent appends to the owner history.
+func (m *SkillMeta) recordOwnerEvent(action, user, from, actor string) {
+	m.OwnerHistory = append(m.OwnerHistory, OwnerEvent{
+		Action: action,
+		User:   user,
+		From:   from,
+		Actor:  actor,
+		At:     time.Now().UTC().Truncate(time.Second),
+	})
+}
+
+// AllDistTags returns the stored dist-tags plus the computed "latest" tag.
+func (m *SkillMeta) AllDistTags() map[string]string {
+	tags := make(map[string]string, len(m.DistTags)+1)
+	for tag, version := range m.DistTags {
+		tags[tag] = version
+	}
+	if lv := m.LatestVersion(); lv != nil {
+		tags[LatestTag] = lv.Version
 	}
-	return p
+	return tags
 }
 
-// IncrementDownloads atomically increments the download counter.
-func (s *Store) IncrementDownloads(name string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	meta, _ := s.loadMetaLocked(name)
-	if meta == nil {
-		return
+func (m *SkillMeta) setDistTag(tag, version string) {
+	if m.DistTags == nil {
+		m.DistTags = make(map[string]string)
 	}
-	meta.Downloads++
-	_ = s.saveMetaLocked(name, meta)
+	m.DistTags[tag] = version
 }
 
-// Search returns skills whose name, description, or tags match the keyword.
-func (s *Store) Search(keyword string) []SkillMeta {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// LatestTag is the dist-tag that always resolves to LatestVersion.
+const LatestTag = "latest"
 
-	keyword = strings.ToLower(keyword)
-	var results []SkillMeta
+var distTagRegex = regexp.MustCompile(`^[a-z][a-z0-9-]{0,31}$`)
 
-	entries, err := os.ReadDir(s.bundlesDir())
-	if err != nil {
-		return results
+// validDistTag reports whether tag can be stored as a dist-tag. Tags must
+// not be mistakable for a version or a version range.
+func validDistTag(tag string) bool {
+	if !distTagRegex.MatchString(tag) {
+		return false
 	}
+	_, err := semver.ParseConstraint(tag)
+	return err != nil
+}
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-		meta, err := s.loadMetaLocked(entry.Name())
-		if err != nil || meta == nil {
-			continue
-		}
-		if matchesKeyword(meta, keyword) {
-			results = append(results, *meta)
+// applyVersion updates skill-level fields and adds vm to the version list,
+// replacing an existing entry with the same version. The owner is only set
+// on a new skill; ownership does not follow whoever publishes last. So is
+// the visibility declared in the version's frontmatter: changing it later
+// takes the admin permission that publishing does not imply.
+func (m *SkillMeta) applyVersion(owner, description string, tags []string, vm VersionMeta) {
+	m.Description = description
+	if m.Owner == "" {
+		m.Owner = owner
+		m.Visibility = vm.Visibility()
+	}
+	if len(tags) > 0 {
+		m.Tags = tags
+	}
+	for i, v := range m.Versions {
+		if v.Version == vm.Version {
+			m.Versions[i] = vm
+			return
 		}
 	}
-	return results
+	m.Versions = append(m.Versions, vm)
+	sortVersions(m.Versions)
+}
+
+// sortVersions orders versions by ascending semver precedence.
+func sortVersions(versions []VersionMeta) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		return semver.Compare(versions[i].Version, versions[j].Version) < 0
+	})
+}
+
+// bundleKey is the object key of a version's bundle in the legacy layout,
+// e.g. "code-review-agent/1.0.0.tar.gz" or "@acme/code-review/1.0.0.tar.gz".
+func bundleKey(name, version string) string {
+	return name + "/" + version + ".tar.gz"
+}
+
+var digestRegex = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+
+// blobKey is the object key of a blob, e.g. "blobs/sha256/9f/9f86d0...".
+// The two-character fan-out keeps directories small.
+func blobKey(digest string) string {
+	hex := strings.TrimPrefix(digest, "sha256:")
+	return blobPrefix + hex[:2] + "/" + hex
+}
+
+// blobPrefix is the common prefix of every blob key.
+const blobPrefix = "blobs/sha256/"
+
+// digestFromKey returns the digest of a blob key, or "" if key is not one.
+func digestFromKey(key string) string {
+	rest, ok := strings.CutPrefix(key, blobPrefix)
+	if !ok {
+		return ""
+	}
+	_, hex, ok := strings.Cut(rest, "/")
+	if !ok || !digestRegex.MatchString("sha256:"+hex) || !strings.HasPrefix(rest, hex[:2]+"/") {
+		return ""
+	}
+	return "sha256:" + hex
 }
 
 func matchesKeyword(meta *SkillMeta, keyword string) bool {
@@ -189,41 +500,3 @@ func matchesKeyword(meta *SkillMeta, keyword string) bool {
 	}
 	return false
 }
-
-// LatestVersion returns the last version in the list (most recently published).
-func (m *SkillMeta) LatestVersion() *VersionMeta {
-	if len(m.Versions) == 0 {
-		return nil
-	}
-	return &m.Versions[len(m.Versions)-1]
-}
-
-// FindVersion returns a specific version or nil.
-func (m *SkillMeta) FindVersion(version string) *VersionMeta {
-	for i := range m.Versions {
-		if m.Versions[i].Version == version {
-			return &m.Versions[i]
-		}
-	}
-	return nil
-}
-
-func (s *Store) loadMetaLocked(name string) (*SkillMeta, error) {
-	data, err := os.ReadFile(s.metaPath(name))
-	if err != nil {
-		return nil, err
-	}
-	var meta SkillMeta
-	if err := json.Unmarshal(data, &meta); err != nil {
-		return nil, err
-	}
-	return &meta, nil
-}
-
-func (s *Store) saveMetaLocked(name string, meta *SkillMeta) error {
-	data, err := json.MarshalIndent(meta, "", "  ")
-	if err != nil {
-		return fmt.Errorf("marshaling meta: %w", err)
-	}
-	return os.WriteFile(s.metaPath(name), data, 0o644)
-}
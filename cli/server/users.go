@@ -0,0 +1,170 @@
+//go:build server
+
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ErrUserExists is returned by MetaStore.CreateUser when the username is taken.
+var ErrUserExists = errors.New("user already exists")
+
+// tokenPrefix marks registry API tokens so they are easy to spot in logs
+// and secret scanners.
+const tokenPrefix = "ask_"
+
+// User is a registered registry account.
+type User struct {
+	Username  string    `json:"username"`
+	Admin     bool      `json:"admin,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// APIToken is a stored API token. Only the SHA-256 hash of the secret is
+// kept; the secret itself is shown once, when the token is created.
+type APIToken struct {
+	ID         string     `json:"id"`
+	Username   string     `json:"username"`
+	Name       string     `json:"name"`
+	Hash       string     `json:"hash"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+
+	// Scopes restrict what the token may do, in the form accepted by
+	// ParseScope. A token without scopes has all of its user's rights.
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// Expired reports whether the token is past its expiry at now.
+func (t *APIToken) Expired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
+
+var usernameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{0,63}$`)
+
+// ValidateUsername checks that name can be used as a registry username.
+func ValidateUsername(name string) error {
+	if !usernameRegex.MatchString(name) {
+		return fmt.Errorf("invalid username %q: must match [a-zA-Z0-9][a-zA-Z0-9._-]{0,63}", name)
+	}
+	return nil
+}
+
+// HashToken returns the hex SHA-256 digest under which a token secret is
+// stored. Secrets are random 256-bit values, so a fast hash is sufficient.
+func HashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewAPIToken generates a token for username and returns it together with
+// its secret. The caller sets Scopes if needed, stores the token with
+// MetaStore.CreateToken and hands the secret to the user.
+func NewAPIToken(username, name string, expiresAt *time.Time) (APIToken, string) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("reading random bytes: %v", err))
+	}
+	secret := tokenPrefix + hex.EncodeToString(b[:])
+	return APIToken{
+		ID:        newID(),
+		Username:  username,
+		Name:      strings.TrimSpace(name),
+		Hash:      HashToken(secret),
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		ExpiresAt: expiresAt,
+	}, secret
+}
+
+// Token permissions. Each one also grants the permissions listed after it:
+// admin implies publish, yank and read; publish and yank imply read.
+const (
+	// PermRead allows reading skills.
+	PermRead = "read"
+	// PermPublish allows publishing versions and moving dist-tags.
+	PermPublish = "publish"
+	// PermYank allows yanking and unyanking versions.
+	PermYank = "yank"
+	// PermAdmin allows deprecating skills and managing API tokens, and
+	// carries the user's registry-wide admin rights, if any.
+	PermAdmin = "admin"
+)
+
+// Scope grants one permission, optionally only on some skills.
+type Scope struct {
+	Permission string
+	// Skills holds skill names or path.Match globs; empty means all skills.
+	Skills []string
+}
+
+// ParseScope parses "<permission>[:<skill>[,<skill>...]]", for example
+// "publish" or "publish:code-review-*,lint".
+func ParseScope(s string) (Scope, error) {
+	perm, skills, hasSkills := strings.Cut(strings.TrimSpace(s), ":")
+	switch perm {
+	case PermRead, PermPublish, PermYank, PermAdmin:
+	default:
+		return Scope{}, fmt.Errorf("invalid scope %q: permission must be one of read, publish, yank, admin", s)
+	}
+	scope := Scope{Permission: perm}
+	if !hasSkills {
+		return scope, nil
+	}
+	for _, pattern := range strings.Split(skills, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return Scope{}, fmt.Errorf("invalid scope %q: bad skill pattern %q", s, pattern)
+		}
+		scope.Skills = append(scope.Skills, pattern)
+	}
+	return scope, nil
+}
+
+// String formats the scope in the form accepted by ParseScope.
+func (s Scope) String() string {
+	if len(s.Skills) == 0 {
+		return s.Permission
+	}
+	return s.Permission + ":" + strings.Join(s.Skills, ",")
+}
+
+// Allows reports whether the scope grants perm on skill. An empty skill
+// stands for operations that are not tied to a skill, which only scopes
+// without a skill restriction allow.
+func (s Scope) Allows(perm, skill string) bool {
+	if !implies(s.Permission, perm) {
+		return false
+	}
+	if len(s.Skills) == 0 {
+		return true
+	}
+	if skill == "" {
+		return false
+	}
+	for _, pattern := range s.Skills {
+		if ok, _ := path.Match(pattern, skill); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// implies reports whether holding permission have grants want.
+func implies(have, want string) bool {
+	switch have {
+	case want, PermAdmin:
+		return true
+	case PermPublish, PermYank:
+		return want == PermRead
+	}
+	return false
+}
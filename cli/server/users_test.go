@@ -0,0 +1,52 @@
+//go:build server
+
+package server
+
+import "testing"
+
+func TestParseScope(t *testing.T) {
+	for _, raw := range []string{"read", "publish", "publish:code-review-*", "yank:a,b", "admin:@acme/*"} {
+		scope, err := ParseScope(raw)
+		if err != nil {
+			t.Errorf("ParseScope(%q) error = %v", raw, err)
+			continue
+		}
+		if scope.String() != raw {
+			t.Errorf("ParseScope(%q).String() = %q", raw, scope.String())
+		}
+	}
+	for _, raw := range []string{"", "write", "publish:", "publish:a,,b", "publish:[a"} {
+		if _, err := ParseScope(raw); err == nil {
+			t.Errorf("ParseScope(%q) should fail", raw)
+		}
+	}
+}
+
+func TestScopeAllows(t *testing.T) {
+	tests := []struct {
+		scope, perm, skill string
+		want               bool
+	}{
+		{"publish:code-review-*", PermPublish, "code-review-go", true},
+		{"publish:code-review-*", PermPublish, "lint", false},
+		{"publish:code-review-*", PermRead, "code-review-go", true},
+		{"publish:code-review-*", PermYank, "code-review-go", false},
+		{"publish:code-review-*", PermPublish, "", false},
+		{"publish:a,b", PermPublish, "b", true},
+		{"yank", PermYank, "anything", true},
+		{"yank", PermPublish, "anything", false},
+		{"read", PermPublish, "x", false},
+		{"admin", PermPublish, "x", true},
+		{"admin", PermAdmin, "", true},
+		{"admin:x", PermAdmin, "", false},
+	}
+	for _, tt := range tests {
+		scope, err := ParseScope(tt.scope)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := scope.Allows(tt.perm, tt.skill); got != tt.want {
+			t.Errorf("%q.Allows(%s, %q) = %v, want %v", tt.scope, tt.perm, tt.skill, got, tt.want)
+		}
+	}
+}